@@ -17,6 +17,9 @@ security, conversion, validation, and more.`,
 }
 
 func init() {
+	// Setup commands
+	rootCmd.AddCommand(commands.NewInitCmd())
+
 	// Documentation commands
 	rootCmd.AddCommand(commands.NewCreateExampleCmd())
 	rootCmd.AddCommand(commands.NewCreateSchemaCmd())
@@ -24,15 +27,27 @@ func init() {
 	// Auditing commands
 	rootCmd.AddCommand(commands.NewAuditCmd())
 	rootCmd.AddCommand(commands.NewCompareCmd())
+	rootCmd.AddCommand(commands.NewUsageCmd())
+	rootCmd.AddCommand(commands.NewGitScanCmd())
+	rootCmd.AddCommand(commands.NewComposeCheckCmd())
+	rootCmd.AddCommand(commands.NewK8sCheckCmd())
 
 	// Synchronization commands
 	rootCmd.AddCommand(commands.NewSyncCmd())
+	rootCmd.AddCommand(commands.NewMergeCmd())
 
 	// Security commands
 	rootCmd.AddCommand(commands.NewEncryptCmd())
 	rootCmd.AddCommand(commands.NewDecryptCmd())
+	rootCmd.AddCommand(commands.NewSealCmd())
+	rootCmd.AddCommand(commands.NewUnsealCmd())
+	rootCmd.AddCommand(commands.NewKeygenCmd())
+	rootCmd.AddCommand(commands.NewRecipientsCmd())
+	rootCmd.AddCommand(commands.NewRekeyCmd())
 	rootCmd.AddCommand(commands.NewHashCmd())
 	rootCmd.AddCommand(commands.NewBase64Cmd())
+	rootCmd.AddCommand(commands.NewGuardCmd())
+	rootCmd.AddCommand(commands.NewHookCmd())
 
 	// Conversion commands
 	rootCmd.AddCommand(commands.NewToCmd())
@@ -41,11 +56,15 @@ func init() {
 	// Validation commands
 	rootCmd.AddCommand(commands.NewValidateCmd())
 	rootCmd.AddCommand(commands.NewDoctorCmd())
+	rootCmd.AddCommand(commands.NewLintCmd())
+	rootCmd.AddCommand(commands.NewSuppressionsCmd())
 	rootCmd.AddCommand(commands.NewEngineerCmd())
 
 	// Utility commands
 	rootCmd.AddCommand(commands.NewArrangeCmd())
 	rootCmd.AddCommand(commands.NewClearValuesCmd())
+	rootCmd.AddCommand(commands.NewHistoryCmd())
+	rootCmd.AddCommand(commands.NewUndoCmd())
 
 	// Info commands
 	rootCmd.AddCommand(commands.NewVersionCmd())
@@ -0,0 +1,186 @@
+package baseline
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+)
+
+// DefaultFile is the conventional baseline file name
+const DefaultFile = "envdoc-baseline.json"
+
+// version is the current baseline file format. Version 2 added the salt.
+const version = 2
+
+// Entry is a known finding recorded in a baseline
+type Entry struct {
+	Fingerprint string `json:"fingerprint"`
+	Rule        string `json:"rule,omitempty"`
+	File        string `json:"file,omitempty"`
+	Key         string `json:"key,omitempty"`
+}
+
+// Baseline is a set of known findings that reports should not repeat. Salt keys the
+// hashes of secret values in fingerprints, so a different or rotated secret under the same
+// key is a new finding.
+type Baseline struct {
+	Version  int     `json:"version"`
+	Salt     string  `json:"salt"`
+	Findings []Entry `json:"findings"`
+}
+
+// New returns an empty baseline with a random salt
+func New() (*Baseline, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate baseline salt: %w", err)
+	}
+	return &Baseline{Version: version, Salt: hex.EncodeToString(salt), Findings: []Entry{}}, nil
+}
+
+// Fingerprint returns a stable identifier for a finding built from the given parts. It is
+// an unkeyed hash, so a low-entropy part can be recovered by guessing: callers must never
+// pass secret values (or lines containing them), only locations, key names, rule IDs and
+// hashes from ValueHash, so baselines can be committed.
+func Fingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ValueHash returns the HMAC-SHA256 of a secret value keyed with the baseline's salt
+func (b *Baseline) ValueHash(value string) string {
+	mac := hmac.New(sha256.New, []byte(b.Salt))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Assign gives every finding of the report that has no fingerprint one derived from its
+// rule, file and key, and the hash of its value when it carries a secret. Line numbers are
+// left out so fingerprints survive unrelated edits; findings without a key fall back to
+// their message.
+func (b *Baseline) Assign(r *report.Report) {
+	var assign func(sections []report.Section)
+	assign = func(sections []report.Section) {
+		for i := range sections {
+			for j := range sections[i].Findings {
+				finding := &sections[i].Findings[j]
+				if finding.Fingerprint != "" {
+					continue
+				}
+				if finding.Secret != "" {
+					finding.Fingerprint = Fingerprint(finding.Rule, finding.File, finding.Key, b.ValueHash(finding.Secret))
+				} else if finding.Key != "" {
+					finding.Fingerprint = Fingerprint(finding.Rule, finding.File, finding.Key)
+				} else {
+					finding.Fingerprint = Fingerprint(finding.Rule, finding.File, "", finding.Message)
+				}
+			}
+			assign(sections[i].Subsections)
+		}
+	}
+	assign(r.Sections)
+}
+
+// Load reads a baseline file
+func Load(filename string) (*Baseline, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", filename, err)
+	}
+	if b.Version > version {
+		return nil, fmt.Errorf("baseline %s has unsupported version %d", filename, b.Version)
+	}
+	if b.Salt == "" {
+		// Version 1 fingerprinted secrets without their value, so none of them match anyway
+		fresh, err := New()
+		if err != nil {
+			return nil, err
+		}
+		b.Salt = fresh.Salt
+	}
+	return &b, nil
+}
+
+// Record replaces the findings of the baseline with every fingerprinted finding of a report
+func (b *Baseline) Record(r *report.Report) {
+	b.Version = version
+	b.Findings = []Entry{}
+	seen := make(map[string]bool)
+	for _, finding := range r.AllFindings() {
+		if finding.Fingerprint == "" || seen[finding.Fingerprint] {
+			continue
+		}
+		seen[finding.Fingerprint] = true
+		b.Findings = append(b.Findings, Entry{
+			Fingerprint: finding.Fingerprint,
+			Rule:        finding.Rule,
+			File:        finding.File,
+			Key:         finding.Key,
+		})
+	}
+	sort.Slice(b.Findings, func(i, j int) bool {
+		a, c := b.Findings[i], b.Findings[j]
+		if a.File != c.File {
+			return a.File < c.File
+		}
+		if a.Key != c.Key {
+			return a.Key < c.Key
+		}
+		if a.Rule != c.Rule {
+			return a.Rule < c.Rule
+		}
+		return a.Fingerprint < c.Fingerprint
+	})
+}
+
+// Save writes the baseline as indented JSON
+func (b *Baseline) Save(filename string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// Filter removes the findings recorded in the baseline from every section of the report
+// and returns how many were removed
+func (b *Baseline) Filter(r *report.Report) int {
+	known := make(map[string]bool, len(b.Findings))
+	for _, entry := range b.Findings {
+		known[entry.Fingerprint] = true
+	}
+
+	removed := 0
+	var filter func(sections []report.Section)
+	filter = func(sections []report.Section) {
+		for i := range sections {
+			kept := sections[i].Findings[:0]
+			for _, finding := range sections[i].Findings {
+				if finding.Fingerprint != "" && known[finding.Fingerprint] {
+					removed++
+					continue
+				}
+				kept = append(kept, finding)
+			}
+			sections[i].Findings = kept
+			filter(sections[i].Subsections)
+		}
+	}
+	filter(r.Sections)
+	return removed
+}
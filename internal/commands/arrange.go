@@ -11,11 +11,14 @@ import (
 
 // NewArrangeCmd returns the arrange command
 func NewArrangeCmd() *cobra.Command {
-	return &cobra.Command{
+	var preview previewOptions
+
+	cmd := &cobra.Command{
 		Use:   "arrange [file]",
 		Short: "Arrange and group environment variables",
 		Long: `Arrange and group environment variable keys in the specified file. 
-Grouping means similar prefixes will be clustered together.`,
+Grouping means similar prefixes will be clustered together.
+The changes are shown as a unified diff before confirming (--dry-run stops there).`,
 		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var inputFile string
@@ -48,6 +51,16 @@ Grouping means similar prefixes will be clustered together.`,
 			// Arrange by prefix
 			arrangedVars := parser.ArrangeByPrefix(envVars)
 
+			// Show preview
+			change, err := newFileChange(inputFile, parser.FormatEnv(arrangedVars))
+			if err != nil {
+				fmt.Printf("Error reading file: %v\n", err)
+				os.Exit(1)
+			}
+			if !showPreview([]fileChange{change}, preview) {
+				return
+			}
+
 			// Confirm action with PIN
 			confirmed, err := utils.ConfirmWithPin(fmt.Sprintf("This will rearrange keys in '%s'.", inputFile))
 			if err != nil {
@@ -60,7 +73,7 @@ Grouping means similar prefixes will be clustered together.`,
 			}
 
 			// Write back to file
-			if err := parser.WriteEnvFile(inputFile, arrangedVars); err != nil {
+			if err := writeChanges([]fileChange{change}); err != nil {
 				fmt.Printf("Error writing file: %v\n", err)
 				os.Exit(1)
 			}
@@ -68,4 +81,8 @@ Grouping means similar prefixes will be clustered together.`,
 			fmt.Printf("✓ File arranged: %s\n", inputFile)
 		},
 	}
+
+	addPreviewFlags(cmd, &preview)
+
+	return cmd
 }
@@ -1,22 +1,39 @@
 package commands
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
-	"strings"
+	"sort"
 
+	"github.com/MayR-Labs/envdoc-go/internal/config"
+	"github.com/MayR-Labs/envdoc-go/internal/drift"
+	"github.com/MayR-Labs/envdoc-go/internal/insecure"
 	"github.com/MayR-Labs/envdoc-go/internal/parser"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/secrets"
 	"github.com/MayR-Labs/envdoc-go/internal/utils"
 	"github.com/spf13/cobra"
 )
 
 // NewAuditCmd returns the audit command
 func NewAuditCmd() *cobra.Command {
-	return &cobra.Command{
+	var environment string
+	var known baselineOptions
+	var output reportOptions
+	var unsealer valueUnsealer
+
+	cmd := &cobra.Command{
 		Use:   "audit [file]",
-		Short: "Generate a report of missing and duplicated keys",
-		Long: `Generates an extensive markdown report of missing environment keys 
-and duplicated keys in the specified file.`,
+		Short: "Generate a report of missing and duplicated keys, exposed secrets and insecure values",
+		Long: `Generates an extensive report of missing environment keys,
+duplicated keys, values that look like real credentials and settings that are
+insecure for the environment the file targets. The environment is inferred from
+the file name (e.g. .env.production) or the environments map in .envdoc.yaml.
+Sealed values are only checked for presence unless --unseal is given.`,
 		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var inputFile string
@@ -45,6 +62,7 @@ and duplicated keys in the specified file.`,
 				fmt.Printf("Error parsing file: %v\n", err)
 				os.Exit(1)
 			}
+			envVars = unsealer.unseal(envVars)
 
 			// Find duplicates
 			duplicates := parser.FindDuplicates(envVars)
@@ -52,26 +70,74 @@ and duplicated keys in the specified file.`,
 			// Find keys with missing values
 			missingValues := findKeysWithMissingValues(envVars)
 
+			// Find values that look like secrets
+			findings := secrets.Scan(envVars)
+			secrets.SortFindings(findings)
+
+			// Check for settings that are insecure in the target environment
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if environment == "" {
+				environment = insecure.InferEnvironment(inputFile, cfg)
+			}
+			issues, err := insecure.Check(envVars, environment, insecure.Rules(cfg))
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
 			// Generate report
-			report := generateAuditReport(inputFile, duplicates, missingValues, len(envVars))
+			auditReport := generateAuditReport(inputFile, duplicates, missingValues, findings, environment, issues, len(envVars))
+			gate := filterFindings(auditReport, []string{inputFile}, known)
 
 			// Show options
-			handleReportOutput(report, "envdoc-audit")
+			handleReportOutput(auditReport, "envdoc-audit", output)
+
+			if gate {
+				exitOnErrors(auditReport)
+			}
 		},
 	}
+
+	cmd.Flags().StringVar(&environment, "env", "", "environment the file targets (default: inferred from the file name)")
+	unsealer.addFlag(cmd)
+	addBaselineFlags(cmd, &known)
+	addReportFlags(cmd, &output)
+
+	return cmd
 }
 
 // NewCompareCmd returns the compare command
 func NewCompareCmd() *cobra.Command {
-	return &cobra.Command{
+	var valueMode string
+	var mustDiffer, mustMatch []string
+	var driftOnly bool
+	var output reportOptions
+	var unsealer valueUnsealer
+
+	cmd := &cobra.Command{
 		Use:   "compare [file1] [file2] [fileN...]",
-		Short: "Compare keys across multiple files",
-		Long: `Generates an extensive markdown report of keys that are missing 
-across multiple specified files.`,
+		Short: "Compare keys and values across multiple files",
+		Long: `Generates an extensive report of keys that are missing across
+multiple specified files, and a key × file matrix showing where values are missing,
+empty, the same or different. Values are masked by default.
+
+Keys that must differ between environments (e.g. APP_KEY) or must be identical
+everywhere (e.g. APP_NAME) can be checked with --must-differ and --must-match,
+or listed under compare in .envdoc.yaml; the command exits with status 1 when one
+fails. Sealed values cannot be compared and are shown as sealed unless --unseal is given.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			var files []string
 			var err error
 
+			if valueMode != "masked" && valueMode != "hashed" && valueMode != "plain" {
+				fmt.Println("Error: --values must be 'masked', 'hashed' or 'plain'")
+				os.Exit(1)
+			}
+
 			// Get files
 			if len(args) >= 2 {
 				files = args
@@ -103,16 +169,38 @@ across multiple specified files.`,
 					fmt.Printf("Error parsing file '%s': %v\n", file, err)
 					os.Exit(1)
 				}
-				allEnvVars[file] = envVars
+				allEnvVars[file] = unsealer.unseal(envVars)
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
 			}
 
+			// Build the value matrix and run the consistency checks
+			matrix := drift.Build(files, allEnvVars)
+			violations := drift.CheckMustDiffer(matrix, append(cfg.Compare.MustDiffer, mustDiffer...))
+			violations = append(violations, drift.CheckMustMatch(matrix, append(cfg.Compare.MustMatch, mustMatch...))...)
+
 			// Generate comparison report
-			report := generateComparisonReport(allEnvVars)
+			comparisonReport := generateComparisonReport(files, allEnvVars, matrix, violations, valueMode, driftOnly)
 
 			// Show options
-			handleReportOutput(report, "envdoc-compare")
+			handleReportOutput(comparisonReport, "envdoc-compare", output)
+
+			exitOnErrors(comparisonReport)
 		},
 	}
+
+	cmd.Flags().StringVar(&valueMode, "values", "masked", "how values are shown in the matrix: masked, hashed or plain")
+	cmd.Flags().StringSliceVar(&mustDiffer, "must-differ", nil, "keys (or glob patterns) that must not share a value between files")
+	cmd.Flags().StringSliceVar(&mustMatch, "must-match", nil, "keys (or glob patterns) that must have the same value in every file")
+	cmd.Flags().BoolVar(&driftOnly, "drift-only", false, "only show keys whose values drift in the matrix")
+	unsealer.addFlag(cmd)
+	addReportFlags(cmd, &output)
+
+	return cmd
 }
 
 // findKeysWithMissingValues finds keys that have empty or missing values
@@ -126,66 +214,182 @@ func findKeysWithMissingValues(envVars []parser.EnvVar) []string {
 	return missing
 }
 
-func generateAuditReport(filename string, duplicates []string, missingValues []string, totalKeys int) string {
-	var sb strings.Builder
-
-	sb.WriteString("# Environment Variables Audit Report\n\n")
-	sb.WriteString("## Table of Contents\n")
-	sb.WriteString("- [Overview](#overview)\n")
-	sb.WriteString("- [Duplicate Keys](#duplicate-keys)\n")
-	sb.WriteString("- [Keys with Missing Values](#keys-with-missing-values)\n\n")
-
-	sb.WriteString("## Overview\n\n")
-	sb.WriteString(fmt.Sprintf("**File:** `%s`\n\n", filename))
-	sb.WriteString(fmt.Sprintf("**Total Keys:** %d\n\n", totalKeys))
-	sb.WriteString(fmt.Sprintf("**Duplicate Keys:** %d\n\n", len(duplicates)))
-	sb.WriteString(fmt.Sprintf("**Keys with Missing Values:** %d\n\n", len(missingValues)))
-
-	sb.WriteString("## Duplicate Keys\n\n")
-	if len(duplicates) == 0 {
-		sb.WriteString("✓ No duplicate keys found.\n\n")
-	} else {
-		sb.WriteString("| Key |\n")
-		sb.WriteString("|-----|\n")
-		for _, key := range duplicates {
-			sb.WriteString(fmt.Sprintf("| `%s` |\n", key))
-		}
-		sb.WriteString("\n")
+func generateAuditReport(filename string, duplicates []string, missingValues []string, findings []secrets.Finding, environment string, issues []insecure.Issue, totalKeys int) *report.Report {
+	r := report.New("Environment Variables Audit Report")
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddCodeField("File", filename)
+	overview.AddField("Environment", environmentLabel(environment))
+	overview.AddField("Total Keys", totalKeys)
+	overview.AddField("Duplicate Keys", len(duplicates))
+	overview.AddField("Keys with Missing Values", len(missingValues))
+	overview.AddField("Potential Secrets", len(findings))
+	overview.AddField("Insecure Settings", len(issues))
+	r.AddSection(overview)
+
+	r.AddSection(report.Section{
+		Title:    "Duplicate Keys",
+		Findings: keyFindings(filename, duplicates, "duplicate-key", report.Error),
+		Empty:    "✓ No duplicate keys found.",
+	})
+
+	r.AddSection(report.Section{
+		Title:    "Keys with Missing Values",
+		Findings: keyFindings(filename, missingValues, "missing-value", report.Warning),
+		Empty:    "✓ No keys with missing values found.",
+	})
+
+	r.AddSection(secretsSection("Potential Secrets", filename, findings))
+	r.AddSection(insecureSection("Insecure Configuration", filename, environment, issues))
+
+	return r
+}
+
+// keyFindings turns a list of keys into findings of one rule
+func keyFindings(filename string, keys []string, rule, severity string) []report.Finding {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	findings := make([]report.Finding, 0, len(sorted))
+	for _, key := range sorted {
+		findings = append(findings, report.Finding{Severity: severity, Rule: rule, File: filename, Key: key})
 	}
+	return findings
+}
 
-	sb.WriteString("## Keys with Missing Values\n\n")
-	if len(missingValues) == 0 {
-		sb.WriteString("✓ No keys with missing values found.\n\n")
-	} else {
-		sb.WriteString("| Key |\n")
-		sb.WriteString("|-----|\n")
-		for _, key := range missingValues {
-			sb.WriteString(fmt.Sprintf("| `%s` |\n", key))
-		}
-		sb.WriteString("\n")
+// environmentLabel returns a printable name for an inferred environment
+func environmentLabel(environment string) string {
+	if environment == "" {
+		return "unknown"
 	}
+	return environment
+}
+
+// secretConfidenceSeverity maps a secret finding's confidence to a report severity
+var secretConfidenceSeverity = map[secrets.Confidence]string{
+	secrets.High:   report.Error,
+	secrets.Medium: report.Warning,
+	secrets.Low:    report.Info,
+}
+
+// secretsSection builds a section listing secret findings with masked values
+func secretsSection(title, filename string, findings []secrets.Finding) report.Section {
+	section := report.Section{Title: title, Empty: "✓ No potential secrets found."}
+	if len(findings) > 0 {
+		section.Text = "Files containing real credentials should never be committed. Rotate any exposed secret and encrypt the file with `envdoc encrypt`."
+	}
+	for _, finding := range findings {
+		section.Findings = append(section.Findings, report.Finding{
+			Severity:   secretConfidenceSeverity[finding.Confidence],
+			Rule:       finding.Rule,
+			File:       filename,
+			Line:       finding.Line,
+			Key:        finding.Key,
+			Value:      finding.MaskedValue,
+			Secret:     finding.Value,
+			Confidence: string(finding.Confidence),
+			Message:    finding.Description,
+		})
+	}
+	return section
+}
 
-	return sb.String()
+// insecureSection builds a section listing insecure settings for the target environment
+func insecureSection(title, filename, environment string, issues []insecure.Issue) report.Section {
+	section := report.Section{Title: title}
+	if environment == "" {
+		section.Text = "Environment could not be inferred from the file name. Pass `--env` or map the file under `environments` in `.envdoc.yaml` to run environment checks."
+		return section
+	}
+	section.Empty = fmt.Sprintf("✓ No insecure settings found for %s.", environment)
+	for _, issue := range issues {
+		value := issue.Value
+		if secrets.LooksLikeSecretKey(issue.Key) && !secrets.IsPlaceholder(value) {
+			value = secrets.Mask(value)
+		}
+		section.Findings = append(section.Findings, report.Finding{
+			Severity: issue.Severity,
+			Rule:     issue.Rule,
+			File:     filename,
+			Key:      issue.Key,
+			Value:    value,
+			Message:  issue.Message,
+		})
+	}
+	return section
 }
 
-func generateComparisonReport(allEnvVars map[string][]parser.EnvVar) string {
-	var sb strings.Builder
+func generateComparisonReport(files []string, allEnvVars map[string][]parser.EnvVar, matrix *drift.Matrix, violations []drift.Violation, valueMode string, driftOnly bool) *report.Report {
+	r := report.New("Environment Variables Comparison Report")
 
-	sb.WriteString("# Environment Variables Comparison Report\n\n")
-	sb.WriteString("## Table of Contents\n")
-	sb.WriteString("- [Overview](#overview)\n")
-	sb.WriteString("- [Files Analyzed](#files-analyzed)\n")
-	sb.WriteString("- [Missing Keys](#missing-keys)\n\n")
+	driftCount := 0
+	for _, row := range matrix.Rows {
+		if row.HasDrift() {
+			driftCount++
+		}
+	}
 
-	sb.WriteString("## Overview\n\n")
-	sb.WriteString(fmt.Sprintf("**Files Compared:** %d\n\n", len(allEnvVars)))
+	overview := report.Section{Title: "Overview"}
+	overview.AddField("Files Compared", len(files))
+	overview.AddField("Keys with Drift", fmt.Sprintf("%d of %d", driftCount, len(matrix.Rows)))
+	overview.AddField("Consistency Violations", len(violations))
+	r.AddSection(overview)
 
-	sb.WriteString("## Files Analyzed\n\n")
-	for file, envVars := range allEnvVars {
-		sb.WriteString(fmt.Sprintf("- `%s` (%d keys)\n", file, len(envVars)))
+	analyzed := report.Section{Title: "Files Analyzed"}
+	for _, file := range files {
+		analyzed.List = append(analyzed.List, fmt.Sprintf("`%s` (%d keys)", file, len(allEnvVars[file])))
+	}
+	r.AddSection(analyzed)
+
+	r.AddSection(missingKeysSection(files, allEnvVars))
+
+	// Hashes are keyed with a key of this run only, so they cannot be matched against
+	// guessed values or against other reports
+	var hashKey []byte
+	if valueMode == "hashed" {
+		hashKey = make([]byte, 32)
+		if _, err := rand.Read(hashKey); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
-	sb.WriteString("\n")
 
+	table := report.Table{Columns: []report.Column{{Title: "Key", Code: true}}}
+	for _, file := range files {
+		table.Columns = append(table.Columns, report.Column{Title: file})
+	}
+	for _, row := range matrix.Rows {
+		if driftOnly && !row.HasDrift() {
+			continue
+		}
+		cells := []string{row.Key}
+		for _, cell := range row.Cells {
+			cells = append(cells, formatDriftCell(cell, valueMode, hashKey))
+		}
+		table.Rows = append(table.Rows, cells)
+	}
+	r.AddSection(report.Section{
+		Title:  "Value Matrix",
+		Text:   fmt.Sprintf("Values are compared with the first file that sets the key and shown %s.", valueMode),
+		Tables: []report.Table{table},
+		Empty:  "✓ No drifting values.",
+	})
+
+	consistency := report.Section{Title: "Consistency Checks", Empty: "✓ No consistency violations."}
+	for _, violation := range violations {
+		consistency.Findings = append(consistency.Findings, report.Finding{
+			Severity: report.Error,
+			Rule:     violation.Check,
+			Key:      violation.Key,
+			Message:  violation.Message,
+		})
+	}
+	r.AddSection(consistency)
+
+	return r
+}
+
+// missingKeysSection builds a section with one subsection per file listing the keys other files define
+func missingKeysSection(files []string, allEnvVars map[string][]parser.EnvVar) report.Section {
 	// Collect all keys from all files
 	allKeys := make(map[string]bool)
 	for _, envVars := range allEnvVars {
@@ -193,29 +397,53 @@ func generateComparisonReport(allEnvVars map[string][]parser.EnvVar) string {
 			allKeys[envVar.Key] = true
 		}
 	}
+	var allKeysList []string
+	for key := range allKeys {
+		allKeysList = append(allKeysList, key)
+	}
 
-	sb.WriteString("## Missing Keys\n\n")
+	section := report.Section{Title: "Missing Keys"}
+	for _, file := range files {
+		missing := parser.FindMissingKeys(allKeysList, parser.GetEnvKeys(allEnvVars[file]))
+		section.AddSubsection(report.Section{
+			Title:    fmt.Sprintf("Missing in `%s`", file),
+			Findings: keyFindings(file, missing, "missing-key", report.Warning),
+			Empty:    "✓ No missing keys.",
+		})
+	}
+	return section
+}
 
-	for file, envVars := range allEnvVars {
-		fileKeys := parser.GetEnvKeys(envVars)
-		var allKeysList []string
-		for key := range allKeys {
-			allKeysList = append(allKeysList, key)
-		}
-		missing := parser.FindMissingKeys(allKeysList, fileKeys)
-
-		sb.WriteString(fmt.Sprintf("### Missing in `%s`\n\n", file))
-		if len(missing) == 0 {
-			sb.WriteString("✓ No missing keys.\n\n")
-		} else {
-			sb.WriteString("| Key |\n")
-			sb.WriteString("|-----|\n")
-			for _, key := range missing {
-				sb.WriteString(fmt.Sprintf("| `%s` |\n", key))
-			}
-			sb.WriteString("\n")
-		}
+// formatDriftCell renders a matrix cell with its value masked, hashed with an HMAC under
+// hashKey or in plain text
+func formatDriftCell(cell drift.Cell, valueMode string, hashKey []byte) string {
+	switch cell.State {
+	case drift.Missing:
+		return "✗ missing"
+	case drift.Empty:
+		return "∅ empty"
+	case drift.Sealed:
+		return "🔒 sealed"
 	}
 
-	return sb.String()
+	var value string
+	switch valueMode {
+	case "plain":
+		value = cell.Value
+	case "hashed":
+		mac := hmac.New(sha256.New, hashKey)
+		mac.Write([]byte(cell.Value))
+		value = hex.EncodeToString(mac.Sum(nil))[:12]
+	default:
+		value = secrets.Mask(cell.Value)
+	}
+
+	switch cell.State {
+	case drift.Same:
+		return fmt.Sprintf("= `%s`", value)
+	case drift.Different:
+		return fmt.Sprintf("≠ `%s`", value)
+	default:
+		return fmt.Sprintf("● `%s`", value)
+	}
 }
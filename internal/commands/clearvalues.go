@@ -11,11 +11,15 @@ import (
 
 // NewClearValuesCmd returns the clear-values command
 func NewClearValuesCmd() *cobra.Command {
-	return &cobra.Command{
+	var preview previewOptions
+
+	cmd := &cobra.Command{
 		Use:   "clear-values [file]",
 		Short: "Clear all values from an environment file",
 		Long: `Clears all values from the specified environment file, leaving only the keys.
-This is a dangerous operation and requires PIN confirmation.`,
+Comments, blank lines and key order are kept. The changes are shown as a unified diff
+before confirming (--dry-run stops there). This is a dangerous operation and requires
+PIN confirmation.`,
 		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var inputFile string
@@ -38,10 +42,35 @@ This is a dangerous operation and requires PIN confirmation.`,
 				os.Exit(1)
 			}
 
+			// Clear all values
+			doc, err := parser.ReadDocument(inputFile)
+			if err != nil {
+				fmt.Printf("Error parsing file: %v\n", err)
+				os.Exit(1)
+			}
+			keys := doc.Keys()
+			for _, key := range keys {
+				doc.Set(key, "")
+			}
+
+			// Show preview
+			change, err := newFileChange(inputFile, doc.String())
+			if err != nil {
+				fmt.Printf("Error reading file: %v\n", err)
+				os.Exit(1)
+			}
+			if !showPreview([]fileChange{change}, preview) {
+				return
+			}
+
 			// First warning
 			fmt.Printf("\n⚠️  WARNING: This operation will CLEAR ALL VALUES from '%s'\n", inputFile)
-			fmt.Println("⚠️  This action is IRREVERSIBLE and will remove all sensitive data!")
-			fmt.Println("⚠️  Make sure you have a backup before proceeding.")
+			if _, enabled, err := openJournal(); err == nil && enabled {
+				fmt.Println("⚠️  This will remove all sensitive data! Only 'envdoc undo' can restore it.")
+			} else {
+				fmt.Println("⚠️  This action is IRREVERSIBLE and will remove all sensitive data!")
+				fmt.Println("⚠️  Make sure you have a backup before proceeding.")
+			}
 
 			// First PIN confirmation
 			confirmed, err := utils.ConfirmWithPin("To proceed with clearing all values, please confirm with PIN")
@@ -69,32 +98,18 @@ This is a dangerous operation and requires PIN confirmation.`,
 				return
 			}
 
-			// Parse input file
-			envVars, err := parser.ParseEnvFile(inputFile)
-			if err != nil {
-				fmt.Printf("Error parsing file: %v\n", err)
-				os.Exit(1)
-			}
-
-			// Clear all values
-			clearedVars := make([]parser.EnvVar, len(envVars))
-			for i, envVar := range envVars {
-				clearedVars[i] = parser.EnvVar{
-					Key:        envVar.Key,
-					Value:      "",
-					Comment:    envVar.Comment,
-					BlankAfter: envVar.BlankAfter,
-				}
-			}
-
 			// Write back to file
-			if err := parser.WriteEnvFile(inputFile, clearedVars); err != nil {
+			if err := writeChanges([]fileChange{change}); err != nil {
 				fmt.Printf("Error writing file: %v\n", err)
 				os.Exit(1)
 			}
 
 			fmt.Printf("✓ All values cleared from: %s\n", inputFile)
-			fmt.Printf("✓ %d keys retained with empty values\n", len(clearedVars))
+			fmt.Printf("✓ %d keys retained with empty values\n", len(keys))
 		},
 	}
+
+	addPreviewFlags(cmd, &preview)
+
+	return cmd
 }
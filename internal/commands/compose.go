@@ -0,0 +1,300 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/compose"
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewComposeCheckCmd returns the compose-check command
+func NewComposeCheckCmd() *cobra.Command {
+	var composeFiles, envFiles []string
+	var output reportOptions
+
+	cmd := &cobra.Command{
+		Use:   "compose-check",
+		Short: "Cross-check env files against docker-compose files",
+		Long: `Parses docker-compose files and checks them against the env files that feed them:
+- variables interpolated with ${VAR} (in images, ports, environment blocks, ...) or passed
+  through by name that are not defined and have no default, so they would be empty
+- env_file entries that point at files that do not exist
+- variables defined in the interpolation env file that no compose file uses
+
+Like docker compose, interpolation reads the .env file next to the first compose file
+unless --env-file is given. Files listed under env_file only feed the containers.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Get compose files
+			if len(composeFiles) == 0 {
+				composeFiles = compose.Find(".")
+				if len(composeFiles) == 0 {
+					fmt.Println("Error: No compose file found. Use --file to select one.")
+					os.Exit(1)
+				}
+			}
+			for _, file := range composeFiles {
+				if !utils.FileExists(file) {
+					fmt.Printf("Error: File '%s' does not exist\n", file)
+					os.Exit(1)
+				}
+			}
+
+			project, err := compose.Load(composeFiles)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Get the env files used for interpolation
+			if len(envFiles) == 0 {
+				defaultEnvFile := filepath.Join(filepath.Dir(composeFiles[0]), ".env")
+				if utils.FileExists(defaultEnvFile) {
+					envFiles = []string{defaultEnvFile}
+				}
+			}
+			interpolationVars := make(map[string][]parser.EnvVar)
+			for _, file := range envFiles {
+				envVars, err := parser.ParseEnvFile(file)
+				if err != nil {
+					fmt.Printf("Error parsing file '%s': %v\n", file, err)
+					os.Exit(1)
+				}
+				interpolationVars[file] = envVars
+			}
+
+			// Generate report
+			composeReport := generateComposeReport(project, envFiles, interpolationVars)
+
+			// Show options
+			handleReportOutput(composeReport, "envdoc-compose-check", output)
+
+			exitOnErrors(composeReport)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&composeFiles, "file", "f", nil, "compose files to check (default: compose.yaml, docker-compose.yml and overrides)")
+	cmd.Flags().StringSliceVar(&envFiles, "env-file", nil, "env files used for interpolation (default: .env next to the first compose file)")
+	addReportFlags(cmd, &output)
+
+	return cmd
+}
+
+func generateComposeReport(project *compose.Project, envFiles []string, interpolationVars map[string][]parser.EnvVar) *report.Report {
+	r := report.New("Docker Compose Cross-Check Report")
+
+	defined := make(map[string]bool)
+	for _, envVars := range interpolationVars {
+		for _, envVar := range envVars {
+			defined[envVar.Key] = true
+		}
+	}
+
+	// Parse the env files that feed services
+	var missingFiles []report.Finding
+	serviceFiles := make(map[string][]string)
+	serviceKeys := make(map[string]map[string]bool)
+	containerKeys := make(map[string]string)
+	usedAsEnvFile := make(map[string]bool)
+	for _, envFile := range project.EnvFiles {
+		path := filepath.Clean(envFile.Path)
+		serviceFiles[envFile.Service] = append(serviceFiles[envFile.Service], path)
+		usedAsEnvFile[path] = true
+		envVars, err := parser.ParseEnvFile(path)
+		if err != nil {
+			severity, message := report.Error, "env_file does not exist; docker compose will refuse to start"
+			if !envFile.Required {
+				severity, message = report.Info, "Optional env_file does not exist"
+			}
+			missingFiles = append(missingFiles, report.Finding{
+				Severity: severity,
+				Rule:     "missing-env-file",
+				File:     envFile.File,
+				Line:     envFile.Line,
+				Key:      path,
+				Message:  fmt.Sprintf("%s (service %s)", message, envFile.Service),
+			})
+			continue
+		}
+		if serviceKeys[envFile.Service] == nil {
+			serviceKeys[envFile.Service] = make(map[string]bool)
+		}
+		for _, envVar := range envVars {
+			serviceKeys[envFile.Service][envVar.Key] = true
+			if _, ok := containerKeys[envVar.Key]; !ok {
+				containerKeys[envVar.Key] = path
+			}
+		}
+	}
+
+	// Group references by variable
+	references := make(map[string][]compose.Reference)
+	for _, ref := range project.References {
+		references[ref.Name] = append(references[ref.Name], ref)
+	}
+	names := make([]string, 0, len(references))
+	for name := range references {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var undefined []report.Finding
+	for _, name := range names {
+		if defined[name] {
+			continue
+		}
+		var unsafe []compose.Reference
+		required := false
+		for _, ref := range references[name] {
+			if !ref.Default {
+				unsafe = append(unsafe, ref)
+				required = required || ref.Required
+			}
+		}
+		if len(unsafe) == 0 {
+			continue
+		}
+
+		first := unsafe[0]
+		severity, rule := report.Error, "undefined-variable"
+		message := "Not defined and has no default, so it interpolates to an empty string"
+		switch {
+		case required:
+			rule, message = "required-variable", "Not defined; docker compose will refuse to start"
+		case allPassThrough(unsafe):
+			severity, rule = report.Warning, "undefined-passthrough"
+			message = "Passed through to the container but not defined, so it is left unset"
+		}
+		if file, ok := containerKeys[name]; ok && !allPassThrough(unsafe) {
+			message += fmt.Sprintf(". It is set in `%s`, but env_file values are not used for interpolation", file)
+		}
+		message += fmt.Sprintf(" (%s)", describeUsage(unsafe))
+
+		undefined = append(undefined, report.Finding{
+			Severity: severity,
+			Rule:     rule,
+			File:     first.File,
+			Line:     first.Line,
+			Key:      name,
+			Message:  message,
+		})
+	}
+
+	var unused []report.Finding
+	for _, file := range envFiles {
+		if usedAsEnvFile[filepath.Clean(file)] {
+			continue
+		}
+		for _, envVar := range interpolationVars[file] {
+			if _, ok := references[envVar.Key]; !ok {
+				unused = append(unused, report.Finding{
+					Severity: report.Warning,
+					Rule:     "unused-variable",
+					File:     file,
+					Key:      envVar.Key,
+					Message:  "Defined for interpolation but not referenced by any compose file",
+				})
+			}
+		}
+	}
+	report.SortFindings(unused)
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddField("Compose Files", codeList(project.Files))
+	if len(envFiles) > 0 {
+		overview.AddField("Interpolation Env Files", codeList(envFiles))
+	} else {
+		overview.AddField("Interpolation Env Files", "None (only the shell environment)")
+	}
+	overview.AddField("Services", len(project.Services))
+	overview.AddField("Variables Referenced", len(names))
+	overview.AddField("Undefined Variables", len(undefined))
+	overview.AddField("Unused Variables", len(unused))
+	r.AddSection(overview)
+
+	services := report.Table{Columns: []report.Column{{Title: "Service", Code: true}, {Title: "Env Files"}, {Title: "Keys from Env Files"}, {Title: "Variables Referenced"}}}
+	for _, service := range project.Services {
+		referenced := make(map[string]bool)
+		for _, ref := range project.References {
+			if ref.Service == service {
+				referenced[ref.Name] = true
+			}
+		}
+		services.Rows = append(services.Rows, []string{
+			service,
+			codeList(serviceFiles[service]),
+			fmt.Sprint(len(serviceKeys[service])),
+			fmt.Sprint(len(referenced)),
+		})
+	}
+	servicesSection := report.Section{Title: "Services", Empty: "No services defined."}
+	if len(services.Rows) > 0 {
+		servicesSection.Tables = append(servicesSection.Tables, services)
+	}
+	r.AddSection(servicesSection)
+
+	undefinedSection := report.Section{
+		Title:    "Undefined Variables",
+		Findings: undefined,
+		Empty:    "✓ Every referenced variable is defined or has a default.",
+	}
+	if len(undefined) > 0 {
+		undefinedSection.Text = "Variables can also come from the shell environment. Define them in the interpolation env file or add a default with `${VAR:-default}`."
+	}
+	r.AddSection(undefinedSection)
+
+	r.AddSection(report.Section{
+		Title:    "Missing Env Files",
+		Findings: missingFiles,
+		Empty:    "✓ Every env_file exists.",
+	})
+	r.AddSection(report.Section{
+		Title:    "Unused Variables",
+		Findings: unused,
+		Empty:    "✓ Every interpolation variable is used.",
+	})
+
+	return r
+}
+
+// allPassThrough reports whether every reference passes the variable through by name
+func allPassThrough(refs []compose.Reference) bool {
+	for _, ref := range refs {
+		if !ref.PassThrough {
+			return false
+		}
+	}
+	return true
+}
+
+// describeUsage summarizes where a variable is referenced
+func describeUsage(refs []compose.Reference) string {
+	seen := make(map[string]bool)
+	var services []string
+	for _, ref := range refs {
+		name := ref.Service
+		if name == "" {
+			name = "top level"
+		}
+		if !seen[name] {
+			seen[name] = true
+			services = append(services, name)
+		}
+	}
+	return fmt.Sprintf("referenced %d time(s) in %s", len(refs), strings.Join(services, ", "))
+}
+
+// codeList formats values as a comma-separated list of code spans
+func codeList(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(quoteAll(values), ", ")
+}
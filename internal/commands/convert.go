@@ -97,7 +97,12 @@ func NewToCmd() *cobra.Command {
 			}
 
 			// Write output file
-			if err := utils.WriteToFile(outputFile, output); err != nil {
+			change, err := newFileChange(outputFile, output)
+			if err != nil {
+				fmt.Printf("Error reading file: %v\n", err)
+				os.Exit(1)
+			}
+			if err := writeChanges([]fileChange{change}); err != nil {
 				fmt.Printf("Error writing file: %v\n", err)
 				os.Exit(1)
 			}
@@ -184,7 +189,12 @@ func NewFromCmd() *cobra.Command {
 			}
 
 			// Write output file
-			if err := parser.WriteEnvFile(outputFile, envVars); err != nil {
+			change, err := newFileChange(outputFile, parser.FormatEnv(envVars))
+			if err != nil {
+				fmt.Printf("Error reading file: %v\n", err)
+				os.Exit(1)
+			}
+			if err := writeChanges([]fileChange{change}); err != nil {
 				fmt.Printf("Error writing file: %v\n", err)
 				os.Exit(1)
 			}
@@ -133,7 +133,12 @@ The schema defines each key as a string type.`,
 			}
 
 			// Write output file
-			if err := utils.WriteToFile(outputFile, schemaJSON); err != nil {
+			change, err := newFileChange(outputFile, schemaJSON)
+			if err != nil {
+				fmt.Printf("Error reading file: %v\n", err)
+				os.Exit(1)
+			}
+			if err := writeChanges([]fileChange{change}); err != nil {
 				fmt.Printf("Error writing file: %v\n", err)
 				os.Exit(1)
 			}
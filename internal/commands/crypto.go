@@ -1,14 +1,22 @@
 package commands
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/MayR-Labs/envdoc-go/internal/config"
+	"github.com/MayR-Labs/envdoc-go/internal/credential"
 	"github.com/MayR-Labs/envdoc-go/internal/crypto"
+	"github.com/MayR-Labs/envdoc-go/internal/guard"
+	"github.com/MayR-Labs/envdoc-go/internal/recipients"
 	"github.com/MayR-Labs/envdoc-go/internal/utils"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 // NewBase64Cmd returns the base64 command
@@ -90,7 +98,12 @@ func NewBase64Cmd() *cobra.Command {
 			}
 
 			// Write output file
-			if err := utils.WriteToFile(outputFile, output); err != nil {
+			change, err := newFileChange(outputFile, output)
+			if err != nil {
+				fmt.Printf("Error reading file: %v\n", err)
+				os.Exit(1)
+			}
+			if err := writeChanges([]fileChange{change}); err != nil {
 				fmt.Printf("Error writing file: %v\n", err)
 				os.Exit(1)
 			}
@@ -153,16 +166,402 @@ func NewHashCmd() *cobra.Command {
 	}
 }
 
+// passwordSourcesHelp documents the password flags in the help of the commands that take them
+const passwordSourcesHelp = `The password is read from --password-file, --password-fd or --password-cmd (only one can
+be given), otherwise from ENVDOC_PASSWORD, and only then prompted for. Files should be
+readable only by you: a warning is printed for files other users can access, and whenever
+ENVDOC_PASSWORD is used, as it is visible to every process started from the environment.`
+
+// outputFileHelp documents the output flags in the help of the commands that take them
+const outputFileHelp = `The output file is prompted for unless -o/--output is given. When stdin is not a terminal,
+the default name is used and an existing output file is only replaced with --force.`
+
+// kdfOptions holds the key derivation flags of commands that encrypt
+type kdfOptions struct {
+	name    string
+	profile string
+	target  time.Duration
+}
+
+// addKDFFlags registers the key derivation flags on a command
+func addKDFFlags(cmd *cobra.Command, opts *kdfOptions) {
+	cmd.Flags().StringVar(&opts.name, "kdf", "", "key derivation function: "+strings.Join(crypto.KDFNames, ", ")+" (default "+crypto.DefaultKDF+")")
+	cmd.Flags().StringVar(&opts.profile, "kdf-profile", "", "key derivation cost: "+strings.Join(crypto.Profiles, ", ")+" (default "+crypto.DefaultProfile+")")
+	cmd.Flags().DurationVar(&opts.target, "kdf-target", 0, "benchmark this machine and pick parameters that unlock in about this time (e.g. 1s)")
+}
+
+// explicit reports whether key derivation flags were given
+func (opts kdfOptions) explicit() bool {
+	return opts.name != "" || opts.profile != "" || opts.target > 0
+}
+
+// params resolves the KDF parameters from the flags and the encryption section of .envdoc.yaml
+func (opts kdfOptions) params() (crypto.KDFParams, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return crypto.KDFParams{}, err
+	}
+	name, profile := crypto.DefaultKDF, crypto.DefaultProfile
+	if cfg.Encryption.KDF != "" {
+		name = cfg.Encryption.KDF
+	}
+	if cfg.Encryption.KDFProfile != "" {
+		profile = cfg.Encryption.KDFProfile
+	}
+	if opts.name != "" {
+		name = opts.name
+	}
+	if opts.profile != "" {
+		profile = opts.profile
+	}
+
+	if opts.target > 0 {
+		fmt.Printf("Benchmarking %s for an unlock time of %s...\n", name, opts.target)
+		params, elapsed, err := crypto.BenchmarkKDF(name, opts.target)
+		if err != nil {
+			return crypto.KDFParams{}, err
+		}
+		fmt.Printf("Selected %s (%s on this machine)\n", params, elapsed.Round(time.Millisecond))
+		return params, nil
+	}
+	return crypto.NewKDFParams(name, profile)
+}
+
+// secretOptions holds the flags that provide a password without prompting. noPrompt is
+// set to the reason when the password must not be prompted for.
+type secretOptions struct {
+	name     string
+	source   credential.Source
+	noPrompt string
+}
+
+// addPasswordFlags registers --password-file, --password-fd and --password-cmd on a command;
+// ENVDOC_PASSWORD is used when none of them is given
+func addPasswordFlags(cmd *cobra.Command, opts *secretOptions) {
+	addSecretFlags(cmd, opts, "password", credential.PasswordEnv)
+}
+
+// addSecretFlags registers the NAME-file, NAME-fd and NAME-cmd flags, read before the env variable
+func addSecretFlags(cmd *cobra.Command, opts *secretOptions, name, env string) {
+	what := strings.ReplaceAll(name, "-", " ")
+	opts.name = name
+	opts.source.Env = env
+	cmd.Flags().StringVar(&opts.source.File, name+"-file", "", "read the "+what+" from this file")
+	cmd.Flags().IntVar(&opts.source.FD, name+"-fd", -1, "read the "+what+" from this open file descriptor")
+	cmd.Flags().StringVar(&opts.source.Cmd, name+"-cmd", "", "read the "+what+" from the output of this shell command")
+	cmd.MarkFlagsMutuallyExclusive(name+"-file", name+"-fd", name+"-cmd")
+}
+
+// password returns the password from the flags or the environment, or prompts for it.
+// With confirm the prompt asks twice.
+func (opts secretOptions) password(message string, confirm bool) (string, error) {
+	secret, err := opts.source.Read()
+	if err != nil {
+		return "", err
+	}
+	if secret != nil {
+		for _, warning := range secret.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		return secret.Value, nil
+	}
+
+	reason := opts.noPrompt
+	if reason == "" && !term.IsTerminal(int(os.Stdin.Fd())) {
+		reason = "stdin is not a terminal"
+	}
+	if reason != "" {
+		return "", fmt.Errorf("no %s given and %s; use --%s-file, --%s-fd, --%s-cmd or %s",
+			strings.ReplaceAll(opts.name, "-", " "), reason, opts.name, opts.name, opts.name, opts.source.Env)
+	}
+	password, err := utils.PromptForPassword(message)
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+	if confirm {
+		confirmPassword, err := utils.PromptForPassword("Confirm password:")
+		if err != nil {
+			return "", err
+		}
+		if password != confirmPassword {
+			return "", fmt.Errorf("passwords do not match")
+		}
+	}
+	return password, nil
+}
+
+// outputOptions holds the flags that name the output file of a command without prompting
+type outputOptions struct {
+	file  string
+	force bool
+}
+
+// addOutputFlags registers -o/--output and --force on a command
+func addOutputFlags(cmd *cobra.Command, opts *outputOptions) {
+	cmd.Flags().StringVarP(&opts.file, "output", "o", "", "write the output to this file instead of asking for its name")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "overwrite the output file if it exists")
+}
+
+// path returns the output file: --output if given, the default name when stdin is not a
+// terminal, and otherwise the name prompted for. Without a prompt an existing file is only
+// overwritten with --force.
+func (opts outputOptions) path(defaultOutput string) (string, error) {
+	if opts.file == "" && term.IsTerminal(int(os.Stdin.Fd())) {
+		return utils.PromptForOutputFile("Enter output filename:", defaultOutput)
+	}
+	outputFile := opts.file
+	if outputFile == "" {
+		outputFile = defaultOutput
+	}
+	if utils.FileExists(outputFile) && !opts.force {
+		return "", fmt.Errorf("'%s' already exists; use --force to overwrite it", outputFile)
+	}
+	return outputFile, nil
+}
+
+// fileDecrypter decrypts encrypted files: files encrypted to recipients with the user's
+// identity, and password-encrypted files with a password that is read once
+type fileDecrypter struct {
+	identityFile string
+	identities   []*crypto.Identity
+	secret       secretOptions
+	password     string
+	hasPassword  bool
+}
+
+// addFlags registers --identity and the password flags on a command
+func (d *fileDecrypter) addFlags(cmd *cobra.Command) {
+	d.addIdentityFlag(cmd)
+	addPasswordFlags(cmd, &d.secret)
+}
+
+// addIdentityFlag registers --identity on a command that only opens files encrypted to recipients
+func (d *fileDecrypter) addIdentityFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&d.identityFile, "identity", "i", "", "identity file for files encrypted to recipients (default: $"+credential.IdentityEnv+", then the one created by 'envdoc keygen')")
+}
+
+// decrypt decrypts data in any format written by envdoc
+func (d *fileDecrypter) decrypt(data string) ([]byte, error) {
+	r, err := crypto.NewDecryptReader(strings.NewReader(data), d.keys())
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// decryptFile decrypts a file in any format written by envdoc as a stream into w. w
+// receives content before all of it is authenticated, so it must be discarded when this
+// fails.
+func (d *fileDecrypter) decryptFile(w io.Writer, file string) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	r, err := crypto.NewDecryptReader(in, d.keys())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// keys returns the secrets for crypto.NewDecryptReader; each is read when a file needs it
+func (d *fileDecrypter) keys() crypto.Keys {
+	return crypto.Keys{Password: d.getPassword, Identities: d.loadIdentities}
+}
+
+// getPassword reads the password on first use
+func (d *fileDecrypter) getPassword() (string, error) {
+	if !d.hasPassword {
+		password, err := d.secret.password("Enter decryption password:", false)
+		if err != nil {
+			return "", err
+		}
+		d.password, d.hasPassword = password, true
+	}
+	return d.password, nil
+}
+
+// loadIdentities reads the identities on first use: from --identity, the secret keys in
+// ENVDOC_IDENTITY, or the identity file created by keygen
+func (d *fileDecrypter) loadIdentities() ([]*crypto.Identity, error) {
+	if d.identities != nil {
+		return d.identities, nil
+	}
+	if d.identityFile == "" {
+		secret, err := credential.Source{FD: -1, Env: credential.IdentityEnv}.Read()
+		if err != nil {
+			return nil, err
+		}
+		if secret != nil {
+			for _, warning := range secret.Warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+			}
+			identities, err := crypto.ParseIdentities(strings.NewReader(secret.Value))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", credential.IdentityEnv, err)
+			}
+			d.identities = identities
+			return identities, nil
+		}
+	}
+	path := d.identityFile
+	if path == "" {
+		var err error
+		path, err = recipients.IdentityPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	identities, err := recipients.LoadIdentities(path)
+	if err != nil {
+		return nil, err
+	}
+	d.identities = identities
+	return identities, nil
+}
+
+// encryptLike encrypts content the way the original data was encrypted: to the project
+// recipients for files encrypted to recipients, otherwise with the password at the KDF
+// cost of the original. The result is armored if the original is.
+func encryptLike(original string, content []byte, d *fileDecrypter) (string, error) {
+	if crypto.IsAge(original) {
+		list, path, err := loadRecipients()
+		if err != nil {
+			return "", err
+		}
+		if len(list.Entries) == 0 {
+			return "", fmt.Errorf("no recipients in %s", displayPath(path))
+		}
+		return encryptAs(original, content, func(w io.Writer, armor bool) (io.WriteCloser, error) {
+			return crypto.NewRecipientsWriter(w, list.Recipients(), armor)
+		})
+	}
+
+	params := crypto.DefaultKDFParams()
+	if originalParams, err := crypto.ParamsOf(original); err == nil {
+		params = originalParams
+	}
+	password, err := d.getPassword()
+	if err != nil {
+		return "", err
+	}
+	return encryptAs(original, content, func(w io.Writer, armor bool) (io.WriteCloser, error) {
+		return crypto.NewEncryptWriter(w, password, params, armor)
+	})
+}
+
+// encryptAs encrypts content with a writer from newWriter, armored if the original data is
+func encryptAs(original string, content []byte, newWriter func(w io.Writer, armor bool) (io.WriteCloser, error)) (string, error) {
+	var out bytes.Buffer
+	w, err := newWriter(&out, crypto.IsArmored(original))
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(content); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// encryptFile encrypts a file as it is read, so large files are not held in memory. The
+// output is only written when encryption succeeds.
+func encryptFile(inputFile, outputFile string, newWriter func(w io.Writer) (io.WriteCloser, error)) error {
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	return writeFileJournaled(outputFile, func(w io.Writer) error {
+		encrypter, err := newWriter(w)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(encrypter, in); err != nil {
+			return err
+		}
+		return encrypter.Close()
+	})
+}
+
 // NewEncryptCmd returns the encrypt command
 func NewEncryptCmd() *cobra.Command {
-	return &cobra.Command{
+	var upgrade bool
+	var toRecipients bool
+	var binary bool
+	var kdf kdfOptions
+	var secret secretOptions
+	var output outputOptions
+
+	cmd := &cobra.Command{
 		Use:   "encrypt [file]",
-		Short: "Encrypt a file using AES-256",
-		Long:  `Encrypts the specified file using AES-256-CBC encryption with PBKDF2 key derivation.`,
-		Args:  cobra.MaximumNArgs(1),
+		Short: "Encrypt a file using AES-256-GCM",
+		Long: `Encrypts the specified file using AES-256-GCM authenticated encryption with a key derived
+from the password by a memory-hard KDF. The output starts with a versioned header recording
+the cipher and key derivation parameters, and any modification of the file is detected on
+decryption.
+
+The file is encrypted in 64 KiB chunks as it is read, so files of any size are encrypted
+and decrypted with little memory; reordered, dropped or truncated chunks are detected.
+The output is ASCII armored text, or smaller binary with --binary (for large or binary
+files that are not reviewed in diffs).
+
+The key derivation function is Argon2id by default, or scrypt or PBKDF2-SHA256 with --kdf.
+--kdf-profile sets its cost: interactive, moderate (default) or sensitive. --kdf-target
+benchmarks this machine instead and picks parameters that unlock in about the given time.
+Defaults can be set in .envdoc.yaml:
+
+  encryption:
+    kdf: argon2id
+    kdf_profile: sensitive
+
+--recipients encrypts to the public keys of the project recipients file instead of a
+password, in the age format (see 'envdoc recipients'). Every recipient decrypts the file
+with their own identity, and 'envdoc recipients add/remove' re-encrypts it when the team
+changes.
+
+--upgrade rewrites files encrypted by older versions of envdoc (AES-256-CBC without
+authentication, or AES-256-GCM over the whole file) in the current format with the same
+password; with KDF flags it also re-encrypts current files whose parameters differ. Without file arguments it upgrades
+every *.encrypted file in the current directory.
+
+` + outputFileHelp + `
+
+` + passwordSourcesHelp,
+		Example: `  envdoc encrypt .env
+  envdoc encrypt .env --kdf-profile sensitive
+  envdoc encrypt .env --kdf-target 2s
+  envdoc encrypt .env.production --recipients
+  envdoc encrypt fixtures.sql --binary
+  envdoc encrypt --upgrade .env.production.encrypted
+  envdoc encrypt .env.production --password-cmd "pass show envdoc/production"
+  envdoc encrypt .env --password-file pw.txt -o .env.encrypted --force`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if upgrade {
+				return nil
+			}
+			return cobra.MaximumNArgs(1)(cmd, args)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
+			params, err := kdf.params()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if upgrade {
+				upgradeEncryptedFiles(args, params, kdf.explicit(), secret)
+				return
+			}
+
 			var inputFile string
-			var err error
 
 			// Get input file
 			if len(args) > 0 {
@@ -181,70 +580,197 @@ func NewEncryptCmd() *cobra.Command {
 				os.Exit(1)
 			}
 
-			// Get password
-			password, err := utils.PromptForPassword("Enter encryption password:")
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				os.Exit(1)
-			}
-
-			if password == "" {
-				fmt.Println("Error: Password cannot be empty")
-				os.Exit(1)
+			if toRecipients {
+				encryptToRecipients(inputFile, binary, output)
+				return
 			}
 
-			// Confirm password
-			confirmPassword, err := utils.PromptForPassword("Confirm password:")
+			// Get password
+			password, err := secret.password("Enter encryption password:", true)
 			if err != nil {
 				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
 
-			if password != confirmPassword {
-				fmt.Println("Error: Passwords do not match")
-				os.Exit(1)
-			}
-
-			// Read input file
-			data, err := utils.ReadFromFile(inputFile)
+			// Get output filename
+			outputFile, err := output.path(inputFile + guard.EncryptedSuffix)
 			if err != nil {
-				fmt.Printf("Error reading file: %v\n", err)
+				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
 
 			// Encrypt
-			encrypted, err := crypto.Encrypt([]byte(data), password)
+			err = encryptFile(inputFile, outputFile, func(w io.Writer) (io.WriteCloser, error) {
+				return crypto.NewEncryptWriter(w, password, params, !binary)
+			})
 			if err != nil {
 				fmt.Printf("Error encrypting: %v\n", err)
 				os.Exit(1)
 			}
 
-			// Get output filename
-			defaultOutput := inputFile + ".encrypted"
-			outputFile, err := utils.PromptForOutputFile("Enter output filename:", defaultOutput)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				os.Exit(1)
+			fmt.Printf("✓ File encrypted: %s (%s)\n", outputFile, params)
+		},
+	}
+
+	cmd.Flags().BoolVar(&upgrade, "upgrade", false, "re-encrypt files in older formats with the current format")
+	cmd.Flags().BoolVar(&toRecipients, "recipients", false, "encrypt to the public keys of the project recipients file instead of a password")
+	cmd.Flags().BoolVar(&binary, "binary", false, "write binary output instead of armored text")
+	addOutputFlags(cmd, &output)
+	addKDFFlags(cmd, &kdf)
+	addPasswordFlags(cmd, &secret)
+
+	return cmd
+}
+
+// encryptToRecipients encrypts a file to the project recipients
+func encryptToRecipients(inputFile string, binary bool, output outputOptions) {
+	list, path, err := loadRecipients()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(list.Entries) == 0 {
+		fmt.Printf("Error: No recipients in %s; add them with 'envdoc recipients add'\n", displayPath(path))
+		os.Exit(1)
+	}
+
+	// Get output filename
+	outputFile, err := output.path(inputFile + guard.EncryptedSuffix)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Encrypt
+	err = encryptFile(inputFile, outputFile, func(w io.Writer) (io.WriteCloser, error) {
+		return crypto.NewRecipientsWriter(w, list.Recipients(), !binary)
+	})
+	if err != nil {
+		fmt.Printf("Error encrypting: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ File encrypted: %s (age, %d recipient(s))\n", outputFile, len(list.Entries))
+}
+
+// upgradeEncryptedFiles re-encrypts legacy files in the current format. With reparam, files
+// in the current format whose KDF parameters differ from params are re-encrypted as well.
+func upgradeEncryptedFiles(files []string, params crypto.KDFParams, reparam bool, secret secretOptions) {
+	// Get files
+	if len(files) == 0 {
+		entries, err := os.ReadDir(".")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), guard.EncryptedSuffix) {
+				files = append(files, entry.Name())
 			}
+		}
+		if len(files) == 0 {
+			fmt.Printf("No *%s files found in the current directory.\n", guard.EncryptedSuffix)
+			return
+		}
+	}
 
-			// Write output file
-			if err := utils.WriteToFile(outputFile, encrypted); err != nil {
-				fmt.Printf("Error writing file: %v\n", err)
+	// Find files in the legacy format
+	contents := make(map[string]string)
+	var legacy []string
+	for _, file := range files {
+		data, err := utils.ReadFromFile(file)
+		if err != nil {
+			fmt.Printf("Error reading '%s': %v\n", file, err)
+			os.Exit(1)
+		}
+		if crypto.IsAge(data) {
+			fmt.Printf("  - %s: age (X25519 recipients), managed by 'envdoc recipients'\n", file)
+			continue
+		}
+		version, err := crypto.Version(data)
+		if err != nil {
+			fmt.Printf("Error: '%s': %v\n", file, err)
+			os.Exit(1)
+		}
+		description, err := crypto.Describe(data)
+		if err != nil {
+			fmt.Printf("Error: '%s': %v\n", file, err)
+			os.Exit(1)
+		}
+		if version == crypto.CurrentVersion {
+			current, err := crypto.ParamsOf(data)
+			if err != nil {
+				fmt.Printf("Error: '%s': %v\n", file, err)
 				os.Exit(1)
 			}
+			if !reparam || current.Equal(params) {
+				fmt.Printf("  - %s: %s, up to date\n", file, description)
+				continue
+			}
+		}
+		fmt.Printf("  - %s: %s\n", file, description)
+		contents[file] = data
+		legacy = append(legacy, file)
+	}
+	if len(legacy) == 0 {
+		fmt.Printf("✓ All %d file(s) are up to date\n", len(files))
+		return
+	}
 
-			fmt.Printf("✓ File encrypted: %s\n", outputFile)
-		},
+	// Get password
+	password, err := secret.password("Enter decryption password:", false)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Decrypt everything before writing, so a wrong password changes nothing
+	var changes []fileChange
+	for _, file := range legacy {
+		decrypted, err := crypto.Decrypt(contents[file], password)
+		if err != nil {
+			fmt.Printf("Error decrypting '%s': %v\n", file, err)
+			os.Exit(1)
+		}
+		encrypted, err := encryptAs(contents[file], decrypted, func(w io.Writer, armor bool) (io.WriteCloser, error) {
+			return crypto.NewEncryptWriter(w, password, params, armor)
+		})
+		if err != nil {
+			fmt.Printf("Error encrypting '%s': %v\n", file, err)
+			os.Exit(1)
+		}
+		changes = append(changes, fileChange{file: file, before: contents[file], after: encrypted})
 	}
+
+	if err := writeChanges(changes); err != nil {
+		fmt.Printf("Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Upgraded %d file(s) to format v%d (%s)\n", len(changes), crypto.CurrentVersion, params)
 }
 
 // NewDecryptCmd returns the decrypt command
 func NewDecryptCmd() *cobra.Command {
-	return &cobra.Command{
+	var decrypter fileDecrypter
+	var output outputOptions
+
+	cmd := &cobra.Command{
 		Use:   "decrypt [file]",
 		Short: "Decrypt an encrypted file",
-		Long:  `Decrypts a file that was encrypted using the encrypt command.`,
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Decrypts a file that was encrypted using the encrypt command, armored or binary. The
+file is decrypted as it is read and the output is only written once all of it is
+authenticated, so a truncated or modified file leaves no partial output. Files in older
+formats are detected and still decrypted; 'envdoc encrypt --upgrade' rewrites them in the
+current format.
+
+Files encrypted to recipients are decrypted with your identity instead of a password
+(created by 'envdoc keygen', or another age identity file with --identity). In scripts,
+the secret keys can be given in ENVDOC_IDENTITY instead.
+
+` + outputFileHelp + `
+
+` + passwordSourcesHelp,
+		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var inputFile string
 			var err error
@@ -266,22 +792,14 @@ func NewDecryptCmd() *cobra.Command {
 				os.Exit(1)
 			}
 
-			// Get password
-			password, err := utils.PromptForPassword("Enter decryption password:")
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				os.Exit(1)
-			}
-
-			// Read input file
-			data, err := utils.ReadFromFile(inputFile)
+			// Read the header, which tells the secret to ask for
+			in, err := os.Open(inputFile)
 			if err != nil {
 				fmt.Printf("Error reading file: %v\n", err)
 				os.Exit(1)
 			}
-
-			// Decrypt
-			decrypted, err := crypto.Decrypt(data, password)
+			defer func() { _ = in.Close() }()
+			decrypted, err := crypto.NewDecryptReader(in, decrypter.keys())
 			if err != nil {
 				fmt.Printf("Error decrypting: %v\n", err)
 				os.Exit(1)
@@ -292,20 +810,31 @@ func NewDecryptCmd() *cobra.Command {
 			if defaultOutput == inputFile {
 				defaultOutput = filepath.Base(inputFile) + ".decrypted"
 			}
-
-			outputFile, err := utils.PromptForOutputFile("Enter output filename:", defaultOutput)
+			outputFile, err := output.path(defaultOutput)
 			if err != nil {
 				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
 
-			// Write output file
-			if err := utils.WriteToFile(outputFile, string(decrypted)); err != nil {
-				fmt.Printf("Error writing file: %v\n", err)
+			// Decrypt, writing the output only once the whole file is authenticated
+			err = writeFileJournaled(outputFile, func(w io.Writer) error {
+				_, err := io.Copy(w, decrypted)
+				return err
+			})
+			if err != nil {
+				fmt.Printf("Error decrypting: %v\n", err)
 				os.Exit(1)
 			}
 
 			fmt.Printf("✓ File decrypted: %s\n", outputFile)
+			if decrypted.Version == crypto.LegacyVersion {
+				fmt.Printf("Note: '%s' uses the legacy unauthenticated format; 'envdoc encrypt --upgrade %s' rewrites it.\n", inputFile, inputFile)
+			}
 		},
 	}
+
+	decrypter.addFlags(cmd)
+	addOutputFlags(cmd, &output)
+
+	return cmd
 }
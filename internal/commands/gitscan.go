@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MayR-Labs/envdoc-go/internal/gitscan"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/spf13/cobra"
+)
+
+// NewGitScanCmd returns the git-scan command
+func NewGitScanCmd() *cobra.Command {
+	var since string
+	var known baselineOptions
+	var output reportOptions
+
+	cmd := &cobra.Command{
+		Use:   "git-scan [dir]",
+		Short: "Scan git history for committed env files and leaked secrets",
+		Long: `Walks every commit reachable from any branch or tag of the local repository and
+reports plaintext env files that were ever committed and values that look like real
+credentials, even when they were deleted later. Only the local object database is read;
+nothing is fetched.
+
+Each finding is reported once, at the oldest commit it appears in. Use --since to limit
+the walk and --baseline to hide findings that are already known.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			root := repositoryRoot(dir)
+			findings, err := gitscan.Scan(root, gitscan.Options{Since: since})
+			if err != nil {
+				fmt.Printf("Error scanning history: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Generate report
+			scanReport := generateGitScanReport(root, since, findings)
+			applyBaseline(scanReport, known)
+			if len(scanReport.AllFindings()) > 0 {
+				scanReport.AddSection(remediationSection())
+			}
+
+			// Show options
+			handleReportOutput(scanReport, "envdoc-git-scan", output)
+
+			if len(scanReport.AllFindings()) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "only scan commits newer than this date (e.g. 2024-01-01 or \"6 months ago\")")
+	addBaselineFlags(cmd, &known)
+	addReportFlags(cmd, &output)
+
+	return cmd
+}
+
+func generateGitScanReport(root, since string, findings []gitscan.Finding) *report.Report {
+	r := report.New("Git History Scan Report")
+
+	var envFiles, leaked []report.Finding
+	for _, finding := range findings {
+		firstSeen := fmt.Sprintf("%s (%s) by %s", shortCommit(finding.Commit), shortDate(finding.Date), finding.Author)
+		state := "removed from the tree but still in history"
+		if finding.Tracked {
+			state = "still tracked"
+		}
+
+		if finding.Rule == gitscan.CommittedEnvFile {
+			envFiles = append(envFiles, report.Finding{
+				Severity:    report.Error,
+				Rule:        finding.Rule,
+				File:        finding.Path,
+				Message:     fmt.Sprintf("First committed in %s, %s", firstSeen, state),
+				Fingerprint: finding.Fingerprint,
+			})
+			continue
+		}
+
+		leaked = append(leaked, report.Finding{
+			Severity:   secretConfidenceSeverity[finding.Confidence],
+			Rule:       finding.Rule,
+			File:       finding.Path,
+			Line:       finding.Line,
+			Key:        finding.Key,
+			Value:      finding.MaskedValue,
+			Secret:     finding.Value,
+			Confidence: string(finding.Confidence),
+			Message:    fmt.Sprintf("%s, first seen in %s, %s", finding.Description, firstSeen, state),
+		})
+	}
+	report.SortFindings(envFiles)
+	report.SortFindings(leaked)
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddCodeField("Repository", root)
+	if since != "" {
+		overview.AddField("Since", since)
+	} else {
+		overview.AddField("Since", "Beginning of history")
+	}
+	overview.AddField("Committed Env Files", len(envFiles))
+	overview.AddField("Potential Secrets", len(leaked))
+	r.AddSection(overview)
+
+	r.AddSection(report.Section{
+		Title:    "Committed Env Files",
+		Findings: envFiles,
+		Empty:    "✓ No plaintext env files found in history.",
+	})
+	r.AddSection(report.Section{
+		Title:    "Secrets in History",
+		Findings: leaked,
+		Empty:    "✓ No potential secrets found in history.",
+	})
+
+	return r
+}
+
+// remediationSection explains how to clean up after secrets leaked into history
+func remediationSection() report.Section {
+	return report.Section{
+		Title: "Remediation",
+		Text:  "Deleting a file does not remove it from history: anyone with a clone can still read it.",
+		List: []string{
+			"Rotate every exposed credential first. Rewriting history does not undo a leak.",
+			"Stop tracking env files with `git rm --cached <file>` and add them to `.gitignore`.",
+			"Remove them from history with `git filter-repo --invert-paths --path <file>` (or BFG), then force-push and ask collaborators to re-clone.",
+			"Commit encrypted copies instead with `envdoc encrypt`.",
+			"Record findings that are accepted or already rotated with `--update-baseline`.",
+		},
+	}
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
+// shortDate keeps the date part of an ISO 8601 timestamp
+func shortDate(date string) string {
+	if len(date) > 10 {
+		return date[:10]
+	}
+	return date
+}
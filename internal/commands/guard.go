@@ -0,0 +1,338 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/git"
+	"github.com/MayR-Labs/envdoc-go/internal/guard"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// hookMarker identifies pre-commit hooks written by envdoc
+const hookMarker = "# envdoc pre-commit hook"
+
+// preCommitConfig is the pre-commit framework config file name
+const preCommitConfig = ".pre-commit-config.yaml"
+
+// NewGuardCmd returns the guard command
+func NewGuardCmd() *cobra.Command {
+	var output reportOptions
+
+	cmd := &cobra.Command{
+		Use:   "guard [dir]",
+		Short: "Check that env files are gitignored, untracked and have encrypted copies",
+		Long: `Checks the git hygiene of every env file in the repository:
+- plaintext env files must be covered by .gitignore and must not be tracked
+- .env, .env.local and .env.*.local must be ignored even before they exist
+- every shared environment (e.g. .env.production) should have a committed
+  encrypted copy created with 'envdoc encrypt'
+
+Templates such as .env.example are expected to be committed and are not flagged.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			root := repositoryRoot(dir)
+			issues, files, err := guard.CheckRepository(root)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Generate report
+			guardReport := generateGuardReport(root, files, issues)
+
+			// Show options
+			handleReportOutput(guardReport, "envdoc-guard", output)
+
+			if guardReport.CountSeverity(report.Error) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	addReportFlags(cmd, &output)
+
+	return cmd
+}
+
+// NewHookCmd returns the hook command with its install, uninstall and run subcommands
+func NewHookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Manage the pre-commit hook that blocks plaintext env files and secrets",
+		Long: `The pre-commit hook blocks commits that stage plaintext env files or values
+matching known credential formats, and validates staged env files against a schema.`,
+	}
+
+	cmd.AddCommand(newHookInstallCmd())
+	cmd.AddCommand(newHookUninstallCmd())
+	cmd.AddCommand(newHookRunCmd())
+
+	return cmd
+}
+
+func newHookInstallCmd() *cobra.Command {
+	var schemaFile string
+	var framework bool
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the envdoc pre-commit hook",
+		Long: `Installs a git pre-commit hook that runs 'envdoc hook run'. With --framework,
+a local hook is added to .pre-commit-config.yaml for the pre-commit framework instead.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			root := repositoryRoot(".")
+
+			entry := "envdoc hook run"
+			if schemaFile != "" {
+				if !utils.FileExists(filepath.Join(root, schemaFile)) {
+					fmt.Printf("Error: Schema file '%s' does not exist in the repository root\n", schemaFile)
+					os.Exit(1)
+				}
+				entry += " --schema " + shellQuote(schemaFile)
+			}
+
+			if framework {
+				installPreCommitConfig(root, entry)
+				return
+			}
+
+			hooksDir, err := git.HooksDir(root)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			hookFile := filepath.Join(hooksDir, "pre-commit")
+
+			// Existing hooks that envdoc did not write are only replaced after confirmation
+			if existing, err := os.ReadFile(hookFile); err == nil && !strings.Contains(string(existing), hookMarker) {
+				confirmed, err := utils.ConfirmWithPin(fmt.Sprintf("A pre-commit hook already exists at '%s' and will be replaced.", hookFile))
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if !confirmed {
+					fmt.Println("Operation cancelled.")
+					return
+				}
+			}
+
+			script := fmt.Sprintf("#!/bin/sh\n%s\n# Remove this file or run 'envdoc hook uninstall' to disable it.\nexec %s\n", hookMarker, entry)
+			if err := os.MkdirAll(hooksDir, 0755); err != nil {
+				fmt.Printf("Error creating hooks directory: %v\n", err)
+				os.Exit(1)
+			}
+			if err := os.WriteFile(hookFile, []byte(script), 0755); err != nil {
+				fmt.Printf("Error writing hook: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Pre-commit hook installed: %s\n", hookFile)
+		},
+	}
+
+	cmd.Flags().StringVarP(&schemaFile, "schema", "s", "", "JSON schema to validate staged env files against (relative to the repository root)")
+	cmd.Flags().BoolVar(&framework, "framework", false, "add the hook to "+preCommitConfig+" for the pre-commit framework")
+
+	return cmd
+}
+
+func newHookUninstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the envdoc pre-commit hook",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			root := repositoryRoot(".")
+			hooksDir, err := git.HooksDir(root)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			hookFile := filepath.Join(hooksDir, "pre-commit")
+
+			existing, err := os.ReadFile(hookFile)
+			if err != nil || !strings.Contains(string(existing), hookMarker) {
+				fmt.Println("No envdoc pre-commit hook installed.")
+				return
+			}
+			if err := os.Remove(hookFile); err != nil {
+				fmt.Printf("Error removing hook: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Pre-commit hook removed: %s\n", hookFile)
+		},
+	}
+}
+
+func newHookRunCmd() *cobra.Command {
+	var schemaFile string
+
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Check staged files (called by the pre-commit hook)",
+		Long: `Checks the files staged for commit and exits with status 1 if any plaintext env
+file or known credential is staged, or if a staged env file does not match the schema.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			root := repositoryRoot(".")
+
+			var schemaJSON string
+			if schemaFile != "" {
+				var err error
+				schemaJSON, err = utils.ReadFromFile(schemaFile)
+				if err != nil {
+					fmt.Printf("Error reading schema: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			issues, staged, err := guard.CheckStaged(root, schemaJSON)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if len(issues) == 0 {
+				fmt.Printf("✓ envdoc: %d staged file(s) checked\n", len(staged))
+				return
+			}
+
+			fmt.Println("envdoc: commit blocked")
+			for _, issue := range issues {
+				location := issue.File
+				if issue.Line > 0 {
+					location = fmt.Sprintf("%s:%d", location, issue.Line)
+				}
+				if issue.Key != "" {
+					location = fmt.Sprintf("%s %s", location, issue.Key)
+				}
+				if issue.Value != "" {
+					location = fmt.Sprintf("%s (%s)", location, issue.Value)
+				}
+				fmt.Printf("  ✗ %s: %s\n", location, issue.Message)
+			}
+			fmt.Println("\nFix the issues above, or bypass the check with 'git commit --no-verify'.")
+			os.Exit(1)
+		},
+	}
+
+	cmd.Flags().StringVarP(&schemaFile, "schema", "s", "", "JSON schema to validate staged env files against")
+
+	return cmd
+}
+
+func generateGuardReport(root string, files []string, issues []guard.Issue) *report.Report {
+	r := report.New("Environment Files Git Guard Report")
+
+	sections := map[string]*report.Section{
+		"ignore":    {Title: "Ignore Coverage", Empty: "✓ All env files are covered by .gitignore."},
+		"tracked":   {Title: "Tracked Plaintext Files", Empty: "✓ No plaintext env files are tracked."},
+		"encrypted": {Title: "Encrypted Copies", Empty: "✓ Every shared environment has a committed encrypted copy."},
+	}
+	sectionOf := map[string]string{
+		"not-ignored":             "ignore",
+		"gitignore-pattern":       "ignore",
+		"tracked-env-file":        "tracked",
+		"missing-encrypted":       "encrypted",
+		"encrypted-not-committed": "encrypted",
+	}
+	for _, issue := range issues {
+		section := sections[sectionOf[issue.Rule]]
+		section.Findings = append(section.Findings, report.Finding{
+			Severity: issue.Severity,
+			Rule:     issue.Rule,
+			File:     issue.File,
+			Key:      issue.Key,
+			Message:  issue.Message,
+		})
+	}
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddCodeField("Repository", root)
+	overview.AddField("Env Files Found", len(files))
+	r.AddSection(overview)
+
+	for _, key := range []string{"ignore", "tracked", "encrypted"} {
+		report.SortFindings(sections[key].Findings)
+		r.AddSection(*sections[key])
+	}
+
+	r.AddSection(report.Section{
+		Title: "Env Files",
+		List:  quoteAll(files),
+		Empty: "No env files found.",
+	})
+
+	return r
+}
+
+// installPreCommitConfig adds a local envdoc hook to the pre-commit framework config
+func installPreCommitConfig(root, entry string) {
+	configFile := filepath.Join(root, preCommitConfig)
+	hook := fmt.Sprintf(`  - repo: local
+    hooks:
+      - id: envdoc
+        name: envdoc
+        entry: %s
+        language: system
+        pass_filenames: false
+`, entry)
+
+	existing, err := os.ReadFile(configFile)
+	switch {
+	case os.IsNotExist(err):
+		if err := utils.WriteToFile(configFile, "repos:\n"+hook); err != nil {
+			fmt.Printf("Error writing file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Created %s. Run 'pre-commit install' to activate it.\n", configFile)
+	case err != nil:
+		fmt.Printf("Error reading %s: %v\n", configFile, err)
+		os.Exit(1)
+	case strings.Contains(string(existing), "envdoc hook run"):
+		fmt.Printf("%s already runs envdoc.\n", configFile)
+	default:
+		fmt.Printf("Add this entry under 'repos:' in %s:\n\n%s\n", configFile, hook)
+	}
+}
+
+// repositoryRoot returns the work tree root of the repository containing dir, exiting if there is none
+func repositoryRoot(dir string) string {
+	if !git.IsRepository(dir) {
+		fmt.Printf("Error: '%s' is not inside a git repository\n", dir)
+		os.Exit(1)
+	}
+	root, err := git.TopLevel(dir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return root
+}
+
+// shellQuote quotes an argument for a POSIX shell script when needed
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`&|;<>()*?[]#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quoteAll wraps each value in backticks for display
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "`" + value + "`"
+	}
+	return quoted
+}
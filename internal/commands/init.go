@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/MayR-Labs/envdoc-go/internal/validator"
+	"github.com/spf13/cobra"
+)
+
+// NewInitCmd returns the init command
+func NewInitCmd() *cobra.Command {
+	var schemaFile, outputFile string
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Interactively create a .env file from a JSON schema",
+		Long: `Walks through every key defined in the JSON schema, grouped by prefix, and asks
+for its value. Descriptions and defaults come from the schema, answers are validated
+inline, and keys that already have a valid value in the output file are skipped. An
+invalid value is offered as the answer to correct, and an existing file is completed in
+place, keeping its comments and layout.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+
+			// Get schema file
+			if schemaFile == "" {
+				schemaFile, err = utils.PromptForAnyFile("Select the schema file:")
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if !utils.FileExists(schemaFile) {
+				fmt.Printf("Error: Schema file '%s' does not exist\n", schemaFile)
+				os.Exit(1)
+			}
+
+			// Read schema
+			schemaJSON, err := utils.ReadFromFile(schemaFile)
+			if err != nil {
+				fmt.Printf("Error reading schema: %v\n", err)
+				os.Exit(1)
+			}
+			schema, err := validator.ParseSchema(schemaJSON)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Load the existing file, if any
+			var doc *parser.Document
+			if utils.FileExists(outputFile) {
+				doc, err = parser.ReadDocument(outputFile)
+				if err != nil {
+					fmt.Printf("Error parsing file: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			// Ask for every key that doesn't already have a valid value. An invalid value is
+			// offered as the answer to correct.
+			answers := make(map[string]string)
+			skipped := 0
+			lastPrefix := ""
+			for _, key := range schema.Keys() {
+				prop := schema.Properties[key]
+
+				var current string
+				if doc != nil {
+					current, _ = doc.Value(key)
+				}
+				if current != "" && (parser.IsSealed(current) || validator.ValidateValue(prop, current) == nil) {
+					skipped++
+					continue
+				}
+
+				if prefix := parser.GetPrefix(key); prefix != lastPrefix {
+					fmt.Printf("\n── %s ──\n", prefix)
+					lastPrefix = prefix
+				}
+
+				value, err := promptForSchemaValue(key, prop, schema.IsRequired(key), parser.Unquote(current))
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				answers[key] = value
+			}
+
+			if len(answers) == 0 {
+				fmt.Printf("✓ All %d keys in '%s' already have valid values\n", skipped, outputFile)
+				return
+			}
+
+			// An existing file is edited in place, keeping its comments and layout
+			var content string
+			if doc != nil {
+				applyInitAnswers(doc, answers, schema)
+				content = doc.String()
+			} else {
+				content = parser.FormatEnv(initEnvVars(answers, schema))
+			}
+			change, err := newFileChange(outputFile, content)
+			if err != nil {
+				fmt.Printf("Error reading file: %v\n", err)
+				os.Exit(1)
+			}
+			if err := writeChanges([]fileChange{change}); err != nil {
+				fmt.Printf("Error writing file: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("\n✓ File written: %s\n", outputFile)
+			fmt.Printf("✓ %d keys set, %d keys already valid\n", len(answers), skipped)
+		},
+	}
+
+	cmd.Flags().StringVarP(&schemaFile, "schema", "s", "", "JSON schema describing the environment")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", ".env", "env file to create or complete")
+
+	return cmd
+}
+
+// promptForSchemaValue asks for a single key using the prompt that best fits its schema type.
+// The current value, if any, is offered instead of the schema default.
+func promptForSchemaValue(key string, prop validator.Property, required bool, current string) (string, error) {
+	defaultValue := prop.DefaultString()
+	if current != "" {
+		defaultValue = current
+	}
+	message := key + ":"
+	if prop.Description != "" {
+		fmt.Printf("  # %s\n", prop.Description)
+	}
+
+	validate := func(value string) error {
+		if value == "" {
+			value = defaultValue
+		}
+		if value == "" {
+			if required {
+				return fmt.Errorf("%s is required", key)
+			}
+			return nil
+		}
+		return validator.ValidateValue(prop, value)
+	}
+
+	switch {
+	case len(prop.Enum) > 0:
+		return utils.PromptForChoice(message, prop.Description, prop.Enum, defaultValue)
+	case prop.Type == "boolean":
+		def, _ := strconv.ParseBool(defaultValue)
+		answer, err := utils.PromptForBool(message, prop.Description, def)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(answer), nil
+	case prop.Secret || prop.Format == "password":
+		value, err := utils.PromptForSecret(message, prop.Description, validate)
+		if err != nil {
+			return "", err
+		}
+		if value == "" {
+			value = defaultValue
+		}
+		return value, nil
+	default:
+		return utils.PromptForValue(message, defaultValue, prop.Description, validate)
+	}
+}
+
+// applyInitAnswers sets the answers in an existing document and inserts new keys next to
+// their neighbours in the schema, with their description as a comment
+func applyInitAnswers(doc *parser.Document, answers map[string]string, schema *validator.Schema) {
+	order := schema.Keys()
+	for _, key := range order {
+		value, ok := answers[key]
+		if !ok {
+			continue
+		}
+		if doc.Has(key) {
+			doc.Set(key, quoteIfNeeded(value))
+			continue
+		}
+		doc.Insert(parser.EnvVar{Key: key, Value: quoteIfNeeded(value), Comment: initComment(schema, key)}, order)
+	}
+}
+
+// initEnvVars returns the answers as the variables of a new file, grouped by prefix
+func initEnvVars(answers map[string]string, schema *validator.Schema) []parser.EnvVar {
+	var result []parser.EnvVar
+	lastPrefix := ""
+	for _, key := range schema.Keys() {
+		value, ok := answers[key]
+		if !ok {
+			continue
+		}
+
+		prefix := parser.GetPrefix(key)
+		if lastPrefix != "" && prefix != lastPrefix {
+			result[len(result)-1].BlankAfter = true
+		}
+		lastPrefix = prefix
+
+		result = append(result, parser.EnvVar{
+			Key:     key,
+			Value:   quoteIfNeeded(value),
+			Comment: initComment(schema, key),
+		})
+	}
+	return result
+}
+
+// initComment returns the comment written above a new key: its schema description
+func initComment(schema *validator.Schema, key string) string {
+	if description := schema.Properties[key].Description; description != "" {
+		return "# " + description
+	}
+	return ""
+}
+
+// quoteIfNeeded wraps values containing whitespace or comment characters in double quotes
+func quoteIfNeeded(value string) string {
+	if parser.Unquote(value) != value {
+		return value
+	}
+	if strings.ContainsAny(value, " \t#") {
+		return "\"" + strings.ReplaceAll(value, "\"", "\\\"") + "\""
+	}
+	return value
+}
@@ -0,0 +1,363 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MayR-Labs/envdoc-go/internal/config"
+	"github.com/MayR-Labs/envdoc-go/internal/git"
+	"github.com/MayR-Labs/envdoc-go/internal/journal"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewHistoryCmd returns the history command
+func NewHistoryCmd() *cobra.Command {
+	var limit int
+	var clear bool
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the file changes recorded in the journal",
+		Long: `Lists the operations recorded in the journal, newest first. Every command that modifies
+env files (arrange, clear-values, sync, merge, engineer, lint --fix, init, from, seal, unseal,
+recipients add/remove, rekey) records the previous content of the files in .envdoc/journal
+at the project root before writing, so 'envdoc undo' can restore them.
+
+Snapshots are encrypted with AES-256-GCM using a key stored in the user's config directory,
+outside the project. Retention and recording are configured in .envdoc.yaml:
+
+  journal:
+    max_entries: 50     # operations kept (default 50)
+    max_age_days: 30    # days operations are kept (default 30)
+    disabled: false     # stop recording
+
+--clear removes every recorded operation.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			j, enabled, err := openJournal()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if clear {
+				confirmed, err := utils.ConfirmWithPin("This will delete every recorded operation; they can no longer be undone.")
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if !confirmed {
+					fmt.Println("Operation cancelled.")
+					return
+				}
+				if err := j.Clear(); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Println("✓ Journal cleared")
+				return
+			}
+
+			if err := j.Prune(time.Now()); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			entries, err := j.Entries()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Journal: %s (%d operation(s), keeping %d for %d days)\n",
+				displayPath(j.Dir()), len(entries), j.Retention.MaxEntries, int(j.Retention.MaxAge.Hours()/24))
+			if !enabled {
+				fmt.Println("Recording is disabled in .envdoc.yaml.")
+			}
+			if len(entries) == 0 {
+				fmt.Println("No operations recorded.")
+				return
+			}
+
+			fmt.Println()
+			shown := 0
+			for i := len(entries) - 1; i >= 0 && (limit <= 0 || shown < limit); i-- {
+				entry := entries[i]
+				fmt.Printf("%s  %s  %s\n", entry.ID, entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Command)
+
+				var files []string
+				for _, file := range entry.Files {
+					files = append(files, displayPath(file.Path))
+				}
+				fmt.Printf("    Files: %s\n", strings.Join(files, ", "))
+				if entry.UndoOf != "" {
+					fmt.Printf("    Undo of %s\n", entry.UndoOf)
+				}
+				if entry.UndoneBy != "" {
+					fmt.Printf("    Undone by %s\n", entry.UndoneBy)
+				}
+				shown++
+			}
+			if shown < len(entries) {
+				fmt.Printf("\n%d older operation(s) not shown (use --limit 0 to list all).\n", len(entries)-shown)
+			}
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "number of operations to list (0 for all)")
+	cmd.Flags().BoolVar(&clear, "clear", false, "delete every recorded operation")
+
+	return cmd
+}
+
+// NewUndoCmd returns the undo command
+func NewUndoCmd() *cobra.Command {
+	var force bool
+	var preview previewOptions
+
+	cmd := &cobra.Command{
+		Use:   "undo [id]",
+		Short: "Restore the files changed by a recorded operation",
+		Long: `Restores the files changed by an operation recorded in the journal to their previous
+content; files the operation created are removed. Without an ID the most recent operation
+that has not been undone is reverted, so repeated undos walk back through the history.
+IDs are listed by 'envdoc history' and can be shortened to a unique prefix.
+
+The undo is itself recorded, so undoing it restores the operation again. Files modified
+since the operation are not overwritten unless --force is given. The restored content is
+shown as a unified diff before confirming (--dry-run stops there).`,
+		Example: `  envdoc undo
+  envdoc undo 20250101-120000-ab12 --dry-run`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			j, _, err := openJournal()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Find the operation
+			var id string
+			if len(args) > 0 {
+				id = args[0]
+			}
+			entry, err := j.Find(id)
+			if err == journal.ErrNotFound && id == "" {
+				fmt.Println("✓ Nothing to undo")
+				return
+			}
+			if err == journal.ErrNotFound {
+				fmt.Printf("Error: No journal entry '%s' (see 'envdoc history')\n", id)
+				os.Exit(1)
+			}
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if entry.UndoneBy != "" {
+				fmt.Printf("Error: %s was already undone by %s\n", entry.ID, entry.UndoneBy)
+				os.Exit(1)
+			}
+
+			// Restore the previous content of every file
+			var changes []fileChange
+			var modified []string
+			for _, file := range entry.Files {
+				isModified, err := j.Modified(file)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if isModified {
+					modified = append(modified, displayPath(file.Path))
+				}
+
+				previous, err := j.Restore(entry, file)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				change, err := newFileChange(displayPath(file.Path), previous)
+				if err != nil {
+					fmt.Printf("Error reading file: %v\n", err)
+					os.Exit(1)
+				}
+				if !file.Existed {
+					if change.created {
+						// Created by the operation and already gone
+						continue
+					}
+					change.after = ""
+					change.deleted = true
+				}
+				changes = append(changes, change)
+			}
+
+			if len(modified) > 0 && !force {
+				fmt.Printf("Error: These files changed since %s and would lose those changes:\n", entry.ID)
+				for _, file := range modified {
+					fmt.Printf("  - %s\n", file)
+				}
+				fmt.Println("Use --force to restore them anyway.")
+				os.Exit(1)
+			}
+
+			// Show preview
+			if !preview.quiet() {
+				fmt.Printf("Undoing %s (%s): %s\n", entry.ID, entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Command)
+			}
+			if !showPreview(changes, preview) {
+				return
+			}
+
+			// Confirm action with PIN
+			confirmed, err := utils.ConfirmWithPin(fmt.Sprintf("This will restore %d file(s) to their content before %s.", len(changes), entry.ID))
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if !confirmed {
+				fmt.Println("Operation cancelled.")
+				return
+			}
+
+			undoEntry, err := writeJournaled(changes, entry.ID)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Mark the operation as undone; undoing an undo makes its operation undoable again
+			undoneBy := "an unrecorded undo"
+			if undoEntry != nil {
+				undoneBy = undoEntry.ID
+			}
+			if err := j.SetUndoneBy(entry.ID, undoneBy); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if entry.UndoOf != "" {
+				if err := j.SetUndoneBy(entry.UndoOf, ""); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			fmt.Printf("✓ Undid %s (%d file(s) restored)\n", entry.ID, len(changes))
+			if undoEntry != nil {
+				fmt.Printf("Recorded as %s; 'envdoc undo %s' restores the changes again.\n", undoEntry.ID, undoEntry.ID)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "restore files even if they changed since the operation")
+	addPreviewFlags(cmd, &preview)
+
+	return cmd
+}
+
+// projectRoot returns the root of the current project: the git repository, or the current
+// directory outside one
+func projectRoot() string {
+	if git.IsRepository(".") {
+		if top, err := git.TopLevel("."); err == nil {
+			return top
+		}
+	}
+	return "."
+}
+
+// openJournal opens the journal of the current project and reports whether recording is enabled
+func openJournal() (*journal.Journal, bool, error) {
+	root := projectRoot()
+	cfg, err := config.LoadFrom(root)
+	if err != nil {
+		return nil, false, err
+	}
+	retention := journal.Retention{
+		MaxEntries: cfg.Journal.MaxEntries,
+		MaxAge:     time.Duration(cfg.Journal.MaxAgeDays) * 24 * time.Hour,
+	}
+	return journal.Open(root, retention), !cfg.Journal.Disabled, nil
+}
+
+// recordChanges records the previous content of the files in the journal. It returns nil
+// when recording is disabled.
+func recordChanges(changes []fileChange, undoOf string) (*journal.Entry, error) {
+	j, enabled, err := openJournal()
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	var records []journal.Change
+	for _, change := range changes {
+		var beforeFile string
+		if change.beforeOnDisk {
+			beforeFile = change.file
+		}
+		records = append(records, journal.Change{
+			Path:       change.file,
+			Before:     change.before,
+			BeforeFile: beforeFile,
+			Existed:    !change.created,
+			After:      change.after,
+			AfterFile:  change.staged,
+			Exists:     !change.deleted,
+		})
+	}
+	entry, err := j.Record(redactCommand(os.Args[1:]), undoOf, records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record the journal (disable it with journal.disabled in .envdoc.yaml): %w", err)
+	}
+	return entry, nil
+}
+
+// secretFlags are the flags whose values are not recorded in the journal, which is stored
+// unencrypted: a password command can hold the password itself
+var secretFlags = map[string]bool{
+	"--password-file":     true,
+	"--password-fd":       true,
+	"--password-cmd":      true,
+	"--new-password-file": true,
+	"--new-password-fd":   true,
+	"--new-password-cmd":  true,
+}
+
+// redactCommand returns the command line recorded for an operation, with the values of the
+// secret flags replaced by ***
+func redactCommand(args []string) string {
+	redacted := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			redacted = append(redacted, args[i:]...)
+			break
+		}
+		name, _, hasValue := strings.Cut(arg, "=")
+		if !secretFlags[name] {
+			redacted = append(redacted, arg)
+			continue
+		}
+		redacted = append(redacted, name+"=***")
+		if !hasValue && i+1 < len(args) {
+			i++
+		}
+	}
+	return strings.Join(redacted, " ")
+}
+
+// displayPath returns a path relative to the current directory when it is inside it
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,324 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/k8s"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/secrets"
+	"github.com/MayR-Labs/envdoc-go/internal/validator"
+	"github.com/spf13/cobra"
+)
+
+// NewK8sCheckCmd returns the k8s-check command
+func NewK8sCheckCmd() *cobra.Command {
+	var envFiles, containers []string
+	var schemaFile string
+	var output reportOptions
+
+	cmd := &cobra.Command{
+		Use:   "k8s-check <manifests...>",
+		Short: "Cross-check env files or a schema against Kubernetes manifests",
+		Long: `Reads Kubernetes manifests (multi-document YAML files, directories, or - for rendered
+Helm or kustomize output on stdin), works out the variables each container receives
+through env (value, configMapKeyRef, secretKeyRef) and envFrom (configMapRef, secretRef),
+and compares them with the declared keys:
+- declared keys a container does not receive (for a schema, only required keys)
+- keys the schema marks as secret that come from a ConfigMap or a literal value
+- secretKeyRef and secretRef references to Secrets or keys the manifests do not generate
+
+Declared keys come from --env files and --schema. Without either, .env.example is used
+when present, otherwise every .env file in the current directory. Init containers are
+only checked for missing keys when selected with --container.`,
+		Example: `  envdoc k8s-check k8s/ --schema env.schema.json
+  helm template ./chart | envdoc k8s-check - --env .env.example
+  kustomize build overlays/prod | envdoc k8s-check - --container api --container worker`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			// Get declared keys
+			if len(envFiles) == 0 && schemaFile == "" {
+				var err error
+				envFiles, err = defaultDeclarationFiles()
+				if err != nil {
+					fmt.Printf("Error finding .env files: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			if len(envFiles) == 0 && schemaFile == "" {
+				fmt.Println("Error: No env files or schema to compare against. Use --env or --schema.")
+				os.Exit(1)
+			}
+			declared, schema, err := loadDeclarations(envFiles, schemaFile)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Parse manifests
+			manifests, err := k8s.Load(args, os.Stdin)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if len(manifests.Containers) == 0 {
+				fmt.Println("Error: No workloads with containers found in the manifests")
+				os.Exit(1)
+			}
+
+			// Generate report
+			k8sReport := generateK8sReport(manifests, envFiles, schemaFile, declared, schema, containers)
+
+			// Show options
+			handleReportOutput(k8sReport, "envdoc-k8s-check", output)
+
+			exitOnErrors(k8sReport)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&envFiles, "env", nil, "env files that declare keys (default: .env.example or all .env files)")
+	cmd.Flags().StringVar(&schemaFile, "schema", "", "JSON schema that declares keys and marks secrets")
+	cmd.Flags().StringSliceVar(&containers, "container", nil, "container name patterns to check for missing keys (default: all app containers)")
+	addReportFlags(cmd, &output)
+
+	return cmd
+}
+
+func generateK8sReport(manifests *k8s.Manifests, envFiles []string, schemaFile string, declared map[string]string, schema *validator.Schema, containerPatterns []string) *report.Report {
+	r := report.New("Kubernetes Manifests Cross-Check Report")
+
+	declaredKeys := make([]string, 0, len(declared))
+	for key := range declared {
+		declaredKeys = append(declaredKeys, key)
+	}
+	sort.Strings(declaredKeys)
+
+	var missing, exposed, references, undeclared []report.Finding
+	table := report.Table{Columns: []report.Column{
+		{Title: "Workload", Code: true}, {Title: "Container", Code: true}, {Title: "Variables"}, {Title: "From Secrets"}, {Title: "From ConfigMaps"}, {Title: "Literal"}, {Title: "Checked"},
+	}}
+	workloads := make(map[string]bool)
+
+	for _, c := range manifests.Containers {
+		workloads[c.Workload] = true
+		where := fmt.Sprintf("container `%s` of %s", c.Name, c.Workload)
+		counts := make(map[k8s.Source]int)
+		seen := make(map[string]bool)
+
+		for _, v := range c.Variables {
+			counts[v.Source]++
+			if seen[v.Name] {
+				continue
+			}
+			seen[v.Name] = true
+
+			if _, ok := declared[v.Name]; !ok {
+				undeclared = append(undeclared, report.Finding{
+					Severity: report.Info,
+					Rule:     "undeclared-variable",
+					File:     c.File,
+					Line:     c.Line,
+					Key:      v.Name,
+					Message:  fmt.Sprintf("Passed to %s but not declared", where),
+				})
+			}
+
+			if finding, ok := exposedSecret(v, schema); ok {
+				finding.File, finding.Line = c.File, c.Line
+				finding.Message += " in " + where
+				exposed = append(exposed, finding)
+			}
+
+			if finding, ok := unresolvedKeyRef(v, manifests); ok {
+				finding.File, finding.Line = c.File, c.Line
+				finding.Message += fmt.Sprintf(" (used by %s)", where)
+				references = append(references, finding)
+			}
+		}
+
+		var unresolvedNames []string
+		for _, ref := range c.Unresolved {
+			unresolvedNames = append(unresolvedNames, fmt.Sprintf("%s `%s`", ref.Source, ref.Ref))
+			severity := report.Warning
+			if ref.Optional {
+				severity = report.Info
+			}
+			rule := "missing-configmap"
+			if ref.Source == k8s.Secret {
+				rule = "missing-secret"
+			}
+			references = append(references, report.Finding{
+				Severity: severity,
+				Rule:     rule,
+				File:     c.File,
+				Line:     c.Line,
+				Key:      ref.Ref,
+				Message:  fmt.Sprintf("envFrom %s is not in the manifests, so the keys it provides are unknown (used by %s)", ref.Source, where),
+			})
+		}
+
+		checked := len(containerPatterns) == 0 && !c.Init || matchesAnyPattern(containerPatterns, c.Name)
+		if checked {
+			for _, key := range declaredKeys {
+				// Keys only declared in the schema are expected when required
+				if c.Receives(key) || (declared[key] == schemaFile && !schema.IsRequired(key)) {
+					continue
+				}
+				severity, message := report.Error, fmt.Sprintf("Declared but not passed to %s", where)
+				if len(unresolvedNames) > 0 {
+					severity = report.Warning
+					message += fmt.Sprintf("; it may come from %s, which is not in the manifests", strings.Join(unresolvedNames, ", "))
+				}
+				missing = append(missing, report.Finding{
+					Severity: severity,
+					Rule:     "missing-key",
+					File:     c.File,
+					Line:     c.Line,
+					Key:      key,
+					Message:  message,
+				})
+			}
+		}
+
+		name := c.Name
+		if c.Init {
+			name += " (init)"
+		}
+		checkedMark := "-"
+		if checked {
+			checkedMark = "✓"
+		}
+		table.Rows = append(table.Rows, []string{
+			c.Workload,
+			name,
+			fmt.Sprint(len(seen)),
+			fmt.Sprint(counts[k8s.Secret]),
+			fmt.Sprint(counts[k8s.ConfigMap]),
+			fmt.Sprint(counts[k8s.Literal]),
+			checkedMark,
+		})
+	}
+	report.SortFindings(undeclared)
+
+	declaredIn := append([]string(nil), envFiles...)
+	if schemaFile != "" {
+		declaredIn = append(declaredIn, schemaFile)
+	}
+	var files []string
+	for _, file := range manifests.Files {
+		files = append(files, filepath.ToSlash(file))
+	}
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddField("Manifests", codeList(files))
+	overview.AddField("Declared In", codeList(declaredIn))
+	overview.AddField("Declared Keys", len(declared))
+	overview.AddField("Workloads", len(workloads))
+	overview.AddField("Containers", len(manifests.Containers))
+	overview.AddField("ConfigMaps", len(manifests.ConfigMaps))
+	overview.AddField("Secrets", len(manifests.Secrets))
+	overview.AddField("Missing Keys", len(missing))
+	overview.AddField("Secrets Outside Secrets", len(exposed))
+	overview.AddField("Unresolved References", len(references))
+	r.AddSection(overview)
+
+	r.AddSection(report.Section{Title: "Containers", Tables: []report.Table{table}})
+
+	r.AddSection(report.Section{
+		Title:    "Missing Keys",
+		Findings: missing,
+		Empty:    "✓ Every checked container receives every declared key.",
+	})
+
+	exposedSection := report.Section{
+		Title:    "Secrets Outside Secrets",
+		Findings: exposed,
+		Empty:    "✓ Every secret key comes from a Secret.",
+	}
+	if len(exposed) > 0 {
+		exposedSection.Text = "ConfigMaps and literal values are stored in plain text and readable by anyone who can read the manifests. Move these keys to a Secret and reference them with `secretKeyRef` or `secretRef`."
+		if schema == nil {
+			exposedSection.Text += " Without a schema, secret keys are recognized by name only; use `--schema` with `\"secret\": true` properties for exact results."
+		}
+	}
+	r.AddSection(exposedSection)
+
+	r.AddSection(report.Section{
+		Title:    "Unresolved References",
+		Findings: references,
+		Empty:    "✓ Every referenced ConfigMap and Secret key is generated by the manifests.",
+	})
+	r.AddSection(report.Section{
+		Title:    "Undeclared Variables",
+		Findings: undeclared,
+		Empty:    "✓ Every variable passed to a container is declared.",
+	})
+
+	return r
+}
+
+// exposedSecret reports a secret key whose value comes from a ConfigMap or a literal value
+func exposedSecret(v k8s.Variable, schema *validator.Schema) (report.Finding, bool) {
+	if v.Source != k8s.ConfigMap && v.Source != k8s.Literal {
+		return report.Finding{}, false
+	}
+
+	severity := report.Error
+	if schema != nil {
+		if !schema.Properties[v.Name].Secret {
+			return report.Finding{}, false
+		}
+	} else {
+		if !secrets.LooksLikeSecretKey(v.Name) {
+			return report.Finding{}, false
+		}
+		severity = report.Warning
+	}
+
+	finding := report.Finding{Severity: severity, Key: v.Name}
+	if v.Source == k8s.ConfigMap {
+		finding.Rule = "secret-in-configmap"
+		finding.Message = fmt.Sprintf("Secret key comes from ConfigMap `%s`", v.Ref)
+	} else {
+		finding.Rule = "secret-literal-value"
+		finding.Message = "Secret key is set as a literal value"
+	}
+	return finding, true
+}
+
+// unresolvedKeyRef reports a configMapKeyRef or secretKeyRef to an object or key the manifests do not generate
+func unresolvedKeyRef(v k8s.Variable, manifests *k8s.Manifests) (report.Finding, bool) {
+	if v.FromEnvFrom {
+		return report.Finding{}, false
+	}
+	objects, kind := manifests.ConfigMaps, "ConfigMap"
+	switch v.Source {
+	case k8s.ConfigMap:
+	case k8s.Secret:
+		objects, kind = manifests.Secrets, "Secret"
+	default:
+		return report.Finding{}, false
+	}
+
+	finding := report.Finding{Severity: report.Error, Key: v.Name}
+	keys, ok := objects[v.Ref]
+	switch {
+	case !ok:
+		finding.Severity = report.Warning
+		finding.Rule = "missing-" + strings.ToLower(kind)
+		finding.Message = fmt.Sprintf("%s `%s` is not in the manifests", kind, v.Ref)
+	case !keys[v.Key]:
+		finding.Rule = "missing-" + strings.ToLower(kind) + "-key"
+		finding.Message = fmt.Sprintf("%s `%s` has no key `%s`", kind, v.Ref, v.Key)
+	default:
+		return report.Finding{}, false
+	}
+	if v.Optional {
+		finding.Severity = report.Info
+		finding.Message += "; the reference is optional"
+	}
+	return finding, true
+}
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MayR-Labs/envdoc-go/internal/config"
+	"github.com/MayR-Labs/envdoc-go/internal/lint"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewLintCmd returns the lint command
+func NewLintCmd() *cobra.Command {
+	var fix bool
+	var preview previewOptions
+	var known baselineOptions
+	var output reportOptions
+
+	cmd := &cobra.Command{
+		Use:   "lint [file]",
+		Short: "Check an environment file for style and correctness problems",
+		Long: `Checks the specified file against a set of lint rules (key naming, spacing,
+quoting, duplicates, final newline, ...). Rules can be tuned in the lint section of
+.envdoc.yaml and silenced for a single line with "# envdoc-disable-next-line RULE",
+or for a key until a date with "# envdoc-ignore RULE reason=\"...\" until=YYYY-MM-DD".
+With --fix, every issue that has an autofix is corrected in place after showing the
+changes as a unified diff (--dry-run stops there).`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var inputFile string
+			var err error
+
+			// Get input file
+			if len(args) > 0 {
+				inputFile = args[0]
+			} else {
+				inputFile, err = utils.PromptForEnvFile("Select the .env file to lint:")
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			// Check if input file exists
+			if !utils.FileExists(inputFile) {
+				fmt.Printf("Error: File '%s' does not exist\n", inputFile)
+				os.Exit(1)
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			linter, err := lint.New(cfg.Lint.Rules)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			doc, err := lint.LoadDocument(inputFile)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if fix {
+				fixed := linter.Fix(doc)
+				if fixed == 0 {
+					fmt.Println("Nothing to fix.")
+				} else {
+					change, err := newFileChange(inputFile, doc.String())
+					if err != nil {
+						fmt.Printf("Error reading file: %v\n", err)
+						os.Exit(1)
+					}
+					if !showPreview([]fileChange{change}, preview) {
+						return
+					}
+
+					confirmed, err := utils.ConfirmWithPin(fmt.Sprintf("This will apply %d fix(es) to '%s'.", fixed, inputFile))
+					if err != nil {
+						fmt.Printf("Error: %v\n", err)
+						os.Exit(1)
+					}
+					if !confirmed {
+						fmt.Println("Operation cancelled.")
+						return
+					}
+					if err := writeChanges([]fileChange{change}); err != nil {
+						fmt.Printf("Error writing file: %v\n", err)
+						os.Exit(1)
+					}
+					fmt.Printf("✓ Fixed %d issue(s) in: %s\n", fixed, inputFile)
+				}
+			}
+
+			issues := linter.Lint(doc)
+
+			// Generate report
+			lintReport := generateLintReport(inputFile, issues)
+			filterFindings(lintReport, []string{inputFile}, known)
+
+			// Show options
+			handleReportOutput(lintReport, "envdoc-lint", output)
+
+			exitOnErrors(lintReport)
+		},
+	}
+
+	cmd.Flags().BoolVar(&fix, "fix", false, "automatically fix issues where possible")
+	addPreviewFlags(cmd, &preview)
+	addBaselineFlags(cmd, &known)
+	addReportFlags(cmd, &output)
+
+	return cmd
+}
+
+func generateLintReport(filename string, issues []lint.Issue) *report.Report {
+	r := report.New("Environment Variables Lint Report")
+
+	counts := make(map[lint.Severity]int)
+	fixable := 0
+	for _, issue := range issues {
+		counts[issue.Severity]++
+		if issue.Fixable {
+			fixable++
+		}
+	}
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddCodeField("File", filename)
+	overview.AddField("Errors", counts[lint.Error])
+	overview.AddField("Warnings", counts[lint.Warning])
+	overview.AddField("Info", counts[lint.Info])
+	overview.AddField("Fixable with --fix", fixable)
+	r.AddSection(overview)
+
+	section := report.Section{Title: "Issues", Empty: "✓ No lint issues found."}
+	for _, issue := range issues {
+		message := issue.Message
+		if issue.Fixable {
+			message += " (fixable)"
+		}
+		section.Findings = append(section.Findings, report.Finding{
+			Severity: string(issue.Severity),
+			Rule:     issue.Rule,
+			File:     filename,
+			Line:     issue.Line,
+			Key:      issue.Key,
+			Message:  message,
+		})
+	}
+	r.AddSection(section)
+
+	return r
+}
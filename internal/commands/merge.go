@@ -0,0 +1,295 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/git"
+	"github.com/MayR-Labs/envdoc-go/internal/guard"
+	"github.com/MayR-Labs/envdoc-go/internal/merge"
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/secrets"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// mergeDrivers are the git merge drivers installed by merge --install-driver, with the
+// .gitattributes patterns that use them
+var mergeDrivers = []struct {
+	name     string
+	command  string
+	patterns []string
+}{
+	{name: "envdoc", command: "envdoc merge %O %A %B", patterns: []string{".env.example", ".env.*.example"}},
+	{name: "envdoc-encrypted", command: "envdoc merge --encrypted %O %A %B", patterns: []string{".env*" + guard.EncryptedSuffix}},
+}
+
+// NewMergeCmd returns the merge command
+func NewMergeCmd() *cobra.Command {
+	var into string
+	var toStdout, encrypted, asReport, installDriver bool
+	var output reportOptions
+	var preview previewOptions
+	var decrypter fileDecrypter
+
+	cmd := &cobra.Command{
+		Use:   "merge BASE OURS THEIRS",
+		Short: "Three-way merge env files at the key level",
+		Long: `Merges the changes between BASE and THEIRS into OURS key by key instead of line by line.
+The layout of OURS is kept, so reordered keys never conflict. Keys changed only in THEIRS
+are applied; keys added or changed differently on both sides, or changed on one side and
+deleted on the other, are conflicts.
+
+The result is written to OURS (or --into FILE, or stdout with --stdout) with git conflict
+markers around conflicting keys; with --report OURS's value is kept for them and the
+conflicts are written as a report instead. The command exits with status 1 on conflicts.
+
+Files ending in .encrypted, or all inputs with --encrypted, are decrypted with the
+password and the result is encrypted again; files encrypted to recipients are decrypted
+with your identity and the result is encrypted to the project recipients. As a git merge
+driver, the password can come from ENVDOC_PASSWORD or --password-cmd. --dry-run shows the change to OURS as a
+unified diff (of the decrypted content) without writing anything.
+
+--install-driver registers envdoc as a git merge driver for .env.example files and
+encrypted env files in the repository's git config and .gitattributes.`,
+		Example: `  envdoc merge .env.example.base .env.example .env.example.theirs
+  envdoc merge --install-driver`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if installDriver {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(3)(cmd, args)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if installDriver {
+				installMergeDrivers(repositoryRoot("."))
+				return
+			}
+
+			baseFile, oursFile, theirsFile := args[0], args[1], args[2]
+			for _, file := range args {
+				if !utils.FileExists(file) {
+					fmt.Printf("Error: File '%s' does not exist\n", file)
+					os.Exit(1)
+				}
+			}
+
+			for _, file := range args {
+				encrypted = encrypted || strings.HasSuffix(file, guard.EncryptedSuffix)
+			}
+
+			// Read the three versions
+			var docs []*parser.Document
+			for _, file := range args {
+				doc, err := readMergeInput(file, encrypted, &decrypter)
+				if err != nil {
+					fmt.Printf("Error reading '%s': %v\n", file, err)
+					os.Exit(1)
+				}
+				docs = append(docs, doc)
+			}
+
+			result := merge.Merge(docs[0], docs[1], docs[2], !asReport)
+			target := oursFile
+			if into != "" {
+				target = into
+			}
+
+			// Preview the decrypted change to OURS without writing
+			if preview.dryRun {
+				showPreview([]fileChange{{file: target, before: docs[1].String(), after: result.Document.String()}}, preview)
+				if len(result.Conflicts) > 0 {
+					os.Exit(1)
+				}
+				return
+			}
+
+			// Write the result, encrypted the way OURS was encrypted
+			content := result.Document.String()
+			if encrypted {
+				ours, err := utils.ReadFromFile(oursFile)
+				if err != nil {
+					fmt.Printf("Error reading '%s': %v\n", oursFile, err)
+					os.Exit(1)
+				}
+				content, err = encryptLike(strings.TrimSpace(ours), []byte(content), &decrypter)
+				if err != nil {
+					fmt.Printf("Error encrypting: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			if toStdout {
+				fmt.Print(content)
+			} else {
+				change, err := newFileChange(target, content)
+				if err != nil {
+					fmt.Printf("Error reading file: %v\n", err)
+					os.Exit(1)
+				}
+				if err := writeChanges([]fileChange{change}); err != nil {
+					fmt.Printf("Error writing file: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("✓ Merged %d change(s) from %s into %s\n", len(result.Changes), theirsFile, target)
+			}
+
+			if asReport {
+				mergeReport := generateMergeReport(baseFile, oursFile, theirsFile, result)
+				handleReportOutput(mergeReport, "envdoc-merge", output)
+			} else if len(result.Conflicts) > 0 && !toStdout {
+				fmt.Printf("✗ %d conflict(s) marked in %s:\n", len(result.Conflicts), target)
+				for _, conflict := range result.Conflicts {
+					fmt.Printf("  - %s: %s\n", conflict.Key, conflict.Description())
+				}
+				if encrypted {
+					fmt.Println("Decrypt the file with 'envdoc decrypt', resolve the markers and encrypt it again.")
+				}
+			}
+
+			if len(result.Conflicts) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&into, "into", "", "write the result to this file instead of OURS")
+	cmd.Flags().BoolVarP(&toStdout, "stdout", "p", false, "print the result instead of writing it")
+	cmd.Flags().BoolVar(&encrypted, "encrypted", false, "treat all inputs as encrypted files (used by the git merge driver)")
+	cmd.Flags().BoolVar(&asReport, "report", false, "keep OURS for conflicting keys and report the conflicts instead of writing markers")
+	cmd.Flags().BoolVar(&installDriver, "install-driver", false, "register envdoc as a git merge driver in .git/config and .gitattributes")
+	decrypter.addFlags(cmd)
+	addReportFlags(cmd, &output)
+	addPreviewFlags(cmd, &preview)
+
+	return cmd
+}
+
+// readMergeInput reads one version of the file, decrypting it if needed. Git passes an
+// empty file as BASE when there is no common ancestor.
+func readMergeInput(file string, encrypted bool, decrypter *fileDecrypter) (*parser.Document, error) {
+	data, err := utils.ReadFromFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if encrypted && strings.TrimSpace(data) != "" {
+		decrypted, err := decrypter.decrypt(strings.TrimSpace(data))
+		if err != nil {
+			return nil, err
+		}
+		data = string(decrypted)
+	}
+	return parser.ParseDocument(strings.NewReader(data))
+}
+
+func generateMergeReport(baseFile, oursFile, theirsFile string, result merge.Result) *report.Report {
+	r := report.New("Env Merge Report")
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddCodeField("Base", baseFile)
+	overview.AddCodeField("Ours", oursFile)
+	overview.AddCodeField("Theirs", theirsFile)
+	overview.AddField("Changes Taken from Theirs", len(result.Changes))
+	overview.AddField("Conflicts", len(result.Conflicts))
+	r.AddSection(overview)
+
+	changes := report.Table{Columns: []report.Column{{Title: "Key", Code: true}, {Title: "Change"}}}
+	for _, change := range result.Changes {
+		changes.Rows = append(changes.Rows, []string{change.Key, change.Kind})
+	}
+	changesSection := report.Section{Title: "Changes Taken from Theirs", Empty: "No changes taken from theirs."}
+	if len(changes.Rows) > 0 {
+		changesSection.Tables = append(changesSection.Tables, changes)
+	}
+	r.AddSection(changesSection)
+
+	var conflicts []report.Finding
+	for _, conflict := range result.Conflicts {
+		conflicts = append(conflicts, report.Finding{
+			Severity: report.Error,
+			Rule:     "merge-conflict",
+			File:     oursFile,
+			Key:      conflict.Key,
+			Message: fmt.Sprintf("%s (base %s, ours %s, theirs %s); kept ours",
+				conflict.Description(), describeSide(conflict.Base), describeSide(conflict.Ours), describeSide(conflict.Theirs)),
+		})
+	}
+	conflictsSection := report.Section{
+		Title:    "Conflicts",
+		Findings: conflicts,
+		Empty:    "✓ No conflicts.",
+	}
+	if len(conflicts) > 0 {
+		conflictsSection.Text = "Values are masked. Edit the merged file to pick the right value for each key."
+	}
+	r.AddSection(conflictsSection)
+
+	return r
+}
+
+// describeSide formats one side of a conflict with a masked value
+func describeSide(side merge.Side) string {
+	if !side.Present {
+		return "absent"
+	}
+	return "`" + secrets.Mask(side.Value) + "`"
+}
+
+// installMergeDrivers registers the merge drivers in the repository config and .gitattributes
+func installMergeDrivers(root string) {
+	for _, driver := range mergeDrivers {
+		settings := [][2]string{
+			{"merge." + driver.name + ".name", "envdoc key-level env file merge"},
+			{"merge." + driver.name + ".driver", driver.command},
+		}
+		for _, setting := range settings {
+			if _, err := git.Command(root, "config", setting[0], setting[1]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	fmt.Println("✓ Merge drivers registered in .git/config")
+
+	attributesFile := filepath.Join(root, ".gitattributes")
+	existing, err := os.ReadFile(attributesFile)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Error reading %s: %v\n", attributesFile, err)
+		os.Exit(1)
+	}
+	present := make(map[string]bool)
+	for _, line := range strings.Split(string(existing), "\n") {
+		present[strings.Join(strings.Fields(line), " ")] = true
+	}
+
+	var added []string
+	for _, driver := range mergeDrivers {
+		for _, pattern := range driver.patterns {
+			line := pattern + " merge=" + driver.name
+			if !present[line] {
+				added = append(added, line)
+			}
+		}
+	}
+	if len(added) == 0 {
+		fmt.Printf("%s already uses the envdoc merge drivers.\n", attributesFile)
+		return
+	}
+
+	content := string(existing)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += strings.Join(added, "\n") + "\n"
+	if err := utils.WriteToFile(attributesFile, content); err != nil {
+		fmt.Printf("Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Added to %s:\n", attributesFile)
+	for _, line := range added {
+		fmt.Printf("  %s\n", line)
+	}
+	fmt.Println("Commit .gitattributes; every clone needs 'envdoc merge --install-driver' to register the drivers.")
+}
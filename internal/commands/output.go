@@ -3,12 +3,218 @@ package commands
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/MayR-Labs/envdoc-go/internal/baseline"
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/suppress"
 	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
 )
 
-// handleReportOutput prompts the user for what to do with a generated report
-func handleReportOutput(report, prefix string) {
+// reportOptions holds the output flags shared by reporting commands
+type reportOptions struct {
+	format string
+	output string
+}
+
+// addReportFlags registers the report output flags on a command
+func addReportFlags(cmd *cobra.Command, opts *reportOptions) {
+	cmd.Flags().StringVar(&opts.format, "format", "markdown", "report format: markdown, html, json or csv")
+	cmd.Flags().StringVar(&opts.output, "output", "", "write the report to this file ('-' for stdout) instead of prompting")
+}
+
+// baselineOptions holds the baseline flags shared by commands with fingerprinted findings
+type baselineOptions struct {
+	file   string
+	update bool
+}
+
+// addBaselineFlags registers the baseline flags on a command
+func addBaselineFlags(cmd *cobra.Command, opts *baselineOptions) {
+	cmd.Flags().StringVar(&opts.file, "baseline", "", "hide findings recorded in this baseline file (create it with --update-baseline)")
+	cmd.Flags().BoolVar(&opts.update, "update-baseline", false, "record the current findings in the baseline file ("+baseline.DefaultFile+" unless --baseline is set)")
+}
+
+// applyBaseline creates or updates the baseline when asked to, then removes known findings
+// from the report. A missing baseline is an error unless it is being created. The outcome is recorded in the report's overview section. It returns
+// whether a baseline is in use.
+func applyBaseline(r *report.Report, opts baselineOptions) bool {
+	filename := opts.file
+	if filename == "" {
+		if !opts.update {
+			return false
+		}
+		filename = baseline.DefaultFile
+	}
+
+	// The fingerprints of secrets depend on the salt of the baseline, kept when it is updated
+	var known *baseline.Baseline
+	var err error
+	switch {
+	case utils.FileExists(filename):
+		known, err = baseline.Load(filename)
+	case opts.update:
+		known, err = baseline.New()
+	default:
+		err = fmt.Errorf("baseline %s does not exist; create it with --update-baseline", filename)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	known.Assign(r)
+
+	var summary string
+	if opts.update {
+		known.Record(r)
+		if err := known.Save(filename); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		summary = fmt.Sprintf("%s (saved with %d finding(s))", filename, len(known.Findings))
+	}
+
+	hidden := known.Filter(r)
+	if summary == "" {
+		summary = fmt.Sprintf("%s (%d known finding(s) hidden)", filename, hidden)
+	}
+	addOverviewField(r, "Baseline", summary)
+	return true
+}
+
+// filterFindings applies inline suppressions and the baseline to a report. Problems with
+// suppressions are added as a final section that the baseline cannot hide. It returns
+// whether remaining errors should fail the command.
+func filterFindings(r *report.Report, files []string, opts baselineOptions) bool {
+	problems := applySuppressions(r, files)
+	gate := applyBaseline(r, opts)
+	if !problems.IsEmpty() {
+		r.AddSection(problems)
+	}
+	return gate
+}
+
+// applySuppressions removes findings covered by active envdoc-ignore comments in the
+// files and returns a section listing expired and invalid suppressions
+func applySuppressions(r *report.Report, files []string) report.Section {
+	problems := report.Section{
+		Title: "Suppressions",
+		Text:  "These `# envdoc-ignore` comments no longer hide anything. Renew or remove them.",
+	}
+
+	var all []suppress.Suppression
+	for _, file := range files {
+		suppressions, err := suppress.ParseFile(file)
+		if err != nil {
+			fmt.Printf("Warning: Could not read suppressions in '%s': %v\n", file, err)
+			continue
+		}
+		all = append(all, suppressions...)
+	}
+	if len(all) == 0 {
+		return problems
+	}
+
+	now := time.Now()
+	var active []suppress.Suppression
+	for _, s := range all {
+		switch s.Status(now) {
+		case suppress.Active:
+			active = append(active, s)
+		case suppress.Expired:
+			problems.Findings = append(problems.Findings, report.Finding{
+				Severity: report.Warning,
+				Rule:     "expired-suppression",
+				File:     s.File,
+				Line:     s.Line,
+				Key:      s.Key,
+				Message:  fmt.Sprintf("Expired on %s (%s)", s.Until, s.Reason),
+			})
+		case suppress.Invalid:
+			problems.Findings = append(problems.Findings, report.Finding{
+				Severity: report.Error,
+				Rule:     "invalid-suppression",
+				File:     s.File,
+				Line:     s.Line,
+				Key:      s.Key,
+				Message:  s.Problem,
+			})
+		}
+	}
+
+	suppressed := 0
+	var filter func(sections []report.Section)
+	filter = func(sections []report.Section) {
+		for i := range sections {
+			kept := sections[i].Findings[:0]
+			for _, finding := range sections[i].Findings {
+				if isSuppressed(active, finding) {
+					suppressed++
+					continue
+				}
+				kept = append(kept, finding)
+			}
+			sections[i].Findings = kept
+			filter(sections[i].Subsections)
+		}
+	}
+	filter(r.Sections)
+
+	addOverviewField(r, "Suppressed Inline", suppressed)
+	return problems
+}
+
+// isSuppressed reports whether any active suppression covers the finding
+func isSuppressed(active []suppress.Suppression, finding report.Finding) bool {
+	for _, s := range active {
+		if s.Matches(finding.File, strings.TrimSpace(finding.Key), finding.Rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// addOverviewField appends a field to the report's overview section, if it has one
+func addOverviewField(r *report.Report, label string, value any) {
+	if len(r.Sections) > 0 && r.Sections[0].Title == "Overview" {
+		r.Sections[0].AddField(label, value)
+	}
+}
+
+// exitOnErrors exits with status 1 when the report still contains error findings
+func exitOnErrors(r *report.Report) {
+	if r.CountSeverity(report.Error) > 0 {
+		os.Exit(1)
+	}
+}
+
+// handleReportOutput renders a report and either writes it where --output says or
+// prompts the user for what to do with it
+func handleReportOutput(r *report.Report, prefix string, opts reportOptions) {
+	content, err := report.Render(r, opts.format)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch opts.output {
+	case "":
+	case "-":
+		fmt.Print(content)
+		return
+	default:
+		if err := utils.WriteToFile(opts.output, content); err != nil {
+			fmt.Printf("Error writing file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Report saved to: %s\n", opts.output)
+		return
+	}
+
 	options := []string{"Show on CLI", "Copy report content", "Save to file"}
 	selected, err := utils.PromptForSelection("What would you like to do with the report?", options)
 	if err != nil {
@@ -18,24 +224,34 @@ func handleReportOutput(report, prefix string) {
 
 	switch selected {
 	case "Show on CLI":
-		fmt.Println("\n" + report)
+		fmt.Println("\n" + content)
 	case "Copy report content":
-		if err := utils.CopyToClipboard(report); err != nil {
+		if err := utils.CopyToClipboard(content); err != nil {
 			fmt.Printf("Error copying to clipboard: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Println("✓ Report copied to clipboard")
 	case "Save to file":
-		defaultFilename := fmt.Sprintf("%s-%s.md", prefix, utils.GetTimestamp())
+		defaultFilename := fmt.Sprintf("%s-%s%s", prefix, utils.GetTimestamp(), report.Extension(opts.format))
 		filename, err := utils.PromptForFile("Enter output filename:", defaultFilename)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
-		if err := utils.WriteToFile(filename, report); err != nil {
+		if err := utils.WriteToFile(filename, content); err != nil {
 			fmt.Printf("Error writing file: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Printf("✓ Report saved to: %s\n", filename)
 	}
 }
+
+// sortedFiles returns the file names of parsed env files in a stable order
+func sortedFiles(allEnvVars map[string][]parser.EnvVar) []string {
+	files := make([]string, 0, len(allEnvVars))
+	for file := range allEnvVars {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
@@ -0,0 +1,328 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/diff"
+	"github.com/MayR-Labs/envdoc-go/internal/journal"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// previewOptions holds the flags shared by commands that modify files
+type previewOptions struct {
+	dryRun     bool
+	format     string
+	showValues bool
+}
+
+// addPreviewFlags registers the preview flags on a command
+func addPreviewFlags(cmd *cobra.Command, opts *previewOptions) {
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show the changes without writing anything")
+	cmd.Flags().StringVar(&opts.format, "diff-format", "text", "preview format: text (unified diff) or json")
+	cmd.Flags().BoolVar(&opts.showValues, "show-values", false, "show values in the preview instead of masking them")
+}
+
+// fileChange is the new content of a file. staged is a temporary file that already holds
+// the new content instead of after, for writeChangesAtomically; with beforeOnDisk the
+// previous content was not read into before and the journal snapshots the file itself.
+type fileChange struct {
+	file         string
+	before       string
+	after        string
+	staged       string
+	beforeOnDisk bool
+	created      bool
+	deleted      bool
+}
+
+// newFileChange reads the current content of a file for a pending change
+func newFileChange(file, after string) (fileChange, error) {
+	before, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return fileChange{}, err
+	}
+	return fileChange{file: file, before: string(before), after: after, created: os.IsNotExist(err)}, nil
+}
+
+// quiet reports whether only the JSON preview should be printed
+func (opts previewOptions) quiet() bool {
+	return opts.format == "json"
+}
+
+// showPreview prints the diff of the pending changes. It returns true when the command should
+// go on to confirm and write, and false on --dry-run or when nothing would change.
+func showPreview(changes []fileChange, opts previewOptions) bool {
+	if opts.format != "text" && opts.format != "json" {
+		fmt.Printf("Error: Unknown diff format '%s' (use text or json)\n", opts.format)
+		os.Exit(1)
+	}
+
+	var diffs []diff.FileDiff
+	changed := 0
+	for _, change := range changes {
+		d := diff.Compute(change.file, change.before, change.after, diff.DefaultContext)
+		if !opts.showValues {
+			d.MaskValues()
+		}
+		if !d.Empty() || change.created || change.deleted {
+			changed++
+		}
+		diffs = append(diffs, d)
+	}
+
+	if opts.quiet() {
+		if err := diff.RenderJSON(os.Stdout, diffs); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if changed > 0 {
+		fmt.Println()
+		diff.Render(os.Stdout, diffs, colorOutput())
+		fmt.Println()
+	}
+
+	switch {
+	case changed == 0:
+		if !opts.quiet() {
+			fmt.Println("✓ Nothing to change")
+		}
+		return false
+	case opts.dryRun:
+		if !opts.quiet() {
+			fmt.Printf("Dry run: %d file(s) would change, nothing was written.\n", changed)
+		}
+		return false
+	}
+	return true
+}
+
+// writeChanges records the changed files in the journal, then writes their new content
+func writeChanges(changes []fileChange) error {
+	_, err := writeJournaled(changes, "")
+	return err
+}
+
+// writeJournaled writes the changes like writeChanges and returns the journal entry, if
+// the journal is enabled. undoOf is the ID of the entry the changes revert.
+func writeJournaled(changes []fileChange, undoOf string) (*journal.Entry, error) {
+	var changed []fileChange
+	for _, change := range changes {
+		if change.before != change.after || change.created || change.deleted {
+			changed = append(changed, change)
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	entry, err := recordChanges(changed, undoOf)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changed {
+		if change.deleted {
+			if err := os.Remove(change.file); err != nil && !os.IsNotExist(err) {
+				return entry, fmt.Errorf("failed to remove '%s': %w", change.file, err)
+			}
+			continue
+		}
+		if err := utils.WriteToFile(change.file, change.after); err != nil {
+			return entry, fmt.Errorf("failed to write '%s': %w", change.file, err)
+		}
+	}
+	if entry != nil && undoOf == "" {
+		fmt.Printf("Recorded as %s in the journal; 'envdoc undo' reverts it.\n", entry.ID)
+	}
+	return entry, nil
+}
+
+// writeChangesAtomically writes changes like writeChanges, but all or nothing: the new
+// contents are written to temporary files next to the originals first, unless they are
+// already staged, and only then moved into place. The originals are kept under a second name
+// until every file is replaced; if one cannot be replaced, the files replaced before it are
+// restored and the journal entry is removed.
+func writeChangesAtomically(changes []fileChange) error {
+	for i, change := range changes {
+		if change.staged != "" {
+			continue
+		}
+		tmp, err := stageFile(change.file, func(w io.Writer) error {
+			_, err := io.WriteString(w, change.after)
+			return err
+		})
+		if err != nil {
+			removeStaged(changes)
+			return fmt.Errorf("failed to write '%s': %w", change.file, err)
+		}
+		changes[i].staged = tmp
+	}
+
+	entry, err := recordChanges(changes, "")
+	if err != nil {
+		removeStaged(changes)
+		return err
+	}
+
+	backups := make([]string, len(changes))
+	removeBackups := func() {
+		for _, backup := range backups {
+			if backup != "" {
+				_ = os.Remove(backup)
+			}
+		}
+	}
+	for i, change := range changes {
+		if !change.created {
+			if backups[i], err = backupFile(change.file); err != nil {
+				err = fmt.Errorf("failed to back up '%s': %w", change.file, err)
+			}
+		}
+		if err == nil {
+			if err = os.Rename(change.staged, change.file); err != nil {
+				err = fmt.Errorf("failed to replace '%s': %w", change.file, err)
+			}
+		}
+		if err == nil {
+			changes[i].staged = ""
+			continue
+		}
+
+		removeStaged(changes)
+		if backups[i] != "" {
+			_ = os.Remove(backups[i])
+			backups[i] = ""
+		}
+		var failed []string
+		for k := i - 1; k >= 0; k-- {
+			var restoreErr error
+			if backups[k] != "" {
+				if restoreErr = os.Rename(backups[k], changes[k].file); restoreErr == nil {
+					backups[k] = ""
+				}
+			} else if changes[k].created {
+				restoreErr = os.Remove(changes[k].file)
+			}
+			if restoreErr != nil {
+				failed = append(failed, changes[k].file)
+			}
+		}
+		if len(failed) > 0 {
+			// The journal entry still holds their previous content
+			return fmt.Errorf("%w; restoring %s failed, 'envdoc undo' restores them", err, strings.Join(failed, ", "))
+		}
+		removeBackups()
+		if entry != nil {
+			if j, _, err := openJournal(); err == nil {
+				_ = j.Remove(entry.ID)
+			}
+		}
+		return fmt.Errorf("%w; no file was changed", err)
+	}
+	removeBackups()
+	if entry != nil {
+		fmt.Printf("Recorded as %s in the journal; 'envdoc undo' reverts it.\n", entry.ID)
+	}
+	return nil
+}
+
+// writeFileJournaled writes a file from a stream, recording its previous content in the
+// journal before it is replaced like writeChangesAtomically. Neither content is held in
+// memory.
+func writeFileJournaled(file string, write func(w io.Writer) error) error {
+	_, err := os.Stat(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	change := fileChange{file: file, created: os.IsNotExist(err), beforeOnDisk: err == nil}
+	if change.staged, err = stageFile(file, write); err != nil {
+		return err
+	}
+	return writeChangesAtomically([]fileChange{change})
+}
+
+// stageFile writes the new content of file with write to a temporary file next to it, with
+// the permissions of file or 0644 for a new file, and returns its path. The directory of a
+// new file is created.
+func stageFile(file string, write func(w io.Writer) error) (string, error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(file); err == nil {
+		mode = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return "", err
+	} else if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	err = write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// backupFile gives file a second name next to it, so it can be restored after it is
+// replaced. The name is a hard link, or a copy where links are not supported.
+func backupFile(file string) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".bak-*")
+	if err != nil {
+		return "", err
+	}
+	backup := tmp.Name()
+	_ = tmp.Close()
+	_ = os.Remove(backup)
+	if err := os.Link(file, backup); err == nil {
+		return backup, nil
+	}
+
+	in, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(backup)
+		return "", err
+	}
+	return backup, nil
+}
+
+// removeStaged removes the staged files of changes that were not moved into place
+func removeStaged(changes []fileChange) {
+	for i := range changes {
+		if changes[i].staged != "" {
+			_ = os.Remove(changes[i].staged)
+			changes[i].staged = ""
+		}
+	}
+}
+
+// colorOutput reports whether stdout is a terminal that should get colored output
+func colorOutput() bool {
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return !noColor && term.IsTerminal(int(os.Stdout.Fd()))
+}
@@ -0,0 +1,372 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/MayR-Labs/envdoc-go/internal/config"
+	"github.com/MayR-Labs/envdoc-go/internal/crypto"
+	"github.com/MayR-Labs/envdoc-go/internal/guard"
+	"github.com/MayR-Labs/envdoc-go/internal/recipients"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewKeygenCmd returns the keygen command
+func NewKeygenCmd() *cobra.Command {
+	var outputFile string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate a personal key pair for files encrypted to recipients",
+		Long: `Generates an X25519 key pair (age format) for files encrypted to a list of team
+members instead of a shared password. The secret key is written to the envdoc directory
+of the user's config directory, or to --output, and the public key is printed: share it
+with a team member who runs 'envdoc recipients add'.
+
+The identity file is compatible with age (age -d -i), and age identities can be used
+with envdoc the same way.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			path := outputFile
+			if path == "" {
+				var err error
+				path, err = recipients.IdentityPath()
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			// Never replace an identity silently: files encrypted to it would be lost
+			if utils.FileExists(path) {
+				if !force {
+					fmt.Printf("Error: An identity already exists at %s\n", path)
+					if identities, err := recipients.LoadIdentities(path); err == nil {
+						fmt.Printf("Its public key is %s\n", identities[0].Recipient())
+					}
+					fmt.Println("Use --force to replace it.")
+					os.Exit(1)
+				}
+				confirmed, err := utils.ConfirmWithPin(fmt.Sprintf("This will replace %s; files encrypted only to it can no longer be decrypted.", path))
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if !confirmed {
+					fmt.Println("Operation cancelled.")
+					return
+				}
+			}
+
+			identity, err := crypto.GenerateIdentity()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := os.WriteFile(path, []byte(recipients.FormatIdentity(identity, time.Now())), 0600); err != nil {
+				fmt.Printf("Error writing file: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("✓ Identity written to %s\n", path)
+			fmt.Printf("Public key: %s\n", identity.Recipient())
+			fmt.Println("Keep the identity file private and backed up; share only the public key.")
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "write the identity to this file (default: the envdoc config directory)")
+	cmd.Flags().BoolVar(&force, "force", false, "replace an existing identity")
+
+	return cmd
+}
+
+// NewRecipientsCmd returns the recipients command
+func NewRecipientsCmd() *cobra.Command {
+	var identity fileDecrypter
+
+	cmd := &cobra.Command{
+		Use:   "recipients",
+		Short: "List and manage the public keys encrypted files are readable by",
+		Long: `Lists the public keys in the project recipients file (.envdoc/recipients.txt at the
+project root, or encryption.recipients_file in .envdoc.yaml). 'envdoc encrypt --recipients'
+encrypts files to these keys in the age format, so every listed team member can decrypt
+them with their own identity (see 'envdoc keygen') and no password is shared.
+
+'recipients add' and 'recipients remove' change the list and re-encrypt every *.encrypted
+file of the project that is encrypted to recipients, so a removed member's key no longer
+opens the current files. Values they could read before should still be rotated.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			list, path, err := loadRecipients()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if len(list.Entries) == 0 {
+				fmt.Printf("No recipients in %s. Add one with 'envdoc recipients add'.\n", displayPath(path))
+				return
+			}
+
+			// Mark the user's own keys
+			own := make(map[string]bool)
+			if identities, err := identity.loadIdentities(); err == nil {
+				for _, id := range identities {
+					own[id.Recipient().String()] = true
+				}
+			}
+
+			fmt.Printf("Recipients in %s (%d):\n", displayPath(path), len(list.Entries))
+			for _, entry := range list.Entries {
+				line := entry.Describe()
+				if own[entry.Recipient.String()] {
+					line += " (you)"
+				}
+				fmt.Printf("  - %s\n", line)
+			}
+		},
+	}
+
+	identity.addIdentityFlag(cmd)
+	cmd.AddCommand(newRecipientsAddCmd())
+	cmd.AddCommand(newRecipientsRemoveCmd())
+
+	return cmd
+}
+
+func newRecipientsAddCmd() *cobra.Command {
+	var name string
+	var self bool
+	var opts recipientUpdateOptions
+
+	cmd := &cobra.Command{
+		Use:   "add [public-key]",
+		Short: "Add a public key and re-encrypt the files for it",
+		Long: `Adds an age public key (age1...) to the recipients file and re-encrypts the files
+encrypted to recipients so its owner can decrypt them. --self adds the public key of your
+own identity, which is how the first recipient is usually added.`,
+		Example: `  envdoc recipients add --self --name alice
+  envdoc recipients add age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p --name bob`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if self {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			var recipient *crypto.Recipient
+			if self {
+				identities, err := opts.identity.loadIdentities()
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				recipient = identities[0].Recipient()
+			} else {
+				var err error
+				recipient, err = crypto.ParseRecipient(args[0])
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			list, path, err := loadRecipients()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := list.Add(name, recipient); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			entry := list.Entries[len(list.Entries)-1]
+
+			updateRecipients(path, list, "+ "+entry.Describe(), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "name of the key owner, written as a comment above the key")
+	cmd.Flags().BoolVar(&self, "self", false, "add the public key of your own identity")
+	addRecipientUpdateFlags(cmd, &opts)
+
+	return cmd
+}
+
+func newRecipientsRemoveCmd() *cobra.Command {
+	var opts recipientUpdateOptions
+
+	cmd := &cobra.Command{
+		Use:   "remove <public-key|name>",
+		Short: "Remove a public key and re-encrypt the files without it",
+		Long: `Removes a recipient by public key or name and re-encrypts the files encrypted to
+recipients for the remaining keys. The removed key cannot decrypt the new files, but it
+could decrypt earlier versions, including copies in git history: rotate the secrets it
+had access to as well.`,
+		Example: `  envdoc recipients remove bob`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			list, path, err := loadRecipients()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			removed, err := list.Remove(args[0])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			updateRecipients(path, list, "- "+removed.Describe(), opts)
+		},
+	}
+
+	addRecipientUpdateFlags(cmd, &opts)
+
+	return cmd
+}
+
+// recipientUpdateOptions holds the flags of the commands that change the recipients
+type recipientUpdateOptions struct {
+	identity    fileDecrypter
+	noReencrypt bool
+	dryRun      bool
+	yes         bool
+}
+
+// addRecipientUpdateFlags registers the flags of the commands that change the recipients
+func addRecipientUpdateFlags(cmd *cobra.Command, opts *recipientUpdateOptions) {
+	opts.identity.addIdentityFlag(cmd)
+	cmd.Flags().BoolVar(&opts.noReencrypt, "no-reencrypt", false, "only update the recipients file")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show the changes without writing anything")
+	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false, "apply without asking for confirmation")
+}
+
+// updateRecipients writes the new recipients list and re-encrypts the files encrypted to
+// recipients for it, all recorded as one operation. Every file is decrypted before anything
+// is written, so a file the user cannot decrypt changes nothing, and the files are replaced
+// all or nothing.
+func updateRecipients(path string, list *recipients.List, summary string, opts recipientUpdateOptions) {
+	listChange, err := newFileChange(path, list.String())
+	if err != nil {
+		fmt.Printf("Error reading file: %v\n", err)
+		os.Exit(1)
+	}
+	changes := []fileChange{listChange}
+
+	// Re-encrypt the files for the new list. Only their headers are kept in memory; the
+	// contents are streamed from the files.
+	var files []string
+	var heads []string
+	if !opts.noReencrypt {
+		root := projectRoot()
+		found, err := guard.DiscoverEncryptedFiles(root)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, file := range found {
+			file = filepath.Join(root, file)
+			head, err := readEncryptedHead(file)
+			if err != nil {
+				fmt.Printf("Error reading '%s': %v\n", displayPath(file), err)
+				os.Exit(1)
+			}
+			if crypto.IsAge(head) {
+				files = append(files, file)
+				heads = append(heads, head)
+				changes = append(changes, fileChange{file: file, beforeOnDisk: true})
+			}
+		}
+	}
+	if len(files) > 0 && len(list.Entries) == 0 {
+		fmt.Printf("Error: %d file(s) are encrypted to recipients; the last recipient cannot be removed\n", len(files))
+		os.Exit(1)
+	}
+	for _, file := range files {
+		if err := opts.identity.decryptFile(io.Discard, file); err != nil {
+			fmt.Printf("Error decrypting '%s': %v\n", displayPath(file), err)
+			fmt.Println("Only a current recipient can re-encrypt the files; use --no-reencrypt to only update the list.")
+			os.Exit(1)
+		}
+	}
+
+	// Show the plan
+	fmt.Printf("Recipients (%s): %s\n", displayPath(path), summary)
+	if len(files) > 0 {
+		fmt.Printf("Re-encrypting %d file(s) for %d recipient(s):\n", len(files), len(list.Entries))
+		for _, file := range files {
+			fmt.Printf("  - %s\n", displayPath(file))
+		}
+	} else if opts.noReencrypt {
+		fmt.Println("Files encrypted to recipients are not re-encrypted (--no-reencrypt).")
+	} else {
+		fmt.Println("No files are encrypted to recipients yet.")
+	}
+	if opts.dryRun {
+		return
+	}
+
+	if !opts.yes {
+		confirmed, err := utils.PromptForConfirmation("Apply these changes?")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !confirmed {
+			fmt.Println("Operation cancelled.")
+			return
+		}
+	}
+
+	// Re-encrypt each file as a stream into a copy staged next to it
+	for i, file := range files {
+		change := &changes[i+1]
+		change.staged, err = stageFile(file, func(w io.Writer) error {
+			encrypter, err := crypto.NewRecipientsWriter(w, list.Recipients(), crypto.IsArmored(heads[i]))
+			if err != nil {
+				return err
+			}
+			if err := opts.identity.decryptFile(encrypter, file); err != nil {
+				return err
+			}
+			return encrypter.Close()
+		})
+		if err != nil {
+			removeStaged(changes)
+			fmt.Printf("Error encrypting '%s': %v\n", displayPath(file), err)
+			fmt.Println("Nothing was changed.")
+			os.Exit(1)
+		}
+	}
+
+	if err := writeChangesAtomically(changes); err != nil {
+		fmt.Printf("Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Updated %s and re-encrypted %d file(s)\n", displayPath(path), len(files))
+}
+
+// loadRecipients reads the recipients file of the current project and returns it with its path
+func loadRecipients() (*recipients.List, string, error) {
+	root := projectRoot()
+	cfg, err := config.LoadFrom(root)
+	if err != nil {
+		return nil, "", err
+	}
+	name := recipients.DefaultFile
+	if cfg.Encryption.RecipientsFile != "" {
+		name = cfg.Encryption.RecipientsFile
+	}
+	path := filepath.Join(root, name)
+	list, err := recipients.Load(path)
+	return list, path, err
+}
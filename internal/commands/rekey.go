@@ -0,0 +1,293 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/credential"
+	"github.com/MayR-Labs/envdoc-go/internal/crypto"
+	"github.com/MayR-Labs/envdoc-go/internal/guard"
+	"github.com/MayR-Labs/envdoc-go/internal/recipients"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewRekeyCmd returns the rekey command
+func NewRekeyCmd() *cobra.Command {
+	var decrypter fileDecrypter
+	var newSecret secretOptions
+	var kdf kdfOptions
+	var only string
+	var dryRun bool
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "rekey [paths...]",
+		Short: "Re-encrypt all encrypted files with a new password or for the current recipients",
+		Long: `Rotates the secret of every *.encrypted file below the current directory, or of the given
+directories, files and glob patterns, in one pass:
+
+  - files encrypted with a password are re-encrypted with a new password, keeping their
+    key derivation parameters unless KDF flags are given
+  - files encrypted to recipients are re-encrypted with fresh keys for the keys in the
+    project recipients file, e.g. after a compromised key was removed from it
+
+Every file is decrypted with the current secrets before the new password is asked for, so
+a file that does not open changes nothing. The files are then replaced all or nothing: if
+one cannot be written, the others are restored. The operation is recorded in the journal.
+
+The current password is read like for decrypt; the new one from --new-password-file,
+--new-password-fd or --new-password-cmd, otherwise from ENVDOC_NEW_PASSWORD, and only
+then prompted for. Old copies of the files, e.g. in git history, still open with the old
+secret: rotate the values they contain if it was compromised.`,
+		Example: `  envdoc rekey
+  envdoc rekey config/ "deploy/*.encrypted" --dry-run
+  envdoc rekey --only password --password-file old.txt --new-password-file new.txt --yes
+  envdoc rekey --only recipients`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if only != "" && only != "password" && only != "recipients" {
+				fmt.Printf("Error: Unknown --only value '%s' (use password or recipients)\n", only)
+				os.Exit(1)
+			}
+
+			files, err := findRekeyFiles(args)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Sort the files by how they are encrypted. Only their headers are kept in memory;
+			// the contents are streamed from the files.
+			var changes []fileChange
+			var heads []string
+			var passwordFiles, recipientFiles int
+			for _, file := range files {
+				data, err := readEncryptedHead(file)
+				if err != nil {
+					fmt.Printf("Error reading '%s': %v\n", file, err)
+					os.Exit(1)
+				}
+				if crypto.IsAge(data) {
+					if only == "password" {
+						continue
+					}
+					recipientFiles++
+				} else {
+					if only == "recipients" {
+						continue
+					}
+					passwordFiles++
+				}
+				changes = append(changes, fileChange{file: file, beforeOnDisk: true})
+				heads = append(heads, data)
+			}
+			if len(changes) == 0 {
+				fmt.Printf("No *%s files to rekey.\n", guard.EncryptedSuffix)
+				return
+			}
+
+			// Verify that every file decrypts before asking for anything new. Files are decrypted
+			// as a stream, so only one chunk of plaintext is in memory at a time.
+			for _, change := range changes {
+				if err := decrypter.decryptFile(io.Discard, change.file); err != nil {
+					fmt.Printf("Error decrypting '%s': %v\n", change.file, err)
+					fmt.Println("Nothing was changed.")
+					os.Exit(1)
+				}
+			}
+			fmt.Printf("✓ All %d file(s) decrypt with the current secrets\n", len(changes))
+
+			// New secrets
+			var newPassword string
+			var params crypto.KDFParams
+			if passwordFiles > 0 {
+				newPassword, err = newSecret.password("Enter new password:", true)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if decrypter.hasPassword && newPassword == decrypter.password {
+					fmt.Println("Error: The new password is the same as the current one")
+					os.Exit(1)
+				}
+				if params, err = kdf.params(); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			var list *recipients.List
+			if recipientFiles > 0 {
+				var path string
+				list, path, err = loadRecipients()
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if len(list.Entries) == 0 {
+					fmt.Printf("Error: No recipients in %s to encrypt %d file(s) to\n", displayPath(path), recipientFiles)
+					os.Exit(1)
+				}
+			}
+
+			// Plan how each file is re-encrypted
+			plan := make([]string, len(changes))
+			writers := make([]func(w io.Writer, armor bool) (io.WriteCloser, error), len(changes))
+			for i, change := range changes {
+				if crypto.IsAge(heads[i]) {
+					writers[i] = func(w io.Writer, armor bool) (io.WriteCloser, error) {
+						return crypto.NewRecipientsWriter(w, list.Recipients(), armor)
+					}
+					plan[i] = fmt.Sprintf("age → %d recipient(s)", len(list.Entries))
+					continue
+				}
+				fileParams := params
+				if !kdf.explicit() {
+					if originalParams, err := crypto.ParamsOf(heads[i]); err == nil {
+						fileParams = originalParams
+					}
+				}
+				writers[i] = func(w io.Writer, armor bool) (io.WriteCloser, error) {
+					return crypto.NewEncryptWriter(w, newPassword, fileParams, armor)
+				}
+				description, err := crypto.Describe(heads[i])
+				if err != nil {
+					fmt.Printf("Error: '%s': %v\n", change.file, err)
+					os.Exit(1)
+				}
+				plan[i] = fmt.Sprintf("%s → new password, v%d (%s)", description, crypto.CurrentVersion, fileParams)
+			}
+
+			// Show the plan
+			fmt.Printf("Rekeying %d file(s):\n", len(changes))
+			for i, change := range changes {
+				fmt.Printf("  - %s: %s\n", change.file, plan[i])
+			}
+			if dryRun {
+				fmt.Println("Dry run: nothing was written.")
+				return
+			}
+
+			if !yes {
+				confirmed, err := utils.PromptForConfirmation("Re-encrypt these files?")
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if !confirmed {
+					fmt.Println("Operation cancelled.")
+					return
+				}
+			}
+
+			// Re-encrypt each file as a stream into a copy staged next to it
+			for i, change := range changes {
+				changes[i].staged, err = stageFile(change.file, func(w io.Writer) error {
+					encrypter, err := writers[i](w, crypto.IsArmored(heads[i]))
+					if err != nil {
+						return err
+					}
+					if err := decrypter.decryptFile(encrypter, change.file); err != nil {
+						return err
+					}
+					return encrypter.Close()
+				})
+				if err != nil {
+					removeStaged(changes)
+					fmt.Printf("Error encrypting '%s': %v\n", change.file, err)
+					fmt.Println("Nothing was changed.")
+					os.Exit(1)
+				}
+			}
+
+			if err := writeChangesAtomically(changes); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			switch {
+			case passwordFiles > 0 && recipientFiles > 0:
+				fmt.Printf("✓ Rekeyed %d file(s) with the new password and %d file(s) for %d recipient(s)\n", passwordFiles, recipientFiles, len(list.Entries))
+			case passwordFiles > 0:
+				fmt.Printf("✓ Rekeyed %d file(s) with the new password\n", passwordFiles)
+			default:
+				fmt.Printf("✓ Rekeyed %d file(s) for %d recipient(s)\n", recipientFiles, len(list.Entries))
+			}
+		},
+	}
+
+	decrypter.addFlags(cmd)
+	addSecretFlags(cmd, &newSecret, "new-password", credential.NewPasswordEnv)
+	addKDFFlags(cmd, &kdf)
+	cmd.Flags().StringVar(&only, "only", "", "only rekey files encrypted with a password or to recipients: password or recipients")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "verify the files and show the plan without writing anything")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "apply without asking for confirmation")
+
+	return cmd
+}
+
+// readEncryptedHead reads the start of an encrypted file that identifies its format
+func readEncryptedHead(file string) (string, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = in.Close() }()
+	return crypto.ReadHead(in)
+}
+
+// findRekeyFiles returns the encrypted files of the arguments: directories are searched
+// for *.encrypted files, glob patterns are expanded and files are taken as they are.
+// Without arguments the current directory is searched.
+func findRekeyFiles(args []string) ([]string, error) {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	seen := make(map[string]bool)
+	var files []string
+	add := func(file string) {
+		file = filepath.Clean(file)
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match '%s'", arg)
+			}
+			for _, match := range matches {
+				if info, err := os.Stat(match); err == nil && !info.IsDir() {
+					add(match)
+				}
+			}
+			continue
+		}
+
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			add(arg)
+			continue
+		}
+		found, err := guard.DiscoverEncryptedFiles(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range found {
+			add(filepath.Join(arg, filepath.FromSlash(file)))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
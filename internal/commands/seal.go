@@ -0,0 +1,331 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MayR-Labs/envdoc-go/internal/crypto"
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// NewSealCmd returns the seal command
+func NewSealCmd() *cobra.Command {
+	var keys []string
+	var kdf kdfOptions
+	var secret secretOptions
+	var preview previewOptions
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "seal [file]",
+		Short: "Encrypt each value of an env file in place, keeping keys readable",
+		Long: `Encrypts every non-empty value of the file in place (KEY=enc:v1:...) while keys,
+comments and layout stay readable, so sealed files can be committed and still be
+diffed, compared, synced and audited. --keys limits sealing to keys matching glob patterns.
+
+Each value is encrypted with AES-256-GCM under a key derived from the password (Argon2id by
+default, see 'envdoc encrypt --help' for --kdf, --kdf-profile and --kdf-target) and bound
+to its key name, so a sealed value cannot be moved to another key unnoticed. Already sealed
+values are left alone; they must have been sealed with the same password.
+
+Without the password, audit, compare, validate and doctor check sealed values for presence
+only; audit, compare and validate check the decrypted values with --unseal. sync adds keys
+whose template value is sealed with an empty value instead of copying the ciphertext.
+
+` + passwordSourcesHelp,
+		Example: `  envdoc seal .env.production
+  envdoc seal .env.production --keys "*_SECRET,*_PASSWORD,APP_KEY"
+  envdoc seal .env.production --password-file pw.txt --yes`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			inputFile := sealInputFile(args, "Select the .env file to seal:")
+
+			params, err := kdf.params()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			doc, err := parser.ReadDocument(inputFile)
+			if err != nil {
+				fmt.Printf("Error parsing file: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Get password, confirming it unless values sealed earlier verify it
+			sealedKey, sealedValue, hasSealed := firstSealedValue(doc)
+			password, err := secret.password("Enter sealing password:", !hasSealed)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			sealer, err := crypto.NewSealer(password, params)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Values sealed earlier must open with this password, so one password unseals the file
+			if hasSealed {
+				if _, err := sealer.Unseal(sealedKey, sealedValue); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					fmt.Println("Values that are already sealed must have been sealed with the same password.")
+					os.Exit(1)
+				}
+			}
+
+			// Seal values
+			sealed := 0
+			err = doc.UpdateValues(func(key, value string) (string, error) {
+				if value == "" || parser.IsSealed(value) || (len(keys) > 0 && !matchesAnyPattern(keys, key)) {
+					return value, nil
+				}
+				sealed++
+				return sealer.Seal(key, value)
+			})
+			if err != nil {
+				fmt.Printf("Error sealing: %v\n", err)
+				os.Exit(1)
+			}
+			if sealed == 0 {
+				fmt.Println("✓ Nothing to seal")
+				return
+			}
+
+			// Show preview
+			change, err := newFileChange(inputFile, doc.String())
+			if err != nil {
+				fmt.Printf("Error reading file: %v\n", err)
+				os.Exit(1)
+			}
+			if !showPreview([]fileChange{change}, preview) {
+				return
+			}
+
+			if !yes {
+				confirmed, err := utils.PromptForConfirmation(fmt.Sprintf("Seal %d value(s) in '%s'?", sealed, inputFile))
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if !confirmed {
+					fmt.Println("Operation cancelled.")
+					return
+				}
+			}
+
+			if err := writeChanges([]fileChange{change}); err != nil {
+				fmt.Printf("Error writing file: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Sealed %d value(s) in %s (%s)\n", sealed, inputFile, params)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&keys, "keys", nil, "only seal keys matching these glob patterns")
+	addKDFFlags(cmd, &kdf)
+	addPasswordFlags(cmd, &secret)
+	addPreviewFlags(cmd, &preview)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "write without asking for confirmation")
+
+	return cmd
+}
+
+// NewUnsealCmd returns the unseal command
+func NewUnsealCmd() *cobra.Command {
+	var keys []string
+	var toStdout bool
+	var unsealer valueUnsealer
+	var preview previewOptions
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "unseal [file]",
+		Short: "Decrypt the sealed values of an env file in place",
+		Long: `Decrypts the values sealed by 'envdoc seal' back to their original text. --keys limits
+unsealing to keys matching glob patterns, and --stdout prints the unsealed file instead of
+writing it. With --stdout only the file goes to stdout: prompts and errors go to stderr,
+and when stdout is not a terminal nothing is prompted for, so the file and the password
+must be given.
+
+` + passwordSourcesHelp,
+		Example: `  envdoc unseal .env.production
+  envdoc unseal .env.production --stdout --password-file ~/.envdoc-password > .env`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			output := os.Stdout
+			if toStdout {
+				// Everything but the file goes to stderr, so no prompt or error ends up in it
+				os.Stdout = os.Stderr
+				if !term.IsTerminal(int(output.Fd())) {
+					if len(args) == 0 {
+						fmt.Println("Error: No file given and stdout is not a terminal; name the file to unseal")
+						os.Exit(1)
+					}
+					unsealer.secret.noPrompt = "stdout is not a terminal"
+				}
+			}
+
+			inputFile := sealInputFile(args, "Select the .env file to unseal:")
+
+			doc, err := parser.ReadDocument(inputFile)
+			if err != nil {
+				fmt.Printf("Error parsing file: %v\n", err)
+				os.Exit(1)
+			}
+			if _, _, ok := firstSealedValue(doc); !ok {
+				if toStdout {
+					fmt.Fprint(output, doc.String())
+					return
+				}
+				fmt.Printf("✓ No sealed values in %s\n", inputFile)
+				return
+			}
+
+			// Unseal values
+			unsealed := 0
+			err = doc.UpdateValues(func(key, value string) (string, error) {
+				if !parser.IsSealed(value) || (len(keys) > 0 && !matchesAnyPattern(keys, key)) {
+					return value, nil
+				}
+				unsealed++
+				return unsealer.sealer().Unseal(key, value)
+			})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if toStdout {
+				fmt.Fprint(output, doc.String())
+				return
+			}
+
+			// Show preview
+			change, err := newFileChange(inputFile, doc.String())
+			if err != nil {
+				fmt.Printf("Error reading file: %v\n", err)
+				os.Exit(1)
+			}
+			if !showPreview([]fileChange{change}, preview) {
+				return
+			}
+
+			if !yes {
+				confirmed, err := utils.PromptForConfirmation(fmt.Sprintf("Write %d unsealed value(s) to '%s' in plain text?", unsealed, inputFile))
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if !confirmed {
+					fmt.Println("Operation cancelled.")
+					return
+				}
+			}
+
+			if err := writeChanges([]fileChange{change}); err != nil {
+				fmt.Printf("Error writing file: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Unsealed %d value(s) in %s\n", unsealed, inputFile)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&keys, "keys", nil, "only unseal keys matching these glob patterns")
+	cmd.Flags().BoolVarP(&toStdout, "stdout", "p", false, "print the unsealed file instead of writing it")
+	addPasswordFlags(cmd, &unsealer.secret)
+	addPreviewFlags(cmd, &preview)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "write without asking for confirmation")
+
+	return cmd
+}
+
+// valueUnsealer decrypts sealed values for commands that check values. It asks for the
+// password the first time a sealed value needs it.
+type valueUnsealer struct {
+	enabled bool
+	secret  secretOptions
+	s       *crypto.Sealer
+}
+
+// addFlag registers --unseal and the password flags on a command
+func (u *valueUnsealer) addFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&u.enabled, "unseal", false, "ask for the password and check sealed values decrypted (default: presence only)")
+	addPasswordFlags(cmd, &u.secret)
+}
+
+// unseal returns the variables with their sealed values decrypted when --unseal is set
+func (u *valueUnsealer) unseal(envVars []parser.EnvVar) []parser.EnvVar {
+	if !u.enabled {
+		return envVars
+	}
+	result := make([]parser.EnvVar, len(envVars))
+	for i, envVar := range envVars {
+		result[i] = envVar
+		if !parser.IsSealed(envVar.Value) {
+			continue
+		}
+		value, err := u.sealer().Unseal(envVar.Key, envVar.Value)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		result[i].Value = value
+	}
+	return result
+}
+
+// sealer reads the password on first use
+func (u *valueUnsealer) sealer() *crypto.Sealer {
+	if u.s != nil {
+		return u.s
+	}
+	password, err := u.secret.password("Enter sealing password:", false)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	u.s, err = crypto.NewSealer(password, crypto.DefaultKDFParams())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return u.s
+}
+
+// sealInputFile returns the file argument or prompts for one, and checks that it exists
+func sealInputFile(args []string, message string) string {
+	var inputFile string
+	var err error
+
+	// Get input file
+	if len(args) > 0 {
+		inputFile = args[0]
+	} else {
+		inputFile, err = utils.PromptForEnvFile(message)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Check if input file exists
+	if !utils.FileExists(inputFile) {
+		fmt.Printf("Error: File '%s' does not exist\n", inputFile)
+		os.Exit(1)
+	}
+	return inputFile
+}
+
+// firstSealedValue returns the first sealed assignment of a document
+func firstSealedValue(doc *parser.Document) (string, string, bool) {
+	for _, key := range doc.Keys() {
+		if value, _ := doc.Value(key); parser.IsSealed(value) {
+			return key, value, true
+		}
+	}
+	return "", "", false
+}
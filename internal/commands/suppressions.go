@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/suppress"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// NewSuppressionsCmd returns the suppressions command
+func NewSuppressionsCmd() *cobra.Command {
+	var expiredOnly bool
+	var output reportOptions
+
+	cmd := &cobra.Command{
+		Use:   "suppressions [files...]",
+		Short: "List inline envdoc-ignore suppressions and their expiry",
+		Long: `Lists the inline suppressions in .env files. A suppression is a comment on the line
+before a key:
+
+  # envdoc-ignore RULE[,RULE] reason="why this is accepted" until=YYYY-MM-DD
+
+It hides matching findings of audit, doctor and lint for that key until the expiry date.
+A reason and an expiry date are mandatory. With --expired only expired and invalid
+suppressions are listed and the command exits with status 1 if there are any.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			files := args
+			if len(files) == 0 {
+				var err error
+				files, err = findEnvFiles()
+				if err != nil {
+					fmt.Printf("Error finding .env files: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			var all []suppress.Suppression
+			for _, file := range files {
+				if !utils.FileExists(file) {
+					fmt.Printf("Error: File '%s' does not exist\n", file)
+					os.Exit(1)
+				}
+				suppressions, err := suppress.ParseFile(file)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				all = append(all, suppressions...)
+			}
+
+			// Generate report
+			suppressionsReport, stale := generateSuppressionsReport(files, all, expiredOnly, time.Now())
+
+			// Show options
+			handleReportOutput(suppressionsReport, "envdoc-suppressions", output)
+
+			if expiredOnly && stale > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&expiredOnly, "expired", false, "only list expired and invalid suppressions")
+	addReportFlags(cmd, &output)
+
+	return cmd
+}
+
+// generateSuppressionsReport lists suppressions and returns the report and the number of expired or invalid ones
+func generateSuppressionsReport(files []string, suppressions []suppress.Suppression, expiredOnly bool, now time.Time) (*report.Report, int) {
+	r := report.New("Inline Suppressions Report")
+
+	counts := make(map[suppress.Status]int)
+	table := report.Table{Columns: []report.Column{
+		{Title: "File", Code: true}, {Title: "Line"}, {Title: "Key", Code: true}, {Title: "Rules"}, {Title: "Until"}, {Title: "Status"}, {Title: "Reason"},
+	}}
+	for _, s := range suppressions {
+		status := s.Status(now)
+		counts[status]++
+		if expiredOnly && status == suppress.Active {
+			continue
+		}
+		reason := s.Reason
+		if status == suppress.Invalid {
+			reason = s.Problem
+		}
+		table.Rows = append(table.Rows, []string{s.File, fmt.Sprint(s.Line), s.Key, strings.Join(s.Rules, ", "), s.Until, string(status), reason})
+	}
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddField("Files Scanned", len(files))
+	overview.AddField("Active", counts[suppress.Active])
+	overview.AddField("Expired", counts[suppress.Expired])
+	overview.AddField("Invalid", counts[suppress.Invalid])
+	r.AddSection(overview)
+
+	section := report.Section{Title: "Suppressions", Empty: "✓ No suppressions found."}
+	if expiredOnly {
+		section.Title = "Expired and Invalid Suppressions"
+		section.Empty = "✓ No expired or invalid suppressions."
+	}
+	if len(table.Rows) > 0 {
+		section.Tables = append(section.Tables, table)
+	}
+	r.AddSection(section)
+
+	return r, counts[suppress.Expired] + counts[suppress.Invalid]
+}
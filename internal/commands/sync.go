@@ -3,25 +3,63 @@ package commands
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/MayR-Labs/envdoc-go/internal/parser"
 	"github.com/MayR-Labs/envdoc-go/internal/utils"
 	"github.com/spf13/cobra"
 )
 
+// syncPlan holds the changes sync makes to one file
+type syncPlan struct {
+	file    string
+	envVars []parser.EnvVar
+	missing []parser.EnvVar
+	extra   []string
+}
+
 // NewSyncCmd returns the sync command
 func NewSyncCmd() *cobra.Command {
-	return &cobra.Command{
+	var templateFile string
+	var prune, arrange bool
+	var preview previewOptions
+
+	cmd := &cobra.Command{
 		Use:   "sync [file1] [file2] [fileN...]",
 		Short: "Synchronize keys across multiple files",
-		Long: `Synchronizes environment variable keys across multiple specified files. 
-Missing keys in each file are added with empty values.`,
+		Long: `Synchronizes environment variable keys across multiple specified files.
+Missing keys in each file are added with empty values, next to their closest
+relatives: after the key that precedes them in the file they come from, or in the
+same prefix group, or otherwise in an "# Added by envdoc" section at the end. The
+rest of each file is left untouched; use --arrange to sort and group every file.
+
+With --template the template (e.g. .env.example) defines the key set instead of the
+union of all files: missing keys are added with the template's comment and value,
+and keys that are not in the template are reported, or removed with --prune.
+The template file itself is never modified.
+
+The exact changes are shown as a unified diff (values masked unless --show-values)
+before confirming; --dry-run stops after the preview and --diff-format json prints it
+as JSON for tooling.`,
+		Example: `  envdoc sync .env .env.staging .env.production
+  envdoc sync --template .env.example .env .env.staging
+  envdoc sync --template .env.example --prune .env`,
 		Run: func(cmd *cobra.Command, args []string) {
 			var files []string
 			var err error
 
+			if prune && templateFile == "" {
+				fmt.Println("Error: --prune requires --template")
+				os.Exit(1)
+			}
+			minFiles := 2
+			if templateFile != "" {
+				minFiles = 1
+			}
+
 			// Get files
-			if len(args) >= 2 {
+			if len(args) >= minFiles {
 				files = args
 			} else {
 				files, err = utils.PromptForMultipleEnvFiles("Select the .env files to sync:")
@@ -29,15 +67,24 @@ Missing keys in each file are added with empty values.`,
 					fmt.Printf("Error: %v\n", err)
 					os.Exit(1)
 				}
-				if len(files) < 2 {
+			}
+
+			// Never write to the template
+			if templateFile != "" {
+				files = withoutFile(files, templateFile)
+			}
+			if len(files) < minFiles {
+				if templateFile != "" {
+					fmt.Println("Error: At least 1 file besides the template is required for synchronization")
+				} else {
 					fmt.Println("Error: At least 2 files are required for synchronization")
-					os.Exit(1)
 				}
+				os.Exit(1)
 			}
 
 			// Check if all files exist
-			for _, file := range files {
-				if !utils.FileExists(file) {
+			for _, file := range append([]string{templateFile}, files...) {
+				if file != "" && !utils.FileExists(file) {
 					fmt.Printf("Error: File '%s' does not exist\n", file)
 					os.Exit(1)
 				}
@@ -54,30 +101,77 @@ Missing keys in each file are added with empty values.`,
 				allEnvVars[file] = envVars
 			}
 
-			// Collect all unique keys
-			allKeys := make(map[string]bool)
-			for _, envVars := range allEnvVars {
-				for _, envVar := range envVars {
-					allKeys[envVar.Key] = true
+			// Work out the key set: the template's keys or all unique keys
+			var keySet []parser.EnvVar
+			if templateFile != "" {
+				keySet, err = parser.ParseEnvFile(templateFile)
+				if err != nil {
+					fmt.Printf("Error parsing template '%s': %v\n", templateFile, err)
+					os.Exit(1)
+				}
+			} else {
+				seen := make(map[string]bool)
+				for _, file := range files {
+					for _, envVar := range allEnvVars[file] {
+						if !seen[envVar.Key] {
+							seen[envVar.Key] = true
+							keySet = append(keySet, parser.EnvVar{Key: envVar.Key})
+						}
+					}
+				}
+			}
+
+			plans := planSync(files, allEnvVars, keySet, templateFile != "")
+
+			// Compute the new content of every file
+			var changes []fileChange
+			for _, plan := range plans {
+				var content string
+				if arrange {
+					content = renderArranged(plan, prune)
+				} else {
+					content, err = renderInPlace(plan, prune, func(key string) []string {
+						if templateFile != "" {
+							return parser.GetEnvKeys(keySet)
+						}
+						return referenceOrder(key, files, allEnvVars)
+					})
+					if err != nil {
+						fmt.Printf("Error reading file '%s': %v\n", plan.file, err)
+						os.Exit(1)
+					}
+				}
+				change, err := newFileChange(plan.file, content)
+				if err != nil {
+					fmt.Printf("Error reading file '%s': %v\n", plan.file, err)
+					os.Exit(1)
 				}
+				changes = append(changes, change)
 			}
 
 			// Show preview of changes
-			fmt.Println("\nSynchronization Preview:")
-			fmt.Println("========================")
-			for file, envVars := range allEnvVars {
-				fileKeys := parser.GetEnvKeys(envVars)
-				var allKeysList []string
-				for key := range allKeys {
-					allKeysList = append(allKeysList, key)
+			if !preview.quiet() {
+				fmt.Println("\nSynchronization Preview:")
+				fmt.Println("========================")
+				if templateFile != "" {
+					fmt.Printf("Template: %s (%d keys, not modified)\n", templateFile, len(keySet))
+				}
+				for _, plan := range plans {
+					fmt.Printf("%s: %d keys to add", plan.file, len(plan.missing))
+					if prune {
+						fmt.Printf(", %d keys to remove", len(plan.extra))
+					} else if len(plan.extra) > 0 {
+						fmt.Printf(", %d keys not in the template (%s)", len(plan.extra), strings.Join(plan.extra, ", "))
+					}
+					fmt.Println()
 				}
-				missing := parser.FindMissingKeys(allKeysList, fileKeys)
-				fmt.Printf("\n%s: %d keys to add\n", file, len(missing))
-				for _, key := range missing {
-					fmt.Printf("  + %s\n", key)
+				if !prune && templateFile != "" && hasExtraKeys(plans) {
+					fmt.Println("Add keys that are not in the template to it, or remove them with --prune.")
 				}
 			}
-			fmt.Println()
+			if !showPreview(changes, preview) {
+				return
+			}
 
 			// Confirm with PIN
 			confirmed, err := utils.ConfirmWithPin("This will modify the specified files.")
@@ -91,31 +185,129 @@ Missing keys in each file are added with empty values.`,
 			}
 
 			// Synchronize files
-			for file, envVars := range allEnvVars {
-				fileKeys := make(map[string]bool)
-				for _, envVar := range envVars {
-					fileKeys[envVar.Key] = true
-				}
+			if err := writeChanges(changes); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
 
-				// Add missing keys
-				for key := range allKeys {
-					if !fileKeys[key] {
-						envVars = append(envVars, parser.EnvVar{
-							Key:   key,
-							Value: "",
-						})
-					}
-				}
+			fmt.Println("✓ Files synchronized successfully")
+		},
+	}
 
-				// Sort and write
-				envVars = parser.ArrangeByPrefix(envVars)
-				if err := parser.WriteEnvFile(file, envVars); err != nil {
-					fmt.Printf("Error writing file '%s': %v\n", file, err)
-					os.Exit(1)
+	cmd.Flags().StringVarP(&templateFile, "template", "t", "", "template file that defines the key set (e.g. .env.example); never modified")
+	cmd.Flags().BoolVar(&prune, "prune", false, "remove keys that are not in the template (requires --template)")
+	cmd.Flags().BoolVar(&arrange, "arrange", false, "sort and group every file by prefix instead of preserving its layout")
+	addPreviewFlags(cmd, &preview)
+
+	return cmd
+}
+
+// planSync works out the keys to add to each file and, with a template, the keys the template does not have
+func planSync(files []string, allEnvVars map[string][]parser.EnvVar, keySet []parser.EnvVar, fromTemplate bool) []syncPlan {
+	inKeySet := make(map[string]bool)
+	for _, envVar := range keySet {
+		inKeySet[envVar.Key] = true
+	}
+
+	var plans []syncPlan
+	for _, file := range files {
+		plan := syncPlan{file: file, envVars: allEnvVars[file]}
+		fileKeys := make(map[string]bool)
+		for _, envVar := range plan.envVars {
+			if fromTemplate && !inKeySet[envVar.Key] && !fileKeys[envVar.Key] {
+				plan.extra = append(plan.extra, envVar.Key)
+			}
+			fileKeys[envVar.Key] = true
+		}
+		for _, envVar := range keySet {
+			if !fileKeys[envVar.Key] {
+				fileKeys[envVar.Key] = true
+				// Sealed secrets are not spread to other files; the key is added empty
+				value := envVar.Value
+				if parser.IsSealed(value) {
+					value = ""
 				}
+				plan.missing = append(plan.missing, parser.EnvVar{Key: envVar.Key, Value: value, Comment: envVar.Comment})
 			}
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
 
-			fmt.Println("✓ Files synchronized successfully")
-		},
+// renderInPlace inserts the missing keys next to their closest relatives and leaves the rest of the file as it is.
+// order returns the key order of the file a missing key comes from.
+func renderInPlace(plan syncPlan, prune bool, order func(key string) []string) (string, error) {
+	doc, err := parser.ReadDocument(plan.file)
+	if err != nil {
+		return "", err
+	}
+	if prune {
+		for _, key := range plan.extra {
+			doc.Remove(key)
+		}
+	}
+	for _, envVar := range plan.missing {
+		doc.Insert(envVar, order(envVar.Key))
+	}
+	return doc.String(), nil
+}
+
+// renderArranged returns the file sorted and grouped by prefix
+func renderArranged(plan syncPlan, prune bool) string {
+	envVars := append([]parser.EnvVar(nil), plan.envVars...)
+	if prune {
+		envVars = removeKeys(envVars, plan.extra)
+	}
+	envVars = append(envVars, plan.missing...)
+	return parser.FormatEnv(parser.ArrangeByPrefix(envVars))
+}
+
+// referenceOrder returns the key order of the first file that defines the key
+func referenceOrder(key string, files []string, allEnvVars map[string][]parser.EnvVar) []string {
+	for _, file := range files {
+		keys := parser.GetEnvKeys(allEnvVars[file])
+		for _, k := range keys {
+			if k == key {
+				return keys
+			}
+		}
+	}
+	return nil
+}
+
+// hasExtraKeys reports whether any file has keys that are not in the template
+func hasExtraKeys(plans []syncPlan) bool {
+	for _, plan := range plans {
+		if len(plan.extra) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// removeKeys drops every occurrence of the given keys
+func removeKeys(envVars []parser.EnvVar, keys []string) []parser.EnvVar {
+	drop := make(map[string]bool)
+	for _, key := range keys {
+		drop[key] = true
+	}
+	var kept []parser.EnvVar
+	for _, envVar := range envVars {
+		if !drop[envVar.Key] {
+			kept = append(kept, envVar)
+		}
+	}
+	return kept
+}
+
+// withoutFile removes a file from a list, comparing cleaned paths
+func withoutFile(files []string, file string) []string {
+	var result []string
+	for _, f := range files {
+		if filepath.Clean(f) != filepath.Clean(file) {
+			result = append(result, f)
+		}
 	}
+	return result
 }
@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/config"
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/usage"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/MayR-Labs/envdoc-go/internal/validator"
+	"github.com/spf13/cobra"
+)
+
+// NewUsageCmd returns the usage command
+func NewUsageCmd() *cobra.Command {
+	var envFiles, excludes []string
+	var schemaFile string
+	var output reportOptions
+
+	cmd := &cobra.Command{
+		Use:   "usage [dir]",
+		Short: "Find env keys used in source code but not declared, and declared but unused",
+		Long: `Scans a source tree for environment variable reads (Go, JavaScript/TypeScript,
+Python, PHP, Ruby, Rust and shell scripts) and compares them with the keys declared in
+env files or a schema. The report lists keys used but not declared, keys declared but
+never read, and where each key is used. Keys used but not declared are errors and
+make the command exit with status 1.
+
+Declared keys come from --env files and --schema. Without either, .env.example is used
+when present, otherwise every .env file in the current directory.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			root := "."
+			if len(args) > 0 {
+				root = args[0]
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Work out where declared keys come from
+			if len(envFiles) == 0 && schemaFile == "" {
+				envFiles, err = defaultDeclarationFiles()
+				if err != nil {
+					fmt.Printf("Error finding .env files: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			if len(envFiles) == 0 && schemaFile == "" {
+				fmt.Println("Error: No env files or schema to compare against. Use --env or --schema.")
+				os.Exit(1)
+			}
+
+			declared, _, err := loadDeclarations(envFiles, schemaFile)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			used, err := usage.Scan(root, append(cfg.Usage.Exclude, excludes...))
+			if err != nil {
+				fmt.Printf("Error scanning source tree: %v\n", err)
+				os.Exit(1)
+			}
+
+			sources := append(append([]string(nil), envFiles...), schemaFile)
+			usageReport := generateUsageReport(root, sources, declared, used, cfg.Usage.Ignore)
+
+			// Show options
+			handleReportOutput(usageReport, "envdoc-usage", output)
+
+			exitOnErrors(usageReport)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&envFiles, "env", nil, "env files that declare keys (default: .env.example or all .env files)")
+	cmd.Flags().StringVar(&schemaFile, "schema", "", "JSON schema that declares keys")
+	cmd.Flags().StringSliceVar(&excludes, "exclude", nil, "extra file or directory name patterns to skip")
+	addReportFlags(cmd, &output)
+
+	return cmd
+}
+
+func generateUsageReport(root string, sources []string, declared map[string]string, used usage.Result, ignore []string) *report.Report {
+	r := report.New("Environment Variables Usage Report")
+
+	var undeclared, unused []report.Finding
+	for _, key := range used.Keys() {
+		if _, ok := declared[key]; ok || matchesAnyPattern(ignore, key) {
+			continue
+		}
+		first := used[key][0]
+		undeclared = append(undeclared, report.Finding{
+			Severity: report.Error,
+			Rule:     "undeclared-key",
+			File:     first.File,
+			Line:     first.Line,
+			Key:      key,
+			Message:  fmt.Sprintf("Read in %d place(s) but not declared", len(used[key])),
+		})
+	}
+
+	declaredKeys := make([]string, 0, len(declared))
+	for key := range declared {
+		declaredKeys = append(declaredKeys, key)
+	}
+	sort.Strings(declaredKeys)
+	for _, key := range declaredKeys {
+		if _, ok := used[key]; ok || matchesAnyPattern(ignore, key) {
+			continue
+		}
+		unused = append(unused, report.Finding{
+			Severity: report.Warning,
+			Rule:     "unused-key",
+			File:     declared[key],
+			Key:      key,
+			Message:  "Declared but never read in the scanned sources",
+		})
+	}
+
+	var declaredIn []string
+	for _, source := range sources {
+		if source != "" {
+			declaredIn = append(declaredIn, "`"+source+"`")
+		}
+	}
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddCodeField("Scanned Directory", root)
+	overview.AddField("Declared In", strings.Join(declaredIn, ", "))
+	overview.AddField("Declared Keys", len(declared))
+	overview.AddField("Keys Read in Code", len(used))
+	overview.AddField("Used but Not Declared", len(undeclared))
+	overview.AddField("Declared but Unused", len(unused))
+	r.AddSection(overview)
+
+	r.AddSection(report.Section{
+		Title:    "Used but Not Declared",
+		Findings: undeclared,
+		Empty:    "✓ Every key read in code is declared.",
+	})
+	r.AddSection(report.Section{
+		Title:    "Declared but Unused",
+		Text:     "Keys can also be read by frameworks or infrastructure (e.g. Laravel config files, docker-compose) that are not scanned. Check before removing them.",
+		Findings: unused,
+		Empty:    "✓ Every declared key is read somewhere.",
+	})
+
+	locations := report.Table{Columns: []report.Column{{Title: "Key", Code: true}, {Title: "Declared"}, {Title: "Used In"}}}
+	for _, key := range used.Keys() {
+		var places []string
+		for _, location := range used[key] {
+			places = append(places, fmt.Sprintf("%s:%d", filepath.ToSlash(location.File), location.Line))
+		}
+		isDeclared := "✗"
+		if _, ok := declared[key]; ok {
+			isDeclared = "✓"
+		}
+		locations.Rows = append(locations.Rows, []string{key, isDeclared, strings.Join(places, ", ")})
+	}
+	usageSection := report.Section{Title: "Usage Locations", Empty: "No environment variable reads found."}
+	if len(locations.Rows) > 0 {
+		usageSection.Tables = append(usageSection.Tables, locations)
+	}
+	r.AddSection(usageSection)
+
+	return r
+}
+
+// defaultDeclarationFiles returns .env.example when present, otherwise every .env file in the current directory
+func defaultDeclarationFiles() ([]string, error) {
+	if utils.FileExists(".env.example") {
+		return []string{".env.example"}, nil
+	}
+	return findEnvFiles()
+}
+
+// loadDeclarations maps the keys declared in env files and an optional schema to the file
+// declaring them first. The parsed schema is nil when no schema file is given.
+func loadDeclarations(envFiles []string, schemaFile string) (map[string]string, *validator.Schema, error) {
+	declared := make(map[string]string)
+	for _, file := range envFiles {
+		envVars, err := parser.ParseEnvFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse '%s': %w", file, err)
+		}
+		for _, envVar := range envVars {
+			if _, ok := declared[envVar.Key]; !ok {
+				declared[envVar.Key] = file
+			}
+		}
+	}
+	if schemaFile == "" {
+		return declared, nil, nil
+	}
+
+	schemaJSON, err := utils.ReadFromFile(schemaFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	schema, err := validator.ParseSchema(schemaJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, key := range schema.Keys() {
+		if _, ok := declared[key]; !ok {
+			declared[key] = schemaFile
+		}
+	}
+	return declared, schema, nil
+}
+
+// matchesAnyPattern reports whether the key matches any of the glob patterns
+func matchesAnyPattern(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
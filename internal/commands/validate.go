@@ -5,7 +5,11 @@ import (
 	"os"
 	"strings"
 
+	"github.com/MayR-Labs/envdoc-go/internal/config"
+	"github.com/MayR-Labs/envdoc-go/internal/insecure"
 	"github.com/MayR-Labs/envdoc-go/internal/parser"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/secrets"
 	"github.com/MayR-Labs/envdoc-go/internal/utils"
 	"github.com/MayR-Labs/envdoc-go/internal/validator"
 	"github.com/spf13/cobra"
@@ -13,11 +17,15 @@ import (
 
 // NewValidateCmd returns the validate command
 func NewValidateCmd() *cobra.Command {
-	return &cobra.Command{
+	var output reportOptions
+	var unsealer valueUnsealer
+
+	cmd := &cobra.Command{
 		Use:   "validate [file] [schema-file]",
 		Short: "Validate a file against a JSON schema",
 		Long: `Validates the specified file against the provided JSON schema file.
-A report is generated detailing any discrepancies found during validation.`,
+A report is generated detailing any discrepancies found during validation.
+Sealed values are only checked for presence unless --unseal is given.`,
 		Args: cobra.MaximumNArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
 			var inputFile, schemaFile string
@@ -70,6 +78,7 @@ A report is generated detailing any discrepancies found during validation.`,
 			}
 
 			// Validate
+			envVars = unsealer.unseal(envVars)
 			errors, err := validator.ValidateAgainstSchema(envVars, schemaJSON)
 			if err != nil {
 				fmt.Printf("Error validating: %v\n", err)
@@ -77,22 +86,69 @@ A report is generated detailing any discrepancies found during validation.`,
 			}
 
 			// Generate report
-			report := generateValidationReport(inputFile, schemaFile, errors)
+			validationReport := generateValidationReport(inputFile, schemaFile, errors)
 
 			// Show options
-			handleReportOutput(report, "envdoc-validate")
+			handleReportOutput(validationReport, "envdoc-validate", output)
 		},
 	}
+
+	unsealer.addFlag(cmd)
+	addReportFlags(cmd, &output)
+
+	return cmd
 }
 
 // NewDoctorCmd returns the doctor command
 func NewDoctorCmd() *cobra.Command {
-	return &cobra.Command{
+	var workspaceMode bool
+	var known baselineOptions
+	var output reportOptions
+
+	cmd := &cobra.Command{
 		Use:   "doctor",
 		Short: "Audit all .env files in the current directory",
 		Long: `Audits and compares every .env file (.env, .env.*) except encrypted files
-in the current working directory. A comprehensive report is generated.`,
+in the current working directory. A comprehensive report is generated.
+
+With --workspace, every directory below the current one that holds .env files is
+treated as a separate service (a group). Each group is audited on its own and the
+results are combined into one report with a summary table and a section per service.`,
 		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if workspaceMode {
+				groups := discoverWorkspace(cfg)
+				if len(groups) == 0 {
+					fmt.Println("No .env files found in the workspace")
+					return
+				}
+
+				// Generate aggregated report
+				workspaceReport, err := generateWorkspaceDoctorReport(groups, cfg)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				var files []string
+				for _, group := range groups {
+					files = append(files, group.Files...)
+				}
+				gate := filterFindings(workspaceReport, files, known)
+
+				// Show options
+				handleReportOutput(workspaceReport, "envdoc-doctor", output)
+
+				if gate {
+					exitOnErrors(workspaceReport)
+				}
+				return
+			}
+
 			// Find all .env files
 			files, err := findEnvFiles()
 			if err != nil {
@@ -112,89 +168,118 @@ in the current working directory. A comprehensive report is generated.`,
 			fmt.Println()
 
 			// Parse all files
-			allEnvVars := make(map[string][]parser.EnvVar)
-			for _, file := range files {
-				envVars, err := parser.ParseEnvFile(file)
-				if err != nil {
-					fmt.Printf("Warning: Could not parse '%s': %v\n", file, err)
-					continue
-				}
-				allEnvVars[file] = envVars
-			}
+			allEnvVars := parseEnvFiles(files)
 
 			// Generate comprehensive report
-			report := generateDoctorReport(allEnvVars)
+			doctorReport, err := generateDoctorReport(allEnvVars, cfg)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			gate := filterFindings(doctorReport, sortedFiles(allEnvVars), known)
 
 			// Show options
-			handleReportOutput(report, "envdoc-doctor")
+			handleReportOutput(doctorReport, "envdoc-doctor", output)
+
+			if gate {
+				exitOnErrors(doctorReport)
+			}
 		},
 	}
+
+	cmd.Flags().BoolVarP(&workspaceMode, "workspace", "w", false, "audit every directory with .env files below the current one as a separate service")
+	addBaselineFlags(cmd, &known)
+	addReportFlags(cmd, &output)
+
+	return cmd
 }
 
 // NewEngineerCmd returns the engineer command
 func NewEngineerCmd() *cobra.Command {
-	return &cobra.Command{
+	var workspaceMode, arrange bool
+	var preview previewOptions
+
+	cmd := &cobra.Command{
 		Use:   "engineer",
-		Short: "Sync and arrange all .env files in the current directory",
-		Long: `Synchronizes and arranges every .env file (.env, .env.*) except encrypted files
-in the current working directory.`,
+		Short: "Sync all .env files in the current directory",
+		Long: `Synchronizes every .env file (.env, .env.*) except encrypted files in the current
+working directory. Missing keys are inserted next to their closest relatives and the
+rest of each file is left as it is; with --arrange every file is also sorted and
+grouped by prefix. The changes are shown as a unified diff before confirming
+(--dry-run stops there).
+
+With --workspace, every directory below the current one that holds .env files is
+engineered separately: keys are only synchronized between files of the same directory.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			// Find all .env files
-			files, err := findEnvFiles()
-			if err != nil {
-				fmt.Printf("Error finding .env files: %v\n", err)
-				os.Exit(1)
-			}
+			var groups []map[string][]parser.EnvVar
 
-			if len(files) == 0 {
-				fmt.Println("No .env files found in the current directory")
-				return
-			}
+			if workspaceMode {
+				cfg, err := config.Load()
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				for _, group := range discoverWorkspace(cfg) {
+					groups = append(groups, parseEnvFiles(group.Files))
+				}
+				if len(groups) == 0 {
+					fmt.Println("No .env files found in the workspace")
+					return
+				}
+			} else {
+				// Find all .env files
+				files, err := findEnvFiles()
+				if err != nil {
+					fmt.Printf("Error finding .env files: %v\n", err)
+					os.Exit(1)
+				}
 
-			fmt.Printf("Found %d .env file(s):\n", len(files))
-			for _, file := range files {
-				fmt.Printf("  - %s\n", file)
-			}
-			fmt.Println()
+				if len(files) == 0 {
+					fmt.Println("No .env files found in the current directory")
+					return
+				}
 
-			// Parse all files
-			allEnvVars := make(map[string][]parser.EnvVar)
-			for _, file := range files {
-				envVars, err := parser.ParseEnvFile(file)
-				if err != nil {
-					fmt.Printf("Warning: Could not parse '%s': %v\n", file, err)
-					continue
+				if !preview.quiet() {
+					fmt.Printf("Found %d .env file(s):\n", len(files))
+					for _, file := range files {
+						fmt.Printf("  - %s\n", file)
+					}
+					fmt.Println()
 				}
-				allEnvVars[file] = envVars
+
+				// Parse all files
+				groups = append(groups, parseEnvFiles(files))
 			}
 
-			// Collect all unique keys
-			allKeys := make(map[string]bool)
-			for _, envVars := range allEnvVars {
-				for _, envVar := range envVars {
-					allKeys[envVar.Key] = true
+			// Compute the new content of every file
+			var changes []fileChange
+			for _, allEnvVars := range groups {
+				groupChanges, err := engineerChanges(allEnvVars, arrange)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
 				}
+				changes = append(changes, groupChanges...)
 			}
 
 			// Show preview
-			fmt.Println("Engineering Preview:")
-			fmt.Println("===================")
-			for file, envVars := range allEnvVars {
-				fileKeys := parser.GetEnvKeys(envVars)
-				var allKeysList []string
-				for key := range allKeys {
-					allKeysList = append(allKeysList, key)
+			if !preview.quiet() {
+				fmt.Println("Engineering Preview:")
+				fmt.Println("===================")
+				for _, allEnvVars := range groups {
+					showEngineerPreview(allEnvVars, arrange)
 				}
-				missing := parser.FindMissingKeys(allKeysList, fileKeys)
-				fmt.Printf("\n%s:\n", file)
-				fmt.Printf("  - Current keys: %d\n", len(envVars))
-				fmt.Printf("  - Keys to add: %d\n", len(missing))
-				fmt.Printf("  - Will be arranged: Yes\n")
 			}
-			fmt.Println()
+			if !showPreview(changes, preview) {
+				return
+			}
 
 			// Confirm with PIN
-			confirmed, err := utils.ConfirmWithPin("This will synchronize and arrange all .env files.")
+			message := "This will synchronize all .env files."
+			if arrange {
+				message = "This will synchronize and arrange all .env files."
+			}
+			confirmed, err := utils.ConfirmWithPin(message)
 			if err != nil {
 				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
@@ -204,37 +289,119 @@ in the current working directory.`,
 				return
 			}
 
-			// Synchronize and arrange
-			for file, envVars := range allEnvVars {
-				fileKeys := make(map[string]bool)
-				for _, envVar := range envVars {
-					fileKeys[envVar.Key] = true
+			// Synchronize
+			if err := writeChanges(changes); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, change := range changes {
+				if change.before != change.after {
+					fmt.Printf("✓ Engineered: %s\n", change.file)
 				}
+			}
 
-				// Add missing keys
-				for key := range allKeys {
-					if !fileKeys[key] {
-						envVars = append(envVars, parser.EnvVar{
-							Key:   key,
-							Value: "",
-						})
-					}
-				}
+			fmt.Println("\n✓ All files engineered successfully")
+		},
+	}
 
-				// Sort and arrange
-				envVars = parser.ArrangeByPrefix(envVars)
+	cmd.Flags().BoolVarP(&workspaceMode, "workspace", "w", false, "engineer every directory with .env files below the current one separately")
+	cmd.Flags().BoolVar(&arrange, "arrange", false, "also sort and group every file by prefix")
+	addPreviewFlags(cmd, &preview)
 
-				// Write back
-				if err := parser.WriteEnvFile(file, envVars); err != nil {
-					fmt.Printf("Error writing '%s': %v\n", file, err)
-					continue
-				}
-				fmt.Printf("✓ Engineered: %s\n", file)
+	return cmd
+}
+
+// parseEnvFiles parses the files, warning about and skipping files that cannot be read
+func parseEnvFiles(files []string) map[string][]parser.EnvVar {
+	allEnvVars := make(map[string][]parser.EnvVar)
+	for _, file := range files {
+		envVars, err := parser.ParseEnvFile(file)
+		if err != nil {
+			fmt.Printf("Warning: Could not parse '%s': %v\n", file, err)
+			continue
+		}
+		allEnvVars[file] = envVars
+	}
+	return allEnvVars
+}
+
+// unionKeys returns every key defined in any of the files
+func unionKeys(allEnvVars map[string][]parser.EnvVar) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, file := range sortedFiles(allEnvVars) {
+		for _, envVar := range allEnvVars[file] {
+			if !seen[envVar.Key] {
+				seen[envVar.Key] = true
+				keys = append(keys, envVar.Key)
 			}
+		}
+	}
+	return keys
+}
 
-			fmt.Println("\n✓ All files engineered successfully")
-		},
+// showEngineerPreview prints what engineering will change in each file
+func showEngineerPreview(allEnvVars map[string][]parser.EnvVar, arrange bool) {
+	allKeys := unionKeys(allEnvVars)
+	for _, file := range sortedFiles(allEnvVars) {
+		envVars := allEnvVars[file]
+		missing := parser.FindMissingKeys(allKeys, parser.GetEnvKeys(envVars))
+		fmt.Printf("\n%s:\n", file)
+		fmt.Printf("  - Current keys: %d\n", len(envVars))
+		fmt.Printf("  - Keys to add: %d\n", len(missing))
+		if arrange {
+			fmt.Printf("  - Will be arranged: Yes\n")
+		} else {
+			fmt.Printf("  - Will be arranged: No (layout preserved)\n")
+		}
+	}
+}
+
+// engineerChanges computes the synchronized content of every file of a group
+func engineerChanges(allEnvVars map[string][]parser.EnvVar, arrange bool) ([]fileChange, error) {
+	allKeys := unionKeys(allEnvVars)
+	files := sortedFiles(allEnvVars)
+	var changes []fileChange
+	for _, file := range files {
+		envVars := allEnvVars[file]
+		fileKeys := make(map[string]bool)
+		for _, envVar := range envVars {
+			fileKeys[envVar.Key] = true
+		}
+
+		// Add missing keys
+		var missing []parser.EnvVar
+		for _, key := range allKeys {
+			if !fileKeys[key] {
+				missing = append(missing, parser.EnvVar{
+					Key:   key,
+					Value: "",
+				})
+			}
+		}
+
+		// Keep the layout unless arranging
+		plan := syncPlan{file: file, envVars: envVars, missing: missing}
+		var content string
+		if arrange {
+			content = renderArranged(plan, false)
+		} else {
+			var err error
+			content, err = renderInPlace(plan, false, func(key string) []string {
+				return referenceOrder(key, files, allEnvVars)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read '%s': %w", file, err)
+			}
+		}
+
+		change, err := newFileChange(file, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s': %w", file, err)
+		}
+		changes = append(changes, change)
 	}
+	return changes, nil
 }
 
 func findEnvFiles() ([]string, error) {
@@ -257,103 +424,73 @@ func findEnvFiles() ([]string, error) {
 	return files, nil
 }
 
-func generateValidationReport(inputFile, schemaFile string, errors []string) string {
-	var sb strings.Builder
-
-	sb.WriteString("# Environment Variables Validation Report\n\n")
-	sb.WriteString("## Table of Contents\n")
-	sb.WriteString("- [Overview](#overview)\n")
-	sb.WriteString("- [Validation Errors](#validation-errors)\n\n")
-
-	sb.WriteString("## Overview\n\n")
-	sb.WriteString(fmt.Sprintf("**File:** `%s`\n\n", inputFile))
-	sb.WriteString(fmt.Sprintf("**Schema:** `%s`\n\n", schemaFile))
-	sb.WriteString(fmt.Sprintf("**Errors Found:** %d\n\n", len(errors)))
-
-	sb.WriteString("## Validation Errors\n\n")
-	if len(errors) == 0 {
-		sb.WriteString("✓ Validation passed! No errors found.\n\n")
-	} else {
-		sb.WriteString("| Error |\n")
-		sb.WriteString("|-------|\n")
-		for _, err := range errors {
-			sb.WriteString(fmt.Sprintf("| %s |\n", err))
-		}
-		sb.WriteString("\n")
+func generateValidationReport(inputFile, schemaFile string, errors []string) *report.Report {
+	r := report.New("Environment Variables Validation Report")
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddCodeField("File", inputFile)
+	overview.AddCodeField("Schema", schemaFile)
+	overview.AddField("Errors Found", len(errors))
+	r.AddSection(overview)
+
+	section := report.Section{Title: "Validation Errors", Empty: "✓ Validation passed! No errors found."}
+	for _, err := range errors {
+		section.Findings = append(section.Findings, report.Finding{Severity: report.Error, File: inputFile, Message: err})
 	}
+	r.AddSection(section)
 
-	return sb.String()
+	return r
 }
 
-func generateDoctorReport(allEnvVars map[string][]parser.EnvVar) string {
-	var sb strings.Builder
-
-	sb.WriteString("# Environment Variables Doctor Report\n\n")
-	sb.WriteString("## Table of Contents\n")
-	sb.WriteString("- [Overview](#overview)\n")
-	sb.WriteString("- [Files Analyzed](#files-analyzed)\n")
-	sb.WriteString("- [Duplicates](#duplicates)\n")
-	sb.WriteString("- [Missing Keys](#missing-keys)\n\n")
-
-	sb.WriteString("## Overview\n\n")
-	sb.WriteString(fmt.Sprintf("**Files Analyzed:** %d\n\n", len(allEnvVars)))
-
-	sb.WriteString("## Files Analyzed\n\n")
-	for file, envVars := range allEnvVars {
-		duplicates := parser.FindDuplicates(envVars)
-		sb.WriteString(fmt.Sprintf("### `%s`\n\n", file))
-		sb.WriteString(fmt.Sprintf("- **Total Keys:** %d\n", len(envVars)))
-		sb.WriteString(fmt.Sprintf("- **Duplicate Keys:** %d\n\n", len(duplicates)))
-	}
+func generateDoctorReport(allEnvVars map[string][]parser.EnvVar, cfg *config.Config) (*report.Report, error) {
+	r := report.New("Environment Variables Doctor Report")
+	files := sortedFiles(allEnvVars)
 
-	sb.WriteString("## Duplicates\n\n")
-	hasDuplicates := false
-	for file, envVars := range allEnvVars {
-		duplicates := parser.FindDuplicates(envVars)
-		if len(duplicates) > 0 {
-			hasDuplicates = true
-			sb.WriteString(fmt.Sprintf("### `%s`\n\n", file))
-			sb.WriteString("| Key |\n")
-			sb.WriteString("|-----|\n")
-			for _, key := range duplicates {
-				sb.WriteString(fmt.Sprintf("| `%s` |\n", key))
-			}
-			sb.WriteString("\n")
-		}
-	}
-	if !hasDuplicates {
-		sb.WriteString("✓ No duplicate keys found in any file.\n\n")
-	}
+	overview := report.Section{Title: "Overview"}
+	overview.AddField("Files Analyzed", len(files))
+	r.AddSection(overview)
 
-	// Collect all keys
-	allKeys := make(map[string]bool)
-	for _, envVars := range allEnvVars {
-		for _, envVar := range envVars {
-			allKeys[envVar.Key] = true
+	analyzed := report.Section{Title: "Files Analyzed"}
+	summary := report.Table{Columns: []report.Column{{Title: "File", Code: true}, {Title: "Total Keys"}, {Title: "Duplicate Keys"}}}
+	for _, file := range files {
+		envVars := allEnvVars[file]
+		summary.Rows = append(summary.Rows, []string{file, fmt.Sprint(len(envVars)), fmt.Sprint(len(parser.FindDuplicates(envVars)))})
+	}
+	analyzed.Tables = append(analyzed.Tables, summary)
+	r.AddSection(analyzed)
+
+	duplicates := report.Section{Title: "Duplicates", Empty: "✓ No duplicate keys found in any file."}
+	for _, file := range files {
+		if dups := parser.FindDuplicates(allEnvVars[file]); len(dups) > 0 {
+			duplicates.AddSubsection(report.Section{
+				Title:    fmt.Sprintf("`%s`", file),
+				Findings: keyFindings(file, dups, "duplicate-key", report.Error),
+			})
 		}
 	}
+	r.AddSection(duplicates)
 
-	sb.WriteString("## Missing Keys\n\n")
-	for file, envVars := range allEnvVars {
-		fileKeys := parser.GetEnvKeys(envVars)
-		var allKeysList []string
-		for key := range allKeys {
-			allKeysList = append(allKeysList, key)
-		}
-		missing := parser.FindMissingKeys(allKeysList, fileKeys)
+	r.AddSection(missingKeysSection(files, allEnvVars))
 
-		sb.WriteString(fmt.Sprintf("### Missing in `%s`\n\n", file))
-		if len(missing) == 0 {
-			sb.WriteString("✓ No missing keys.\n\n")
-		} else {
-			sb.WriteString("| Key |\n")
-			sb.WriteString("|-----|\n")
-			for _, key := range missing {
-				sb.WriteString(fmt.Sprintf("| `%s` |\n", key))
-			}
-			sb.WriteString("\n")
+	secretsParent := report.Section{Title: "Potential Secrets"}
+	for _, file := range files {
+		findings := secrets.Scan(allEnvVars[file])
+		secrets.SortFindings(findings)
+		secretsParent.AddSubsection(secretsSection(fmt.Sprintf("Secrets in `%s`", file), file, findings))
+	}
+	r.AddSection(secretsParent)
+
+	insecureParent := report.Section{Title: "Insecure Configuration"}
+	rules := insecure.Rules(cfg)
+	for _, file := range files {
+		environment := insecure.InferEnvironment(file, cfg)
+		issues, err := insecure.Check(allEnvVars[file], environment, rules)
+		if err != nil {
+			return nil, err
 		}
+		insecureParent.AddSubsection(insecureSection(fmt.Sprintf("`%s` (%s)", file, environmentLabel(environment)), file, environment, issues))
 	}
+	r.AddSection(insecureParent)
 
-	return sb.String()
+	return r, nil
 }
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MayR-Labs/envdoc-go/internal/config"
+	"github.com/MayR-Labs/envdoc-go/internal/report"
+	"github.com/MayR-Labs/envdoc-go/internal/workspace"
+)
+
+// discoverWorkspace finds the env file groups below the current directory and lists them
+func discoverWorkspace(cfg *config.Config) []workspace.Group {
+	groups, err := workspace.Discover(".", cfg.Workspace.Exclude)
+	if err != nil {
+		fmt.Printf("Error discovering workspace: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(groups) > 0 {
+		fmt.Printf("Found %d service(s) with .env files:\n", len(groups))
+		for _, group := range groups {
+			fmt.Printf("  - %s (%d file(s))\n", group.Name(), len(group.Files))
+		}
+		fmt.Println()
+	}
+	return groups
+}
+
+func generateWorkspaceDoctorReport(groups []workspace.Group, cfg *config.Config) (*report.Report, error) {
+	r := report.New("Environment Variables Workspace Doctor Report")
+
+	summary := report.Table{Columns: []report.Column{
+		{Title: "Service", Code: true}, {Title: "Files"}, {Title: "Keys"}, {Title: "Errors"}, {Title: "Warnings"}, {Title: "Info"},
+	}}
+	var services []report.Section
+	totalFiles, totalErrors, totalWarnings := 0, 0, 0
+
+	for _, group := range groups {
+		allEnvVars := parseEnvFiles(group.Files)
+		groupReport, err := generateDoctorReport(allEnvVars, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		errors := groupReport.CountSeverity(report.Error)
+		warnings := groupReport.CountSeverity(report.Warning)
+		summary.Rows = append(summary.Rows, []string{
+			group.Name(),
+			fmt.Sprint(len(allEnvVars)),
+			fmt.Sprint(len(unionKeys(allEnvVars))),
+			fmt.Sprint(errors),
+			fmt.Sprint(warnings),
+			fmt.Sprint(groupReport.CountSeverity(report.Info)),
+		})
+		totalFiles += len(allEnvVars)
+		totalErrors += errors
+		totalWarnings += warnings
+
+		// The group's own overview is replaced by its row in the summary table
+		service := report.Section{Title: fmt.Sprintf("`%s`", group.Name())}
+		for _, section := range groupReport.Sections {
+			if section.Title != "Overview" {
+				service.AddSubsection(section)
+			}
+		}
+		services = append(services, service)
+	}
+
+	overview := report.Section{Title: "Overview"}
+	overview.AddField("Services", len(groups))
+	overview.AddField("Files Analyzed", totalFiles)
+	overview.AddField("Errors", totalErrors)
+	overview.AddField("Warnings", totalWarnings)
+	r.AddSection(overview)
+
+	r.AddSection(report.Section{Title: "Summary", Tables: []report.Table{summary}})
+	for _, service := range services {
+		r.AddSection(service)
+	}
+
+	return r, nil
+}
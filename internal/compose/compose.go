@@ -0,0 +1,287 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFiles are the compose file names looked up when none are given, in the order compose merges them
+var DefaultFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml", "compose.override.yaml", "compose.override.yml", "docker-compose.override.yml", "docker-compose.override.yaml"}
+
+// Reference is a variable a compose file reads from the environment, either through
+// ${VAR} interpolation or by passing it through in an environment block
+type Reference struct {
+	Name    string
+	File    string
+	Line    int
+	Service string // empty outside services
+	// Default is true when the reference provides a fallback (${VAR:-x}, ${VAR-x}, ${VAR:+x})
+	Default bool
+	// Required is true for ${VAR:?err} and ${VAR?err}, which make compose fail when unset
+	Required bool
+	// PassThrough is true for environment entries without a value, which copy the variable into the container
+	PassThrough bool
+}
+
+// EnvFile is an env_file entry of a service
+type EnvFile struct {
+	Service string
+	// Path is resolved relative to the compose file's directory
+	Path     string
+	File     string
+	Line     int
+	Required bool
+}
+
+// Project is the merged content of one or more compose files
+type Project struct {
+	Files      []string
+	Services   []string
+	References []Reference
+	EnvFiles   []EnvFile
+}
+
+// Find returns the default compose files present in dir
+func Find(dir string) []string {
+	var files []string
+	for _, name := range DefaultFiles {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// Load parses the compose files
+func Load(files []string) (*Project, error) {
+	project := &Project{Files: files}
+	services := make(map[string]bool)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s: expected a mapping at the top level", file)
+		}
+
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i], root.Content[i+1]
+			if key.Value != "services" || value.Kind != yaml.MappingNode {
+				project.addReferences(file, "", value)
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				name, service := value.Content[j].Value, value.Content[j+1]
+				services[name] = true
+				project.loadService(file, name, service)
+			}
+		}
+	}
+
+	for name := range services {
+		project.Services = append(project.Services, name)
+	}
+	sort.Strings(project.Services)
+	return project, nil
+}
+
+// loadService records the references and env files of a single service
+func (p *Project) loadService(file, service string, node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		p.addReferences(file, service, node)
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "env_file":
+			p.loadEnvFiles(file, service, value)
+		case "environment":
+			p.loadEnvironment(file, service, value)
+		default:
+			p.addReferences(file, service, value)
+		}
+	}
+}
+
+// loadEnvFiles records env_file entries: a string, a list of strings or a list of {path, required}
+func (p *Project) loadEnvFiles(file, service string, node *yaml.Node) {
+	entries := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		entries = node.Content
+	}
+	for _, entry := range entries {
+		envFile := EnvFile{Service: service, File: file, Line: entry.Line, Required: true}
+		switch entry.Kind {
+		case yaml.ScalarNode:
+			envFile.Path = entry.Value
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(entry.Content); i += 2 {
+				switch entry.Content[i].Value {
+				case "path":
+					envFile.Path = entry.Content[i+1].Value
+				case "required":
+					envFile.Required = entry.Content[i+1].Value != "false"
+				}
+			}
+		}
+		if envFile.Path == "" {
+			continue
+		}
+		p.addReferences(file, service, entry)
+		if !filepath.IsAbs(envFile.Path) {
+			envFile.Path = filepath.Join(filepath.Dir(file), envFile.Path)
+		}
+		p.EnvFiles = append(p.EnvFiles, envFile)
+	}
+}
+
+// loadEnvironment records interpolations in an environment block and the variables it passes through
+func (p *Project) loadEnvironment(file, service string, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Tag == "!!null" {
+				p.References = append(p.References, Reference{Name: key.Value, File: file, Line: key.Line, Service: service, PassThrough: true})
+				continue
+			}
+			p.addReferences(file, service, value)
+		}
+	case yaml.SequenceNode:
+		for _, entry := range node.Content {
+			if !strings.Contains(entry.Value, "=") {
+				p.References = append(p.References, Reference{Name: entry.Value, File: file, Line: entry.Line, Service: service, PassThrough: true})
+				continue
+			}
+			p.addReferences(file, service, entry)
+		}
+	}
+}
+
+// addReferences records the interpolations in every scalar below the node
+func (p *Project) addReferences(file, service string, node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		for _, interpolation := range Interpolations(node.Value) {
+			p.References = append(p.References, Reference{
+				Name:     interpolation.Name,
+				File:     file,
+				Line:     node.Line,
+				Service:  service,
+				Default:  interpolation.Default,
+				Required: interpolation.Required,
+			})
+		}
+		return
+	}
+	for _, child := range node.Content {
+		p.addReferences(file, service, child)
+	}
+}
+
+// Interpolation is a single variable substitution in a string
+type Interpolation struct {
+	Name     string
+	Default  bool
+	Required bool
+}
+
+// Interpolations returns the variables substituted in a compose string, including those
+// nested in default values, which count as having a default. $$ is an escaped dollar sign.
+func Interpolations(s string) []Interpolation {
+	var result []Interpolation
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			continue
+		}
+		switch next := s[i+1]; {
+		case next == '$':
+			i++
+		case next == '{':
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				return result
+			}
+			result = append(result, parseBraced(s[i+2:end])...)
+			i = end
+		case isNameStart(next):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			result = append(result, Interpolation{Name: s[i+1 : j]})
+			i = j - 1
+		}
+	}
+	return result
+}
+
+// parseBraced parses the inside of ${...}
+func parseBraced(expr string) []Interpolation {
+	j := 0
+	for j < len(expr) && isNameChar(expr[j]) {
+		j++
+	}
+	if j == 0 {
+		return nil
+	}
+	interpolation := Interpolation{Name: expr[:j]}
+	rest := expr[j:]
+	operator := strings.TrimPrefix(rest, ":")
+	if operator != "" {
+		switch operator[0] {
+		case '-', '+':
+			interpolation.Default = true
+		case '?':
+			interpolation.Required = true
+		}
+		rest = operator[1:]
+	}
+	// Variables nested in a default or message are only read as a fallback
+	nested := Interpolations(rest)
+	for i := range nested {
+		nested[i].Default = true
+	}
+	return append([]Interpolation{interpolation}, nested...)
+}
+
+// matchingBrace returns the index of the brace closing the one at open, or -1
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the project config file names, in lookup order
+var FileNames = []string{".envdoc.yaml", ".envdoc.yml"}
+
+// Config represents the project configuration file
+type Config struct {
+	// Environments maps env file names (or glob patterns) to the environment they target
+	Environments map[string]string `yaml:"environments,omitempty"`
+	Insecure     InsecureConfig    `yaml:"insecure,omitempty"`
+	Lint         LintConfig        `yaml:"lint,omitempty"`
+	Compare      CompareConfig     `yaml:"compare,omitempty"`
+	Usage        UsageConfig       `yaml:"usage,omitempty"`
+	Workspace    WorkspaceConfig   `yaml:"workspace,omitempty"`
+	Journal      JournalConfig     `yaml:"journal,omitempty"`
+	Encryption   EncryptionConfig  `yaml:"encryption,omitempty"`
+}
+
+// EncryptionConfig sets the defaults of the encrypt command
+type EncryptionConfig struct {
+	// KDF is the key derivation function: argon2id (default), scrypt or pbkdf2
+	KDF string `yaml:"kdf,omitempty"`
+	// KDFProfile is the cost profile: interactive, moderate (default) or sensitive
+	KDFProfile string `yaml:"kdf_profile,omitempty"`
+	// RecipientsFile lists the public keys files are encrypted to, relative to the project
+	// root (default .envdoc/recipients.txt)
+	RecipientsFile string `yaml:"recipients_file,omitempty"`
+}
+
+// JournalConfig configures the operation journal used by undo
+type JournalConfig struct {
+	// Disabled turns off recording of file changes
+	Disabled bool `yaml:"disabled,omitempty"`
+	// MaxEntries is the number of operations kept (default 50)
+	MaxEntries int `yaml:"max_entries,omitempty"`
+	// MaxAgeDays is the number of days operations are kept (default 30)
+	MaxAgeDays int `yaml:"max_age_days,omitempty"`
+}
+
+// WorkspaceConfig configures env file discovery in workspace mode
+type WorkspaceConfig struct {
+	// Exclude lists directory names or paths (glob patterns, relative to the workspace root) to skip
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+// UsageConfig configures the source code scan of the usage command
+type UsageConfig struct {
+	// Exclude lists extra file or directory name patterns to skip
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Ignore lists keys (or glob patterns) that are provided by the platform and never declared
+	Ignore []string `yaml:"ignore,omitempty"`
+}
+
+// CompareConfig configures the cross-file consistency checks of compare
+type CompareConfig struct {
+	// MustDiffer lists keys (or glob patterns) that must not share a value between files
+	MustDiffer []string `yaml:"must_differ,omitempty"`
+	// MustMatch lists keys (or glob patterns) that must have the same value in every file
+	MustMatch []string `yaml:"must_match,omitempty"`
+}
+
+// LintConfig configures the lint rules
+type LintConfig struct {
+	// Rules maps rule IDs to a severity (error, warning, info) or "off"
+	Rules map[string]string `yaml:"rules,omitempty"`
+}
+
+// InsecureConfig configures the insecure configuration checks
+type InsecureConfig struct {
+	Disable []string       `yaml:"disable,omitempty"`
+	Rules   []InsecureRule `yaml:"rules,omitempty"`
+}
+
+// InsecureRule describes a value that is insecure in some environments
+type InsecureRule struct {
+	ID           string   `yaml:"id"`
+	Keys         []string `yaml:"keys"`
+	Environments []string `yaml:"environments,omitempty"`
+	Equals       []string `yaml:"equals,omitempty"`
+	Matches      string   `yaml:"matches,omitempty"`
+	Empty        bool     `yaml:"empty,omitempty"`
+	Severity     string   `yaml:"severity,omitempty"`
+	Message      string   `yaml:"message,omitempty"`
+}
+
+// Load reads the project config from the current directory
+func Load() (*Config, error) {
+	return LoadFrom(".")
+}
+
+// LoadFrom reads the project config from the given directory.
+// A missing config file is not an error; an empty config is returned instead.
+func LoadFrom(dir string) (*Config, error) {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		var cfg Config
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return &cfg, nil
+	}
+
+	return &Config{}, nil
+}
+
+// Environment returns the environment configured for a file, if any
+func (c *Config) Environment(filename string) (string, bool) {
+	base := filepath.Base(filename)
+	if env, ok := c.Environments[filename]; ok {
+		return env, true
+	}
+	if env, ok := c.Environments[base]; ok {
+		return env, true
+	}
+	patterns := make([]string, 0, len(c.Environments))
+	for pattern := range c.Environments {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, base); matched {
+			return c.Environments[pattern], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,140 @@
+package credential
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Environment variables read when no source flag is given
+const (
+	PasswordEnv    = "ENVDOC_PASSWORD"
+	NewPasswordEnv = "ENVDOC_NEW_PASSWORD"
+	IdentityEnv    = "ENVDOC_IDENTITY"
+)
+
+// Source describes where a secret is read from instead of prompting. At most one of File,
+// FD and Cmd may be set; they take precedence over the environment variable Env.
+type Source struct {
+	File string
+	// FD is a file descriptor opened by the caller, negative when unset
+	FD  int
+	Cmd string
+	Env string
+}
+
+// Secret is a secret read from a source
+type Secret struct {
+	Value string
+	// Origin describes the source for messages
+	Origin string
+	// Warnings explain why the source may expose the secret
+	Warnings []string
+}
+
+// Read returns the secret from the configured source, or nil when none is configured.
+// One trailing newline is removed, as files and commands usually end with one.
+func (s Source) Read() (*Secret, error) {
+	set := 0
+	for _, isSet := range []bool{s.File != "", s.FD >= 0, s.Cmd != ""} {
+		if isSet {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("only one of a file, a file descriptor and a command can provide the secret")
+	}
+
+	var secret *Secret
+	var err error
+	switch {
+	case s.File != "":
+		secret, err = readFile(s.File)
+	case s.FD >= 0:
+		secret, err = readFD(s.FD)
+	case s.Cmd != "":
+		secret, err = runCommand(s.Cmd)
+	case s.Env != "":
+		value, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return nil, nil
+		}
+		secret = &Secret{
+			Value:  value,
+			Origin: s.Env,
+			Warnings: []string{fmt.Sprintf("%s is visible to every process started from this environment and is easily logged; "+
+				"prefer a file descriptor or a file readable only by you", s.Env)},
+		}
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret.Value = trimNewline(secret.Value)
+	if secret.Value == "" {
+		return nil, fmt.Errorf("%s is empty", secret.Origin)
+	}
+	return secret, nil
+}
+
+func readFile(path string) (*Secret, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret file: %w", err)
+	}
+	secret := &Secret{Value: string(data), Origin: path}
+
+	// Permissions are not meaningful on Windows
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		secret.Warnings = append(secret.Warnings, fmt.Sprintf("%s is accessible by other users (mode %04o); restrict it with chmod 600", path, info.Mode().Perm()))
+	}
+	return secret, nil
+}
+
+func readFD(fd int) (*Secret, error) {
+	file := os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+	if file == nil {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	defer func() { _ = file.Close() }()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file descriptor %d: %w", fd, err)
+	}
+	return &Secret{Value: string(data), Origin: fmt.Sprintf("file descriptor %d", fd)}, nil
+}
+
+// runCommand runs a command through the shell and returns its output. Its stdin and stderr
+// are passed through, so password managers can still ask for confirmation.
+func runCommand(command string) (*Secret, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("secret command %q failed: %w", command, err)
+	}
+	return &Secret{Value: stdout.String(), Origin: fmt.Sprintf("command %q", command)}, nil
+}
+
+// trimNewline removes one trailing line ending
+func trimNewline(s string) string {
+	if strings.HasSuffix(s, "\n") {
+		s = strings.TrimSuffix(s, "\n")
+		s = strings.TrimSuffix(s, "\r")
+	}
+	return s
+}
@@ -0,0 +1,430 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Files encrypted to recipients use the age v1 format (https://age-encryption.org/v1), so
+// they can also be decrypted with the age tools. A random file key is wrapped for every
+// X25519 recipient in the text header:
+//
+//	age-encryption.org/v1
+//	-> X25519 <ephemeral share>
+//	<file key wrapped with ChaCha20-Poly1305>
+//	--- <HMAC-SHA256 of the header>
+//
+// followed by the payload: a 16 byte nonce and the content in ChaCha20-Poly1305 chunks
+// (see stream.go). envdoc writes the ASCII armored form unless binary output is asked for.
+
+const (
+	ageIntro       = "age-encryption.org/v1"
+	ageX25519Label = "age-encryption.org/v1/X25519"
+	ageFileKeySize = 16
+	ageNonceSize   = 16
+	ageColumns     = 64
+)
+
+// Key prefixes (bech32 human-readable parts)
+const (
+	recipientHRP = "age"
+	identityHRP  = "AGE-SECRET-KEY-"
+)
+
+// ErrNoIdentity is returned when none of the identities is a recipient of the file
+var ErrNoIdentity = errors.New("none of your identities is a recipient of this file")
+
+var ageB64 = base64.RawStdEncoding.Strict()
+
+// Recipient is an X25519 public key files are encrypted to
+type Recipient struct {
+	key *ecdh.PublicKey
+}
+
+// ParseRecipient parses an age public key (age1...)
+func ParseRecipient(s string) (*Recipient, error) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("malformed recipient %q: %w", s, err)
+	}
+	if hrp != recipientHRP {
+		return nil, fmt.Errorf("malformed recipient %q: not an age public key", s)
+	}
+	key, err := ecdh.X25519().NewPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("malformed recipient %q: %w", s, err)
+	}
+	return &Recipient{key: key}, nil
+}
+
+// String returns the public key in the age1... form
+func (r *Recipient) String() string {
+	s, _ := bech32Encode(recipientHRP, r.key.Bytes())
+	return s
+}
+
+// Identity is an X25519 private key that decrypts files encrypted to its recipient
+type Identity struct {
+	key *ecdh.PrivateKey
+}
+
+// GenerateIdentity returns a new random identity
+func GenerateIdentity() (*Identity, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return &Identity{key: key}, nil
+}
+
+// ParseIdentity parses an age secret key (AGE-SECRET-KEY-1...)
+func ParseIdentity(s string) (*Identity, error) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("malformed secret key: %w", err)
+	}
+	if hrp != strings.ToLower(identityHRP) {
+		return nil, fmt.Errorf("malformed secret key: not an age secret key")
+	}
+	key, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("malformed secret key: %w", err)
+	}
+	return &Identity{key: key}, nil
+}
+
+// ParseIdentities reads an identity file: one secret key per line, with # comments
+func ParseIdentities(r io.Reader) ([]*Identity, error) {
+	var identities []*Identity
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		identity, err := ParseIdentity(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		identities = append(identities, identity)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no secret keys found")
+	}
+	return identities, nil
+}
+
+// String returns the secret key in the AGE-SECRET-KEY-1... form
+func (i *Identity) String() string {
+	s, _ := bech32Encode(identityHRP, i.key.Bytes())
+	return strings.ToUpper(s)
+}
+
+// Recipient returns the public key of the identity
+func (i *Identity) Recipient() *Recipient {
+	return &Recipient{key: i.key.PublicKey()}
+}
+
+// IsAge reports whether encrypted data is in the age format, armored or binary
+func IsAge(data string) bool {
+	return isAge([]byte(strings.TrimLeft(data, " \t\r\n")))
+}
+
+func isAge(data []byte) bool {
+	return hasArmor(data, ageArmorLabel) || bytes.HasPrefix(data, []byte(ageIntro+"\n"))
+}
+
+// EncryptToRecipients encrypts data to the recipients in the armored age format
+func EncryptToRecipients(data []byte, recipients []*Recipient) (string, error) {
+	var out bytes.Buffer
+	w, err := NewRecipientsWriter(&out, recipients, true)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// NewRecipientsWriter returns a writer that encrypts what is written to it to the
+// recipients in the age format, armored or binary. The data is only complete once the
+// writer is closed.
+func NewRecipientsWriter(w io.Writer, recipients []*Recipient, armor bool) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients")
+	}
+	fileKey := make([]byte, ageFileKeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, fmt.Errorf("failed to generate file key: %w", err)
+	}
+
+	// Header
+	var header bytes.Buffer
+	header.WriteString(ageIntro + "\n")
+	for _, recipient := range recipients {
+		share, body, err := wrapX25519(fileKey, recipient)
+		if err != nil {
+			return nil, err
+		}
+		header.WriteString("-> X25519 " + ageB64.EncodeToString(share) + "\n")
+		writeStanzaBody(&header, ageB64.EncodeToString(body))
+	}
+	header.WriteString("---")
+	mac, err := ageHeaderMAC(fileKey, header.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	header.WriteString(" " + ageB64.EncodeToString(mac) + "\n")
+
+	// Payload
+	nonce := make([]byte, ageNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	header.Write(nonce)
+	aead, err := agePayloadAEAD(fileKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	out, closer, err := armored(w, ageArmorLabel, armor)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := out.Write(header.Bytes()); err != nil {
+		return nil, err
+	}
+	return newStreamWriter(aead, nil, out, closer), nil
+}
+
+// DecryptWithIdentities decrypts age data, armored or binary, with the first identity that
+// is one of its recipients
+func DecryptWithIdentities(data string, identities []*Identity) ([]byte, error) {
+	r, err := newAgeReader(bufio.NewReader(strings.NewReader(strings.TrimLeft(data, " \t\r\n"))), identities)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// newAgeReader reads the header of age data, armored or binary, and returns a reader of
+// the decrypted content
+func newAgeReader(r *bufio.Reader, identities []*Identity) (io.Reader, error) {
+	if start, _ := r.Peek(len(armorBegin(ageArmorLabel))); hasArmor(start, ageArmorLabel) {
+		dearmored, err := newArmorReader(r, ageArmorLabel)
+		if err != nil {
+			return nil, err
+		}
+		r = bufio.NewReader(dearmored)
+	}
+	stanzas, macInput, mac, err := readAgeHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileKey []byte
+	for _, stanza := range stanzas {
+		if len(stanza.args) == 0 || stanza.args[0] != "X25519" {
+			continue
+		}
+		for _, identity := range identities {
+			if fileKey, err = unwrapX25519(stanza, identity); err == nil {
+				break
+			}
+		}
+		if fileKey != nil {
+			break
+		}
+	}
+	if fileKey == nil {
+		return nil, ErrNoIdentity
+	}
+
+	expected, err := ageHeaderMAC(fileKey, macInput)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac, expected) {
+		return nil, fmt.Errorf("header authentication failed: the file was modified")
+	}
+	nonce := make([]byte, ageNonceSize)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+	aead, err := agePayloadAEAD(fileKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return newStreamReader(aead, nil, r, errModified), nil
+}
+
+// ageStanza is a recipient entry of the header
+type ageStanza struct {
+	args []string
+	body []byte
+}
+
+// wrapX25519 wraps the file key for a recipient and returns the ephemeral share and body
+func wrapX25519(fileKey []byte, recipient *Recipient) ([]byte, []byte, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	shared, err := ephemeral.ECDH(recipient.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	share := ephemeral.PublicKey().Bytes()
+	wrapKey, err := hkdf.Key(sha256.New, shared, append(share, recipient.key.Bytes()...), ageX25519Label, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return share, aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil), nil
+}
+
+// unwrapX25519 returns the file key if the stanza was wrapped for the identity
+func unwrapX25519(stanza ageStanza, identity *Identity) ([]byte, error) {
+	if len(stanza.args) != 2 {
+		return nil, fmt.Errorf("invalid X25519 stanza")
+	}
+	share, err := ageB64.DecodeString(stanza.args[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 stanza: %w", err)
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(share)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 stanza: %w", err)
+	}
+	if len(stanza.body) != ageFileKeySize+chacha20poly1305.Overhead {
+		return nil, fmt.Errorf("invalid X25519 stanza body")
+	}
+	shared, err := identity.key.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	public := identity.key.PublicKey().Bytes()
+	wrapKey, err := hkdf.Key(sha256.New, shared, append(share, public...), ageX25519Label, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), stanza.body, nil)
+}
+
+// ageHeaderMAC authenticates the header up to and including the "---" of the MAC line
+func ageHeaderMAC(fileKey, header []byte) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, fileKey, nil, "header", sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(header)
+	return mac.Sum(nil), nil
+}
+
+// readAgeHeader reads the header of binary age data up to the MAC line and returns the
+// stanzas, the MAC input and the MAC
+func readAgeHeader(r *bufio.Reader) ([]ageStanza, []byte, []byte, error) {
+	var header bytes.Buffer
+	nextLine := func() (string, error) {
+		line, err := r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			return "", fmt.Errorf("malformed age header: line too long")
+		}
+		if err != nil {
+			return "", fmt.Errorf("age header is truncated")
+		}
+		header.Write(line)
+		return string(line[:len(line)-1]), nil
+	}
+
+	intro, err := nextLine()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if intro != ageIntro {
+		return nil, nil, nil, fmt.Errorf("unsupported age version %q", intro)
+	}
+
+	var stanzas []ageStanza
+	for {
+		start := header.Len()
+		line, err := nextLine()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if strings.HasPrefix(line, "--- ") {
+			mac, err := ageB64.DecodeString(line[4:])
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("malformed header MAC: %w", err)
+			}
+			return stanzas, header.Bytes()[:start+3], mac, nil
+		}
+		if !strings.HasPrefix(line, "-> ") {
+			return nil, nil, nil, fmt.Errorf("malformed age header line %q", line)
+		}
+
+		stanza := ageStanza{args: strings.Split(line[3:], " ")}
+		for {
+			bodyLine, err := nextLine()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			decoded, err := ageB64.DecodeString(bodyLine)
+			if err != nil || len(bodyLine) > ageColumns {
+				return nil, nil, nil, fmt.Errorf("malformed age stanza body")
+			}
+			stanza.body = append(stanza.body, decoded...)
+			if len(bodyLine) < ageColumns {
+				break
+			}
+		}
+		stanzas = append(stanzas, stanza)
+	}
+}
+
+// writeStanzaBody writes a stanza body in lines of ageColumns characters. The body ends
+// with a short line, which is empty when the body fills the last line.
+func writeStanzaBody(buf *bytes.Buffer, s string) {
+	for len(s) >= ageColumns {
+		buf.WriteString(s[:ageColumns] + "\n")
+		s = s[ageColumns:]
+	}
+	buf.WriteString(s + "\n")
+}
+
+// agePayloadAEAD returns the payload cipher, keyed from the file key and the payload nonce
+func agePayloadAEAD(fileKey, nonce []byte) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, fileKey, nonce, "payload", chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
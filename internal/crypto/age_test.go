@@ -0,0 +1,160 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// The age fixtures were encrypted by the reference implementation (filippo.io/age v1.2.1)
+// to the identity in testdata/age_identity.txt, whose recipient it derives as
+// referenceRecipient. The content is testPlaintext of the given size. Files written by
+// NewRecipientsWriter were checked to decrypt with the reference implementation when the
+// fixtures were made.
+const referenceRecipient = "age13ysfewfh2c3rftxnzawnm788f84lw2p8ngzzczk3adqnru537q5s7gqurl"
+
+func referenceIdentity(t *testing.T) *Identity {
+	t.Helper()
+	identities, err := ParseIdentities(strings.NewReader(readFixture(t, "age_identity.txt")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identities) != 1 {
+		t.Fatalf("%d identities", len(identities))
+	}
+	return identities[0]
+}
+
+func TestAgeRecipientOfReferenceIdentity(t *testing.T) {
+	if got := referenceIdentity(t).Recipient().String(); got != referenceRecipient {
+		t.Errorf("recipient %s, want %s", got, referenceRecipient)
+	}
+	recipient, err := ParseRecipient(referenceRecipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipient.String() != referenceRecipient {
+		t.Errorf("ParseRecipient round trip gave %s", recipient)
+	}
+	if _, err := ParseRecipient(referenceRecipient[:len(referenceRecipient)-1] + "q"); err == nil {
+		t.Error("recipient with a bad checksum was accepted")
+	}
+}
+
+func TestAgeDecryptReferenceFiles(t *testing.T) {
+	identity := referenceIdentity(t)
+	tests := []struct {
+		file  string
+		size  int
+		armor bool
+	}{
+		{"age_empty.age", 0, false},
+		{"age_chunk.age", streamChunkSize, false},
+		{"age_multi.age", streamChunkSize + 100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data := readFixture(t, tt.file)
+			if !IsAge(data) {
+				t.Fatal("not recognized as age")
+			}
+			if IsArmored(data) != tt.armor {
+				t.Errorf("IsArmored = %v, want %v", !tt.armor, tt.armor)
+			}
+
+			r, err := NewDecryptReader(strings.NewReader(data), Keys{
+				Identities: func() ([]*Identity, error) { return []*Identity{identity}, nil },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			plaintext, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(plaintext, testPlaintext(tt.size)) {
+				t.Errorf("decrypted %d bytes that differ from the %d byte content", len(plaintext), tt.size)
+			}
+		})
+	}
+}
+
+func TestAgeRejectsOtherIdentity(t *testing.T) {
+	other, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptWithIdentities(readFixture(t, "age_chunk.age"), []*Identity{other}); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("got %v, want ErrNoIdentity", err)
+	}
+}
+
+func TestAgeRejectsModifiedReferenceFile(t *testing.T) {
+	identity := referenceIdentity(t)
+	data := []byte(readFixture(t, "age_chunk.age"))
+	for _, offset := range []int{30, len(data) / 2, len(data) - 1} {
+		modified := bytes.Clone(data)
+		modified[offset] ^= 1
+		if _, err := DecryptWithIdentities(string(modified), []*Identity{identity}); err == nil {
+			t.Errorf("flipped byte %d was not detected", offset)
+		}
+	}
+	if _, err := DecryptWithIdentities(string(data[:len(data)-streamChunkSize/2]), []*Identity{identity}); err == nil {
+		t.Error("truncated file was accepted")
+	}
+}
+
+func TestAgeRoundTrip(t *testing.T) {
+	alice, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := referenceIdentity(t)
+	recipients := []*Recipient{alice.Recipient(), bob.Recipient()}
+
+	for _, armor := range []bool{true, false} {
+		for _, size := range testSizes {
+			plaintext := testPlaintext(size)
+			var out bytes.Buffer
+			w, err := NewRecipientsWriter(&out, recipients, armor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write(plaintext); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if IsArmored(out.String()) != armor {
+				t.Errorf("size %d: IsArmored = %v, want %v", size, !armor, armor)
+			}
+
+			for _, identity := range []*Identity{alice, bob} {
+				decrypted, err := DecryptWithIdentities(out.String(), []*Identity{identity})
+				if err != nil {
+					t.Fatalf("size %d, armor %v: %v", size, armor, err)
+				}
+				if !bytes.Equal(decrypted, plaintext) {
+					t.Errorf("size %d, armor %v: decrypted content differs", size, armor)
+				}
+			}
+		}
+	}
+}
+
+func TestAgeIdentityRoundTrip(t *testing.T) {
+	identity, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseIdentity(identity.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Recipient().String() != identity.Recipient().String() {
+		t.Error("parsed identity has another recipient")
+	}
+}
@@ -0,0 +1,176 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Armored data is binary data as base64 text between marker lines, in lines of 64
+// characters:
+//
+//	-----BEGIN ENVDOC ENCRYPTED FILE-----
+//	RU5WRE9DAwEC...
+//	-----END ENVDOC ENCRYPTED FILE-----
+//
+// It is written and read as a stream, like the data it wraps.
+
+// Armor labels
+const (
+	envdocArmorLabel = "ENVDOC ENCRYPTED FILE"
+	ageArmorLabel    = "AGE ENCRYPTED FILE"
+)
+
+const armorColumns = 64
+
+func armorBegin(label string) string {
+	return "-----BEGIN " + label + "-----"
+}
+
+func armorEnd(label string) string {
+	return "-----END " + label + "-----"
+}
+
+// hasArmor reports whether data starts with the begin marker of the label
+func hasArmor(data []byte, label string) bool {
+	return bytes.HasPrefix(data, []byte(armorBegin(label)))
+}
+
+// armored returns the writer for data that is armored when armor is set, and the closer
+// that finishes the armor (nil otherwise)
+func armored(w io.Writer, label string, armor bool) (io.Writer, io.Closer, error) {
+	if !armor {
+		return w, nil, nil
+	}
+	aw, err := newArmorWriter(w, label)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aw, aw, nil
+}
+
+// armorWriter base64-encodes what is written to it in lines
+type armorWriter struct {
+	w      io.Writer
+	label  string
+	lines  *lineWriter
+	base64 io.WriteCloser
+}
+
+// newArmorWriter writes the begin marker and returns the writer of the armored data
+func newArmorWriter(w io.Writer, label string) (io.WriteCloser, error) {
+	if _, err := io.WriteString(w, armorBegin(label)+"\n"); err != nil {
+		return nil, err
+	}
+	lines := &lineWriter{w: w}
+	return &armorWriter{w: w, label: label, lines: lines, base64: base64.NewEncoder(base64.StdEncoding, lines)}, nil
+}
+
+func (a *armorWriter) Write(p []byte) (int, error) {
+	return a.base64.Write(p)
+}
+
+// Close writes the padding and the end marker
+func (a *armorWriter) Close() error {
+	if err := a.base64.Close(); err != nil {
+		return err
+	}
+	if a.lines.column > 0 {
+		if _, err := io.WriteString(a.w, "\n"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(a.w, armorEnd(a.label)+"\n")
+	return err
+}
+
+// lineWriter breaks what is written to it into lines of armorColumns characters
+type lineWriter struct {
+	w      io.Writer
+	column int
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		part := p
+		if len(part) > armorColumns-l.column {
+			part = part[:armorColumns-l.column]
+		}
+		if _, err := l.w.Write(part); err != nil {
+			return n, err
+		}
+		n += len(part)
+		l.column += len(part)
+		p = p[len(part):]
+		if l.column == armorColumns {
+			if _, err := io.WriteString(l.w, "\n"); err != nil {
+				return n, err
+			}
+			l.column = 0
+		}
+	}
+	return n, nil
+}
+
+// newArmorReader reads the begin marker and returns a reader of the decoded data
+func newArmorReader(r *bufio.Reader, label string) (io.Reader, error) {
+	line, err := readArmorLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line != armorBegin(label) {
+		return nil, fmt.Errorf("missing %q", armorBegin(label))
+	}
+	return base64.NewDecoder(base64.StdEncoding, &armorBody{r: r, end: armorEnd(label)}), nil
+}
+
+// armorBody returns the base64 text of the lines up to the end marker
+type armorBody struct {
+	r       *bufio.Reader
+	end     string
+	pending []byte
+	done    bool
+}
+
+func (a *armorBody) Read(p []byte) (int, error) {
+	for len(a.pending) == 0 {
+		if a.done {
+			return 0, io.EOF
+		}
+		line, err := readArmorLine(a.r)
+		if err == io.EOF {
+			return 0, fmt.Errorf("armored data is truncated: missing %q", a.end)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if line == a.end {
+			a.done = true
+			continue
+		}
+		a.pending = []byte(line)
+	}
+	n := copy(p, a.pending)
+	a.pending = a.pending[n:]
+	return n, nil
+}
+
+// readArmorLine reads one line without its line ending. Lines are limited to the reader's
+// buffer, so malformed input cannot grow memory.
+func readArmorLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", fmt.Errorf("malformed armored data: line too long")
+	}
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(line)), nil
+}
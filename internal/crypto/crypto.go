@@ -1,70 +1,427 @@
 package crypto
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hkdf"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"golang.org/x/crypto/pbkdf2"
 )
 
 const (
-	saltSize   = 32
-	iterations = 10000
-	keySize    = 32
+	saltSize = 32
+	keySize  = 32
 )
 
-// Encrypt encrypts data using AES-256-CBC with PBKDF2
+// Format versions. Version 1 is the legacy AES-256-CBC format without a header and
+// version 2 sealed the whole content at once; both are still decrypted but no longer
+// written.
+const (
+	LegacyVersion  = 1
+	blockVersion   = 2
+	CurrentVersion = 3
+)
+
+// streamKeyLabel separates the payload key of version 3 from the KDF key
+const streamKeyLabel = "envdoc/v3/payload"
+
+// sniffSize is enough of the start of encrypted data to tell the formats apart
+const sniffSize = 64
+
+// ErrAuthentication is returned when data fails authentication: the password is wrong or
+// the data was modified
+var ErrAuthentication = errors.New("decryption failed: wrong password or the data was modified")
+
+// Encrypt encrypts data with AES-256-GCM under a key derived from the password with the
+// default KDF, in the versioned format described in format.go
 func Encrypt(data []byte, password string) (string, error) {
-	// Generate salt
+	return EncryptWith(data, password, DefaultKDFParams())
+}
+
+// EncryptWith encrypts data like Encrypt, deriving the key with the given KDF parameters.
+// The result is armored text.
+func EncryptWith(data []byte, password string, params KDFParams) (string, error) {
+	var out bytes.Buffer
+	w, err := NewEncryptWriter(&out, password, params, true)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// NewEncryptWriter returns a writer that encrypts what is written to it with a key derived
+// from the password, armored or binary. The data is only complete once the writer is
+// closed.
+func NewEncryptWriter(w io.Writer, password string, params KDFParams, armor bool) (io.WriteCloser, error) {
 	salt := make([]byte, saltSize)
 	if _, err := rand.Read(salt); err != nil {
-		return "", fmt.Errorf("failed to generate salt: %w", err)
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	nonce := make([]byte, streamNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	// Derive key using PBKDF2
-	key := pbkdf2.Key([]byte(password), salt, iterations, keySize, sha256.New)
+	h := header{version: CurrentVersion, cipher: cipherAES256GCM, kdf: params.get(), salt: salt, nonce: nonce}
+	aead, err := h.streamAEAD(password)
+	if err != nil {
+		return nil, err
+	}
+	out, closer, err := armored(w, envdocArmorLabel, armor)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create cipher
-	block, err := aes.NewCipher(key)
+	// The header is authenticated with every chunk, so its parameters cannot be changed either
+	encoded := h.encode()
+	if _, err := out.Write(encoded); err != nil {
+		return nil, err
+	}
+	return newStreamWriter(aead, encoded, out, closer), nil
+}
+
+// Keys provides the secrets to decrypt with. They are only asked for when the format of
+// the data needs them; a nil function means the secret is not available.
+type Keys struct {
+	Password   func() (string, error)
+	Identities func() ([]*Identity, error)
+}
+
+func (k Keys) password() (string, error) {
+	if k.Password == nil {
+		return "", fmt.Errorf("the data is encrypted to recipients, not with a password")
+	}
+	return k.Password()
+}
+
+func (k Keys) identities() ([]*Identity, error) {
+	if k.Identities == nil {
+		return nil, fmt.Errorf("the data is encrypted to recipients; decrypt it with an identity")
+	}
+	return k.Identities()
+}
+
+// DecryptReader reads the decrypted content of encrypted data. The content is only
+// authenticated once the reader returns io.EOF; on any other error what was read must be
+// discarded.
+type DecryptReader struct {
+	io.Reader
+	// Version is the format version, 0 for data encrypted to recipients
+	Version int
+}
+
+// NewDecryptReader reads the header of data in any format written by envdoc, armored or
+// binary, and returns a reader of the decrypted content. Chunked formats are decrypted as
+// they are read; the older formats are small and decrypted at once.
+func NewDecryptReader(r io.Reader, keys Keys) (*DecryptReader, error) {
+	br := bufio.NewReader(r)
+	// Text formats may start with blank lines
+	for {
+		b, err := br.Peek(1)
+		if err != nil || !isSpace(b[0]) {
+			break
+		}
+		_, _ = br.ReadByte()
+	}
+
+	start, _ := br.Peek(sniffSize)
+	switch {
+	case isAge(start):
+		identities, err := keys.identities()
+		if err != nil {
+			return nil, err
+		}
+		content, err := newAgeReader(br, identities)
+		if err != nil {
+			return nil, err
+		}
+		return &DecryptReader{Reader: content}, nil
+	case hasArmor(start, envdocArmorLabel):
+		dearmored, err := newArmorReader(br, envdocArmorLabel)
+		if err != nil {
+			return nil, err
+		}
+		return newStreamDecryptReader(dearmored, keys)
+	case hasMagic(start):
+		return newStreamDecryptReader(br, keys)
+	}
+
+	data, err := io.ReadAll(br)
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
+		return nil, err
 	}
+	password, err := keys.password()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, version, err := decryptBlock(string(data), password)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptReader{Reader: bytes.NewReader(plaintext), Version: version}, nil
+}
 
-	// Pad data
-	paddedData := pkcs7Pad(data, aes.BlockSize)
+// newStreamDecryptReader reads the header of version 3 data
+func newStreamDecryptReader(r io.Reader, keys Keys) (*DecryptReader, error) {
+	h, encoded, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if h.version != CurrentVersion {
+		return nil, fmt.Errorf("encrypted data has a corrupted header")
+	}
+	password, err := keys.password()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := h.streamAEAD(password)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptReader{Reader: newStreamReader(aead, encoded, r, ErrAuthentication), Version: CurrentVersion}, nil
+}
 
-	// Create IV
-	iv := make([]byte, aes.BlockSize)
-	if _, err := rand.Read(iv); err != nil {
-		return "", fmt.Errorf("failed to generate IV: %w", err)
+// streamAEAD returns the payload cipher of version 3 data
+func (h header) streamAEAD(password string) (cipher.AEAD, error) {
+	key, err := h.kdf.derive([]byte(password), h.salt)
+	if err != nil {
+		return nil, err
+	}
+	streamKey, err := hkdf.Key(sha256.New, key, h.nonce, streamKeyLabel, keySize)
+	if err != nil {
+		return nil, err
 	}
+	return newAEAD(h.cipher, streamKey)
+}
 
-	// Encrypt
-	ciphertext := make([]byte, len(paddedData))
-	mode := cipher.NewCBCEncrypter(block, iv)
-	mode.CryptBlocks(ciphertext, paddedData)
+// keyStreamLabel separates the payload key of NewKeyWriter from the caller's key
+const keyStreamLabel = "envdoc/key-stream"
 
-	// Combine salt + iv + ciphertext
-	result := append(salt, iv...)
-	result = append(result, ciphertext...)
+// NewKeyWriter returns a writer that encrypts what is written to it with a 32 byte key, in
+// chunks like version 3, each bound to ad. A random nonce is written first and the payload
+// key is derived from the key and the nonce, so one key can encrypt any number of streams.
+// The data is only complete once the writer is closed.
+func NewKeyWriter(w io.Writer, key, ad []byte) (io.WriteCloser, error) {
+	nonce := make([]byte, streamNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	aead, err := keyStreamAEAD(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(nonce); err != nil {
+		return nil, err
+	}
+	return newStreamWriter(aead, ad, w, nil), nil
+}
 
-	// Encode to base64
-	return base64.StdEncoding.EncodeToString(result), nil
+// NewKeyReader returns a reader of the content of data written by NewKeyWriter with the
+// same key and additional data. The content is only authenticated once it returns io.EOF.
+func NewKeyReader(r io.Reader, key, ad []byte) (io.Reader, error) {
+	nonce := make([]byte, streamNonceSize)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+	aead, err := keyStreamAEAD(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return newStreamReader(aead, ad, r, errModified), nil
 }
 
-// Decrypt decrypts data using AES-256-CBC with PBKDF2
+func keyStreamAEAD(key, nonce []byte) (cipher.AEAD, error) {
+	streamKey, err := hkdf.Key(sha256.New, key, nonce, keyStreamLabel, keySize)
+	if err != nil {
+		return nil, err
+	}
+	return newAEAD(cipherAES256GCM, streamKey)
+}
+
+// headSize is enough of the chunked formats to identify them and read their header
+const headSize = 4096
+
+// ReadHead reads enough of the start of encrypted data for Version, ParamsOf, Describe,
+// IsAge and IsArmored. The chunked formats are identified by their header; the older
+// formats have none and are read whole, as they are small.
+func ReadHead(r io.Reader) (string, error) {
+	head := make([]byte, headSize)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	head = head[:n]
+	start := bytes.TrimLeft(head, " \t\r\n")
+	if isAge(start) || hasArmor(start, envdocArmorLabel) || hasMagic(start) {
+		return string(head), nil
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(head) + string(rest), nil
+}
+
+// Decrypt decrypts data written by Encrypt, in the current or an older format
 func Decrypt(encryptedData, password string) ([]byte, error) {
-	// Decode from base64
-	data, err := base64.StdEncoding.DecodeString(encryptedData)
+	r, err := NewDecryptReader(strings.NewReader(encryptedData), Keys{
+		Password: func() (string, error) { return password, nil },
+	})
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// decryptBlock decrypts the base64 formats of versions 1 and 2 and returns the version
+func decryptBlock(encryptedData, password string) ([]byte, int, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encryptedData))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode data: %w", err)
+	}
+	if !hasMagic(data) {
+		plaintext, err := decryptLegacy(data, password)
+		return plaintext, LegacyVersion, err
+	}
+
+	h, offset, err := decodeHeader(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode data: %w", err)
+		return nil, 0, err
+	}
+	if h.version != blockVersion {
+		return nil, 0, fmt.Errorf("encrypted data has a corrupted header")
 	}
+	key, err := h.kdf.derive([]byte(password), h.salt)
+	if err != nil {
+		return nil, 0, err
+	}
+	aead, err := newAEAD(h.cipher, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	plaintext, err := aead.Open(nil, h.nonce, data[offset:], data[:offset])
+	if err != nil {
+		return nil, 0, ErrAuthentication
+	}
+	return plaintext, blockVersion, nil
+}
 
+// inspect returns the header of encrypted data without decrypting it. Legacy data has only
+// its version set.
+func inspect(encryptedData string) (header, error) {
+	r := bufio.NewReader(strings.NewReader(strings.TrimLeft(encryptedData, " \t\r\n")))
+	start, _ := r.Peek(sniffSize)
+	switch {
+	case isAge(start):
+		return header{}, fmt.Errorf("data is encrypted to recipients")
+	case hasArmor(start, envdocArmorLabel):
+		dearmored, err := newArmorReader(r, envdocArmorLabel)
+		if err != nil {
+			return header{}, err
+		}
+		h, _, err := readHeader(dearmored)
+		return h, err
+	case hasMagic(start):
+		h, _, err := readHeader(r)
+		return h, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encryptedData))
+	if err != nil {
+		return header{}, fmt.Errorf("failed to decode data: %w", err)
+	}
+	if !hasMagic(data) {
+		if len(data) < saltSize+2*aes.BlockSize || (len(data)-saltSize)%aes.BlockSize != 0 {
+			return header{}, fmt.Errorf("not encrypted by envdoc")
+		}
+		return header{version: LegacyVersion}, nil
+	}
+	h, _, err := decodeHeader(data)
+	return h, err
+}
+
+// Version returns the format version of encrypted data
+func Version(encryptedData string) (int, error) {
+	h, err := inspect(encryptedData)
+	if err != nil {
+		return 0, err
+	}
+	return int(h.version), nil
+}
+
+// ParamsOf returns the KDF parameters of encrypted data, so it can be encrypted again
+// at the same cost. Legacy data has no header and returns an error.
+func ParamsOf(encryptedData string) (KDFParams, error) {
+	h, err := inspect(encryptedData)
+	if err != nil {
+		return KDFParams{}, err
+	}
+	if h.version == LegacyVersion {
+		return KDFParams{}, fmt.Errorf("data is in the legacy format")
+	}
+	return KDFParams{kdf: h.kdf}, nil
+}
+
+// Describe returns a short description of the format of encrypted data
+func Describe(encryptedData string) (string, error) {
+	h, err := inspect(encryptedData)
+	if err != nil {
+		return "", err
+	}
+	switch h.version {
+	case LegacyVersion:
+		return "v1 (legacy AES-256-CBC, PBKDF2-SHA256, 10000 iterations)", nil
+	case blockVersion:
+		return fmt.Sprintf("v%d (%s, %s)", h.version, cipherName(h.cipher), h.kdf.describe()), nil
+	}
+	return fmt.Sprintf("v%d (%s in %d KiB chunks, %s)", h.version, cipherName(h.cipher), streamChunkSize/1024, h.kdf.describe()), nil
+}
+
+// IsArmored reports whether encrypted data is text, as opposed to binary. Only the current
+// format and age can be binary.
+func IsArmored(encryptedData string) bool {
+	data := []byte(strings.TrimLeft(encryptedData, " \t\r\n"))
+	return !hasMagic(data) && !bytes.HasPrefix(data, []byte(ageIntro+"\n"))
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// newAEAD returns the authenticated cipher with the given ID
+func newAEAD(id byte, key []byte) (cipher.AEAD, error) {
+	switch id {
+	case cipherAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+		return cipher.NewGCM(block)
+	}
+	return nil, fmt.Errorf("unsupported cipher %d", id)
+}
+
+// legacyIterations is the PBKDF2 iteration count of the legacy format
+const legacyIterations = 10000
+
+// decryptLegacy decrypts the legacy format: salt, IV and AES-256-CBC ciphertext with PKCS7
+// padding and no authentication
+func decryptLegacy(data []byte, password string) ([]byte, error) {
 	if len(data) < saltSize+aes.BlockSize {
 		return nil, fmt.Errorf("encrypted data is too short")
 	}
@@ -73,9 +430,12 @@ func Decrypt(encryptedData, password string) ([]byte, error) {
 	salt := data[:saltSize]
 	iv := data[saltSize : saltSize+aes.BlockSize]
 	ciphertext := data[saltSize+aes.BlockSize:]
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted data is corrupted")
+	}
 
 	// Derive key using PBKDF2
-	key := pbkdf2.Key([]byte(password), salt, iterations, keySize, sha256.New)
+	key := pbkdf2.Key([]byte(password), salt, legacyIterations, keySize, sha256.New)
 
 	// Create cipher
 	block, err := aes.NewCipher(key)
@@ -103,16 +463,6 @@ func HashSHA256(data []byte) string {
 	return fmt.Sprintf("%x", hash)
 }
 
-// pkcs7Pad pads data to the specified block size
-func pkcs7Pad(data []byte, blockSize int) []byte {
-	padding := blockSize - (len(data) % blockSize)
-	padText := make([]byte, padding)
-	for i := range padText {
-		padText[i] = byte(padding)
-	}
-	return append(data, padText...)
-}
-
 // pkcs7Unpad removes padding from data
 func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
 	if len(data) == 0 {
@@ -120,7 +470,7 @@ func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
 	}
 
 	padding := int(data[len(data)-1])
-	if padding > blockSize || padding > len(data) {
+	if padding == 0 || padding > blockSize || padding > len(data) {
 		return nil, fmt.Errorf("invalid padding")
 	}
 
@@ -0,0 +1,230 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fastParams keeps the key derivation cheap; the KDFs themselves are covered by the
+// legacy fixtures
+var fastParams = KDFParams{kdf: pbkdf2KDF{iterations: 1000}}
+
+// testSizes covers the empty stream and the chunk boundaries
+var testSizes = []int{0, 1, streamChunkSize - 1, streamChunkSize, streamChunkSize + 1, 3*streamChunkSize + 7}
+
+// testPlaintext returns n bytes of a repeating pattern; the age fixtures use the same one
+func testPlaintext(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func passwordKeys(password string) Keys {
+	return Keys{Password: func() (string, error) { return password, nil }}
+}
+
+func encryptBytes(t *testing.T, plaintext []byte, password string, armor bool) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := NewEncryptWriter(&out, password, fastParams, armor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func decryptBytes(data []byte, keys Keys) ([]byte, int, error) {
+	r, err := NewDecryptReader(bytes.NewReader(data), keys)
+	if err != nil {
+		return nil, 0, err
+	}
+	plaintext, err := io.ReadAll(r)
+	return plaintext, r.Version, err
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	for _, armor := range []bool{true, false} {
+		for _, size := range testSizes {
+			plaintext := testPlaintext(size)
+			data := encryptBytes(t, plaintext, "secret", armor)
+			if got := IsArmored(string(data)); got != armor {
+				t.Errorf("size %d: IsArmored = %v, want %v", size, got, armor)
+			}
+
+			decrypted, version, err := decryptBytes(data, passwordKeys("secret"))
+			if err != nil {
+				t.Fatalf("size %d, armor %v: %v", size, armor, err)
+			}
+			if version != CurrentVersion {
+				t.Errorf("size %d: version %d, want %d", size, version, CurrentVersion)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("size %d, armor %v: decrypted content differs", size, armor)
+			}
+		}
+	}
+}
+
+func TestDecryptWrongPassword(t *testing.T) {
+	data := encryptBytes(t, []byte("A=1\n"), "secret", true)
+	if _, _, err := decryptBytes(data, passwordKeys("other")); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("got %v, want ErrAuthentication", err)
+	}
+}
+
+func TestDecryptModifiedHeader(t *testing.T) {
+	data := encryptBytes(t, []byte("A=1\n"), "secret", false)
+	// The last byte of the header is part of the nonce, which is authenticated with every chunk
+	h, offset, err := decodeHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.version != CurrentVersion {
+		t.Fatalf("version %d", h.version)
+	}
+	data[offset-1] ^= 1
+	if _, _, err := decryptBytes(data, passwordKeys("secret")); err == nil {
+		t.Error("modified header decrypted")
+	}
+}
+
+func TestParamsOf(t *testing.T) {
+	data := encryptBytes(t, []byte("A=1\n"), "secret", true)
+	params, err := ParamsOf(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !params.Equal(fastParams) {
+		t.Errorf("ParamsOf = %s, want %s", params, fastParams)
+	}
+}
+
+func TestReadHead(t *testing.T) {
+	large := encryptBytes(t, testPlaintext(3*streamChunkSize), "secret", false)
+	head, err := ReadHead(bytes.NewReader(large))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(head) != headSize {
+		t.Errorf("read %d bytes of the current format, want %d", len(head), headSize)
+	}
+	if description, err := Describe(head); err != nil || !strings.HasPrefix(description, "v3 ") {
+		t.Errorf("Describe(head) = %q, %v", description, err)
+	}
+
+	legacy := readFixture(t, "v2_pbkdf2.txt")
+	head, err = ReadHead(strings.NewReader(legacy))
+	if err != nil || head != legacy {
+		t.Errorf("legacy data is not read whole: %q, %v", head, err)
+	}
+}
+
+// The fixtures were written by earlier releases: v1 by the AES-256-CBC format without a
+// header, v2 by the single-block AES-256-GCM format, each with the interactive profile of
+// its KDF. All use the password "correct horse".
+func TestDecryptLegacyFixtures(t *testing.T) {
+	tests := []struct {
+		file        string
+		version     int
+		description string
+		plaintext   string
+	}{
+		{"v1.txt", LegacyVersion, "v1 (legacy AES-256-CBC", "DB_PASSWORD=legacy secret\nAPI_KEY=abc123\n"},
+		{"v2_pbkdf2.txt", blockVersion, "v2 (AES-256-GCM, PBKDF2-SHA256, 600000 iterations)", "DB_PASSWORD=block secret\nAPI_KEY=abc123\n"},
+		{"v2_argon2id.txt", blockVersion, "v2 (AES-256-GCM, Argon2id, t=2, m=64 MiB, p=4)", "DB_PASSWORD=block secret\nAPI_KEY=abc123\n"},
+		{"v2_scrypt.txt", blockVersion, "v2 (AES-256-GCM, scrypt, N=2^15, r=8, p=1)", "DB_PASSWORD=block secret\nAPI_KEY=abc123\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data := readFixture(t, tt.file)
+
+			if version, err := Version(data); err != nil || version != tt.version {
+				t.Errorf("Version = %d, %v, want %d", version, err, tt.version)
+			}
+			if description, err := Describe(data); err != nil || !strings.HasPrefix(description, tt.description) {
+				t.Errorf("Describe = %q, %v, want prefix %q", description, err, tt.description)
+			}
+			if !IsArmored(data) {
+				t.Error("legacy data is text")
+			}
+
+			plaintext, version, err := decryptBytes([]byte(data), passwordKeys("correct horse"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != tt.version {
+				t.Errorf("decrypted as version %d, want %d", version, tt.version)
+			}
+			if string(plaintext) != tt.plaintext {
+				t.Errorf("got %q, want %q", plaintext, tt.plaintext)
+			}
+
+			if tt.version == blockVersion {
+				if _, _, err := decryptBytes([]byte(data), passwordKeys("wrong")); !errors.Is(err, ErrAuthentication) {
+					t.Errorf("wrong password: got %v, want ErrAuthentication", err)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyStreamRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, keySize)
+	for _, size := range testSizes {
+		plaintext := testPlaintext(size)
+		var out bytes.Buffer
+		w, err := NewKeyWriter(&out, key, []byte("file"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(plaintext); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sealed := out.Bytes()
+
+		r, err := NewKeyReader(bytes.NewReader(sealed), key, []byte("file"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("size %d: decrypted content differs", size)
+		}
+
+		// The additional data binds the stream to its context
+		r, err = NewKeyReader(bytes.NewReader(sealed), key, []byte("other"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadAll(r); err == nil {
+			t.Errorf("size %d: stream opened with other additional data", size)
+		}
+	}
+}
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,149 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Encrypted data is a binary header followed by the ciphertext:
+//
+//	magic      "ENVDOC"
+//	version    1 byte (3)
+//	cipher     1 byte (1 = AES-256-GCM)
+//	kdf        1 byte (1 = PBKDF2-SHA256, 2 = Argon2id, 3 = scrypt)
+//	params     2 byte big-endian length, then the KDF parameters
+//	salt       1 byte length, then the salt
+//	nonce      1 byte length, then the nonce
+//	ciphertext the rest
+//
+// In version 3 the ciphertext is a stream of chunks (see stream.go), sealed with a key
+// derived from the KDF key and the 16 byte nonce, and the data is written armored (see
+// armor.go) or binary. Version 2 sealed the whole content at once with the 12 byte nonce
+// and was written as plain base64. The header is passed to the cipher as additional data,
+// so it is authenticated too.
+var magic = []byte("ENVDOC")
+
+// Cipher IDs
+const (
+	cipherAES256GCM byte = 1
+)
+
+// Nonce sizes by version
+const (
+	gcmNonceSize    = 12
+	streamNonceSize = 16
+)
+
+// Bounds that keep a crafted header from allocating or spinning without limit
+const (
+	minSaltSize = 16
+	maxParams   = 64
+)
+
+func cipherName(id byte) string {
+	switch id {
+	case cipherAES256GCM:
+		return "AES-256-GCM"
+	}
+	return fmt.Sprintf("cipher %d", id)
+}
+
+// header holds everything needed to derive the key and decrypt
+type header struct {
+	version byte
+	cipher  byte
+	kdf     kdf
+	salt    []byte
+	nonce   []byte
+}
+
+// hasMagic reports whether data starts with the versioned format's magic bytes. Legacy
+// data starts with a random salt, so a collision is practically impossible.
+func hasMagic(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// encode returns the binary header
+func (h header) encode() []byte {
+	params := h.kdf.params()
+	var b bytes.Buffer
+	b.Write(magic)
+	b.WriteByte(h.version)
+	b.WriteByte(h.cipher)
+	b.WriteByte(h.kdf.id())
+	_ = binary.Write(&b, binary.BigEndian, uint16(len(params)))
+	b.Write(params)
+	b.WriteByte(byte(len(h.salt)))
+	b.Write(h.salt)
+	b.WriteByte(byte(len(h.nonce)))
+	b.Write(h.nonce)
+	return b.Bytes()
+}
+
+// decodeHeader parses the header and returns it with the offset of the ciphertext
+func decodeHeader(data []byte) (header, int, error) {
+	h, raw, err := readHeader(bytes.NewReader(data))
+	return h, len(raw), err
+}
+
+// readHeader reads the header and returns it with its encoded form, which authenticates
+// the ciphertext
+func readHeader(r io.Reader) (header, []byte, error) {
+	var raw bytes.Buffer
+	r = io.TeeReader(r, &raw)
+	var h header
+	fail := func() (header, []byte, error) {
+		return header{}, nil, fmt.Errorf("encrypted data has a corrupted header")
+	}
+
+	start := make([]byte, len(magic)+3)
+	if _, err := io.ReadFull(r, start); err != nil || !hasMagic(start) {
+		return fail()
+	}
+	h.version, h.cipher = start[len(magic)], start[len(magic)+1]
+	kdfID := start[len(magic)+2]
+	if h.version != blockVersion && h.version != CurrentVersion {
+		return header{}, nil, fmt.Errorf("unsupported format version %d (upgrade envdoc)", h.version)
+	}
+	if h.cipher != cipherAES256GCM {
+		return header{}, nil, fmt.Errorf("unsupported cipher %d (upgrade envdoc)", h.cipher)
+	}
+	var paramsLen uint16
+	if err := binary.Read(r, binary.BigEndian, &paramsLen); err != nil || paramsLen > maxParams {
+		return fail()
+	}
+	params := make([]byte, paramsLen)
+	if _, err := io.ReadFull(r, params); err != nil {
+		return fail()
+	}
+	var err error
+	if h.kdf, err = decodeKDF(kdfID, params); err != nil {
+		return header{}, nil, err
+	}
+	if h.salt, err = readField(r); err != nil || len(h.salt) < minSaltSize {
+		return fail()
+	}
+	nonceSize := streamNonceSize
+	if h.version == blockVersion {
+		nonceSize = gcmNonceSize
+	}
+	if h.nonce, err = readField(r); err != nil || len(h.nonce) != nonceSize {
+		return fail()
+	}
+	return h, raw.Bytes(), nil
+}
+
+// readField reads a field prefixed with a one byte length
+func readField(r io.Reader) ([]byte, error) {
+	var size [1]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	field := make([]byte, size[0])
+	if _, err := io.ReadFull(r, field); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
@@ -0,0 +1,295 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDF IDs, as stored in the header
+const (
+	kdfPBKDF2SHA256 byte = 1
+	kdfArgon2id     byte = 2
+	kdfScrypt       byte = 3
+)
+
+// Key derivation function names
+const (
+	KDFArgon2id = "argon2id"
+	KDFScrypt   = "scrypt"
+	KDFPBKDF2   = "pbkdf2"
+)
+
+// Cost profiles
+const (
+	ProfileInteractive = "interactive"
+	ProfileModerate    = "moderate"
+	ProfileSensitive   = "sensitive"
+)
+
+// Defaults for new encryptions
+const (
+	DefaultKDF     = KDFArgon2id
+	DefaultProfile = ProfileModerate
+)
+
+// KDFNames and Profiles list the accepted names, for flags and errors
+var (
+	KDFNames = []string{KDFArgon2id, KDFScrypt, KDFPBKDF2}
+	Profiles = []string{ProfileInteractive, ProfileModerate, ProfileSensitive}
+)
+
+// profiles holds the parameters of each profile. Argon2id follows the libsodium limits,
+// scrypt the N values recommended for interactive use and file encryption, PBKDF2 the
+// OWASP iteration count and multiples of it.
+var profiles = map[string]map[string]kdf{
+	KDFArgon2id: {
+		ProfileInteractive: argon2idKDF{time: 2, memory: 64 * 1024, threads: 4},
+		ProfileModerate:    argon2idKDF{time: 3, memory: 256 * 1024, threads: 4},
+		ProfileSensitive:   argon2idKDF{time: 4, memory: 1024 * 1024, threads: 4},
+	},
+	KDFScrypt: {
+		ProfileInteractive: scryptKDF{logN: 15, r: 8, p: 1},
+		ProfileModerate:    scryptKDF{logN: 17, r: 8, p: 1},
+		ProfileSensitive:   scryptKDF{logN: 20, r: 8, p: 1},
+	},
+	KDFPBKDF2: {
+		ProfileInteractive: pbkdf2KDF{iterations: 600000},
+		ProfileModerate:    pbkdf2KDF{iterations: 1200000},
+		ProfileSensitive:   pbkdf2KDF{iterations: 2400000},
+	},
+}
+
+// Limits on header parameters, so a crafted file cannot make decryption allocate or run
+// without bound
+const (
+	maxArgon2Time   = 64
+	maxArgon2Memory = 4 * 1024 * 1024 // KiB
+	maxScryptMemory = 4 << 30         // bytes
+)
+
+// kdf derives the encryption key from a password. Its parameters are stored in the header,
+// so they can be raised later without breaking existing files.
+type kdf interface {
+	id() byte
+	params() []byte
+	derive(password, salt []byte) ([]byte, error)
+	describe() string
+}
+
+// KDFParams selects the key derivation function and its cost for an encryption. The zero
+// value uses the default KDF and profile.
+type KDFParams struct {
+	kdf kdf
+}
+
+// NewKDFParams returns the parameters of a profile of a key derivation function
+func NewKDFParams(name, profile string) (KDFParams, error) {
+	byProfile, ok := profiles[name]
+	if !ok {
+		return KDFParams{}, fmt.Errorf("unknown key derivation function '%s' (use %s)", name, strings.Join(KDFNames, ", "))
+	}
+	k, ok := byProfile[profile]
+	if !ok {
+		return KDFParams{}, fmt.Errorf("unknown KDF profile '%s' (use %s)", profile, strings.Join(Profiles, ", "))
+	}
+	return KDFParams{kdf: k}, nil
+}
+
+// DefaultKDFParams returns the parameters used when none are given
+func DefaultKDFParams() KDFParams {
+	return KDFParams{kdf: profiles[DefaultKDF][DefaultProfile]}
+}
+
+// String describes the function and its parameters
+func (p KDFParams) String() string {
+	return p.get().describe()
+}
+
+// Equal reports whether both select the same function with the same parameters
+func (p KDFParams) Equal(other KDFParams) bool {
+	return p.get().id() == other.get().id() && bytes.Equal(p.get().params(), other.get().params())
+}
+
+func (p KDFParams) get() kdf {
+	if p.kdf == nil {
+		return DefaultKDFParams().kdf
+	}
+	return p.kdf
+}
+
+// BenchmarkKDF picks parameters for which deriving a key takes about the target time on
+// this machine and returns them with the measured time. It never goes below the
+// interactive profile: on machines too slow for the target, that profile is returned with
+// its measured time, which exceeds the target.
+func BenchmarkKDF(name string, target time.Duration) (KDFParams, time.Duration, error) {
+	measure := func(k kdf) time.Duration {
+		start := time.Now()
+		_, _ = k.derive([]byte("benchmark password"), make([]byte, saltSize))
+		return time.Since(start)
+	}
+
+	switch name {
+	case KDFArgon2id:
+		// Lower the memory down to the interactive profile's until its passes fit, then add
+		// passes, which scale linearly
+		floor := profiles[KDFArgon2id][ProfileInteractive].(argon2idKDF)
+		k := argon2idKDF{time: floor.time, memory: 256 * 1024, threads: floor.threads}
+		elapsed := measure(k)
+		for elapsed > target && k.memory > floor.memory {
+			k.memory /= 2
+			elapsed = measure(k)
+		}
+		if passes := uint32(uint64(target) * uint64(k.time) / uint64(elapsed)); passes > k.time {
+			k.time = min(passes, maxArgon2Time)
+			elapsed = measure(k)
+		}
+		return KDFParams{kdf: k}, elapsed, nil
+
+	case KDFScrypt:
+		// Double N while it fits
+		k := scryptKDF{logN: 15, r: 8, p: 1}
+		elapsed := measure(k)
+		for {
+			next := k
+			next.logN++
+			if !withinLimits(next) {
+				break
+			}
+			nextElapsed := measure(next)
+			if nextElapsed > target {
+				break
+			}
+			k, elapsed = next, nextElapsed
+		}
+		return KDFParams{kdf: k}, elapsed, nil
+
+	case KDFPBKDF2:
+		// Iterations scale linearly
+		k := pbkdf2KDF{iterations: 600000}
+		elapsed := measure(k)
+		scaled := uint64(k.iterations) * uint64(target) / uint64(elapsed) / 10000 * 10000
+		if scaled > uint64(k.iterations) {
+			k.iterations = uint32(min(scaled, 100000000))
+			elapsed = measure(k)
+		}
+		return KDFParams{kdf: k}, elapsed, nil
+	}
+	return KDFParams{}, 0, fmt.Errorf("unknown key derivation function '%s' (use %s)", name, strings.Join(KDFNames, ", "))
+}
+
+// withinLimits reports whether decryption would accept the parameters
+func withinLimits(k kdf) bool {
+	_, err := decodeKDF(k.id(), k.params())
+	return err == nil
+}
+
+// decodeKDF parses the KDF parameters of a header
+func decodeKDF(id byte, params []byte) (kdf, error) {
+	switch id {
+	case kdfPBKDF2SHA256:
+		if len(params) != 4 {
+			return nil, fmt.Errorf("encrypted data has invalid PBKDF2 parameters")
+		}
+		k := pbkdf2KDF{iterations: binary.BigEndian.Uint32(params)}
+		if k.iterations < 1000 || k.iterations > 100000000 {
+			return nil, fmt.Errorf("encrypted data has invalid PBKDF2 parameters")
+		}
+		return k, nil
+	case kdfArgon2id:
+		if len(params) != 9 {
+			return nil, fmt.Errorf("encrypted data has invalid Argon2id parameters")
+		}
+		k := argon2idKDF{time: binary.BigEndian.Uint32(params), memory: binary.BigEndian.Uint32(params[4:]), threads: params[8]}
+		if k.time < 1 || k.time > maxArgon2Time || k.memory < 8*uint32(k.threads) || k.memory > maxArgon2Memory || k.threads < 1 {
+			return nil, fmt.Errorf("encrypted data has invalid Argon2id parameters")
+		}
+		return k, nil
+	case kdfScrypt:
+		if len(params) != 9 {
+			return nil, fmt.Errorf("encrypted data has invalid scrypt parameters")
+		}
+		k := scryptKDF{logN: params[0], r: binary.BigEndian.Uint32(params[1:]), p: binary.BigEndian.Uint32(params[5:])}
+		if k.logN < 10 || k.logN > 30 || k.r < 1 || k.r > 64 || k.p < 1 || k.p > 16 ||
+			uint64(128)*uint64(k.r)<<k.logN > maxScryptMemory {
+			return nil, fmt.Errorf("encrypted data has invalid scrypt parameters")
+		}
+		return k, nil
+	}
+	return nil, fmt.Errorf("unsupported key derivation function %d (upgrade envdoc)", id)
+}
+
+// pbkdf2KDF is PBKDF2 with HMAC-SHA256
+type pbkdf2KDF struct {
+	iterations uint32
+}
+
+func (k pbkdf2KDF) id() byte { return kdfPBKDF2SHA256 }
+
+func (k pbkdf2KDF) params() []byte {
+	return binary.BigEndian.AppendUint32(nil, k.iterations)
+}
+
+func (k pbkdf2KDF) derive(password, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(password, salt, int(k.iterations), keySize, sha256.New), nil
+}
+
+func (k pbkdf2KDF) describe() string {
+	return fmt.Sprintf("PBKDF2-SHA256, %d iterations", k.iterations)
+}
+
+// argon2idKDF is Argon2id; memory is in KiB
+type argon2idKDF struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+func (k argon2idKDF) id() byte { return kdfArgon2id }
+
+func (k argon2idKDF) params() []byte {
+	params := binary.BigEndian.AppendUint32(nil, k.time)
+	params = binary.BigEndian.AppendUint32(params, k.memory)
+	return append(params, k.threads)
+}
+
+func (k argon2idKDF) derive(password, salt []byte) ([]byte, error) {
+	return argon2.IDKey(password, salt, k.time, k.memory, k.threads, keySize), nil
+}
+
+func (k argon2idKDF) describe() string {
+	return fmt.Sprintf("Argon2id, t=%d, m=%d MiB, p=%d", k.time, k.memory/1024, k.threads)
+}
+
+// scryptKDF is scrypt with N = 2^logN
+type scryptKDF struct {
+	logN uint8
+	r    uint32
+	p    uint32
+}
+
+func (k scryptKDF) id() byte { return kdfScrypt }
+
+func (k scryptKDF) params() []byte {
+	params := binary.BigEndian.AppendUint32([]byte{k.logN}, k.r)
+	return binary.BigEndian.AppendUint32(params, k.p)
+}
+
+func (k scryptKDF) derive(password, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key(password, salt, 1<<k.logN, int(k.r), int(k.p), keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return key, nil
+}
+
+func (k scryptKDF) describe() string {
+	return fmt.Sprintf("scrypt, N=2^%d, r=%d, p=%d", k.logN, k.r, k.p)
+}
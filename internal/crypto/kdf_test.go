@@ -0,0 +1,63 @@
+package crypto
+
+import "testing"
+
+func TestNewKDFParams(t *testing.T) {
+	for _, name := range KDFNames {
+		for _, profile := range Profiles {
+			params, err := NewKDFParams(name, profile)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", name, profile, err)
+			}
+			// Every profile must be accepted when decrypting
+			if !withinLimits(params.get()) {
+				t.Errorf("%s/%s (%s) is outside the decryption limits", name, profile, params)
+			}
+		}
+	}
+	if _, err := NewKDFParams("md5", ProfileModerate); err == nil {
+		t.Error("unknown KDF was accepted")
+	}
+	if _, err := NewKDFParams(KDFArgon2id, "extreme"); err == nil {
+		t.Error("unknown profile was accepted")
+	}
+	if !(KDFParams{}).Equal(DefaultKDFParams()) {
+		t.Error("the zero value is not the default")
+	}
+}
+
+func TestDecodeKDFLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		k     kdf
+		valid bool
+	}{
+		{"pbkdf2", pbkdf2KDF{iterations: 600000}, true},
+		{"pbkdf2 too few iterations", pbkdf2KDF{iterations: 999}, false},
+		{"pbkdf2 too many iterations", pbkdf2KDF{iterations: 100000001}, false},
+		{"argon2id", argon2idKDF{time: 3, memory: 256 * 1024, threads: 4}, true},
+		{"argon2id too much memory", argon2idKDF{time: 3, memory: maxArgon2Memory + 1, threads: 4}, false},
+		{"argon2id too many passes", argon2idKDF{time: maxArgon2Time + 1, memory: 64 * 1024, threads: 4}, false},
+		{"argon2id no threads", argon2idKDF{time: 3, memory: 64 * 1024, threads: 0}, false},
+		{"scrypt", scryptKDF{logN: 17, r: 8, p: 1}, true},
+		{"scrypt too much memory", scryptKDF{logN: 30, r: 8, p: 1}, false},
+		{"scrypt small N", scryptKDF{logN: 9, r: 8, p: 1}, false},
+	}
+	for _, tt := range tests {
+		k, err := decodeKDF(tt.k.id(), tt.k.params())
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: got error %v, want valid %v", tt.name, err, tt.valid)
+			continue
+		}
+		if err == nil && k != tt.k {
+			t.Errorf("%s: decoded %v, want %v", tt.name, k, tt.k)
+		}
+	}
+
+	if _, err := decodeKDF(99, nil); err == nil {
+		t.Error("unknown KDF ID was accepted")
+	}
+	if _, err := decodeKDF(kdfArgon2id, []byte{1, 2, 3}); err == nil {
+		t.Error("short parameters were accepted")
+	}
+}
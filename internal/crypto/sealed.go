@@ -0,0 +1,149 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+)
+
+// sealedSaltSize is shorter than the file salt to keep sealed values compact
+const sealedSaltSize = 16
+
+// A sealed value is parser.SealedPrefix ("enc:v1:") followed by unpadded URL-safe base64 of:
+//
+//	kdf        1 byte KDF ID
+//	params     1 byte length, then the KDF parameters
+//	salt       1 byte length, then the salt
+//	nonce      12 bytes
+//	ciphertext the rest, including the authentication tag
+//
+// The value is encrypted with AES-256-GCM. The prefix, the key name and the header are
+// authenticated, so a sealed value cannot be moved to another key or altered. Every value
+// carries its own parameters and can be copied between files.
+
+// Sealer encrypts and decrypts single values of env files. Values sealed by one Sealer
+// share a salt, so the password-derived key is computed once; derived keys are cached
+// when unsealing for the same reason.
+type Sealer struct {
+	password []byte
+	params   KDFParams
+	salt     []byte
+	keys     map[string][]byte
+}
+
+// NewSealer returns a Sealer for the password. New values are sealed with params.
+func NewSealer(password string, params KDFParams) (*Sealer, error) {
+	salt := make([]byte, sealedSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return &Sealer{password: []byte(password), params: params, salt: salt, keys: make(map[string][]byte)}, nil
+}
+
+// Seal encrypts the value of a key. The value is sealed as it is written in the file,
+// including any quotes, so Unseal gives back the exact text.
+func (s *Sealer) Seal(name, value string) (string, error) {
+	k := s.params.get()
+	header := sealedHeader(k, s.salt)
+	key, err := s.key(k, s.salt)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(cipherAES256GCM, key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	payload := append(header, nonce...)
+	payload = aead.Seal(payload, nonce, []byte(value), sealedAAD(name, header))
+	return parser.SealedPrefix + base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// Unseal decrypts a sealed value of a key
+func (s *Sealer) Unseal(name, sealed string) (string, error) {
+	sealed = parser.Unquote(sealed)
+	if !strings.HasPrefix(sealed, parser.SealedPrefix) {
+		return "", fmt.Errorf("value of %s is not sealed", name)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(sealed, parser.SealedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("sealed value of %s is corrupted", name)
+	}
+
+	// Parse the header
+	r := bytes.NewReader(payload)
+	corrupted := fmt.Errorf("sealed value of %s is corrupted", name)
+	kdfID, err := r.ReadByte()
+	if err != nil {
+		return "", corrupted
+	}
+	params, err := readField(r)
+	if err != nil {
+		return "", corrupted
+	}
+	k, err := decodeKDF(kdfID, params)
+	if err != nil {
+		return "", fmt.Errorf("sealed value of %s: %w", name, err)
+	}
+	salt, err := readField(r)
+	if err != nil || len(salt) < minSaltSize {
+		return "", corrupted
+	}
+	headerSize := len(payload) - r.Len()
+	if r.Len() < gcmNonceSize {
+		return "", corrupted
+	}
+	header := payload[:headerSize]
+	nonce := payload[headerSize : headerSize+gcmNonceSize]
+
+	key, err := s.key(k, salt)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(cipherAES256GCM, key)
+	if err != nil {
+		return "", err
+	}
+	value, err := aead.Open(nil, nonce, payload[headerSize+gcmNonceSize:], sealedAAD(name, header))
+	if err != nil {
+		return "", fmt.Errorf("cannot unseal %s: wrong password, or the value was modified or belongs to another key", name)
+	}
+	return string(value), nil
+}
+
+// key derives the key for a salt and parameters, or returns it from the cache
+func (s *Sealer) key(k kdf, salt []byte) ([]byte, error) {
+	cacheKey := string(sealedHeader(k, salt))
+	if key, ok := s.keys[cacheKey]; ok {
+		return key, nil
+	}
+	key, err := k.derive(s.password, salt)
+	if err != nil {
+		return nil, err
+	}
+	s.keys[cacheKey] = key
+	return key, nil
+}
+
+// sealedHeader encodes the KDF and salt of a sealed value
+func sealedHeader(k kdf, salt []byte) []byte {
+	params := k.params()
+	header := []byte{k.id(), byte(len(params))}
+	header = append(header, params...)
+	header = append(header, byte(len(salt)))
+	return append(header, salt...)
+}
+
+// sealedAAD binds a sealed value to its key name and header
+func sealedAAD(name string, header []byte) []byte {
+	aad := []byte(parser.SealedPrefix + name + "\x00")
+	return append(aad, header...)
+}
@@ -0,0 +1,77 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+)
+
+func TestSealRoundTrip(t *testing.T) {
+	sealer, err := NewSealer("secret", fastParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"", "plain", `"quoted value"`, "ünïcode ✓", strings.Repeat("x", 1000)} {
+		sealed, err := sealer.Seal("DB_PASSWORD", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !parser.IsSealed(sealed) {
+			t.Errorf("%q: sealed value %q lacks the prefix", value, sealed)
+		}
+
+		// A new Sealer reads the parameters and salt from the value
+		opener, err := NewSealer("secret", KDFParams{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		unsealed, err := opener.Unseal("DB_PASSWORD", sealed)
+		if err != nil {
+			t.Fatalf("%q: %v", value, err)
+		}
+		if unsealed != value {
+			t.Errorf("unsealed %q, want %q", unsealed, value)
+		}
+	}
+}
+
+func TestUnsealRejects(t *testing.T) {
+	sealer, err := NewSealer("secret", fastParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealer.Seal("API_KEY", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrongPassword, err := NewSealer("other", fastParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrongPassword.Unseal("API_KEY", sealed); err == nil {
+		t.Error("unsealed with the wrong password")
+	}
+	if _, err := sealer.Unseal("OTHER_KEY", sealed); err == nil {
+		t.Error("value moved to another key was unsealed")
+	}
+
+	// Flip a character of the payload
+	payload := []byte(sealed)
+	i := len(parser.SealedPrefix) + 20
+	if payload[i] == 'A' {
+		payload[i] = 'B'
+	} else {
+		payload[i] = 'A'
+	}
+	if _, err := sealer.Unseal("API_KEY", string(payload)); err == nil {
+		t.Error("modified value was unsealed")
+	}
+	if _, err := sealer.Unseal("API_KEY", sealed[:len(sealed)-4]); err == nil {
+		t.Error("truncated value was unsealed")
+	}
+	if _, err := sealer.Unseal("API_KEY", "abc123"); err == nil {
+		t.Error("plain value was unsealed")
+	}
+}
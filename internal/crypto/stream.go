@@ -0,0 +1,176 @@
+package crypto
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Content is encrypted in chunks (the STREAM construction), so files of any size are
+// encrypted and decrypted with a fixed amount of memory. Every chunk is sealed with its
+// position as the nonce, an 11 byte big-endian counter followed by a flag byte that is 1
+// for the last chunk only:
+//
+//	chunk      up to 64 KiB of plaintext, plus the authentication tag
+//
+// Reordered, duplicated or dropped chunks fail to authenticate, and a stream cut at a
+// chunk boundary is detected because its new last chunk lacks the flag. Only the first
+// chunk of an empty stream may be empty. Both formats with chunked content (version 3 and
+// age) use this layout.
+
+const streamChunkSize = 64 * 1024
+
+// streamNonce returns the nonce of a chunk
+func streamNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	for i := 10; i >= 3; i-- {
+		nonce[i] = byte(counter)
+		counter >>= 8
+	}
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+var (
+	errStreamClosed = errors.New("encrypted stream is closed")
+	errModified     = errors.New("decryption failed: the data was modified")
+)
+
+// streamWriter encrypts what is written to it chunk by chunk. Close seals the last chunk
+// and then closes closer, if any.
+type streamWriter struct {
+	aead    cipher.AEAD
+	ad      []byte
+	w       io.Writer
+	closer  io.Closer
+	buf     []byte
+	sealed  []byte
+	counter uint64
+	err     error
+}
+
+func newStreamWriter(aead cipher.AEAD, ad []byte, w io.Writer, closer io.Closer) *streamWriter {
+	return &streamWriter{aead: aead, ad: ad, w: w, closer: closer, buf: make([]byte, 0, streamChunkSize)}
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	n := 0
+	for len(p) > 0 {
+		// A full chunk is sealed only once more data follows, so the last chunk is never empty
+		if len(s.buf) == streamChunkSize {
+			if err := s.flush(false); err != nil {
+				return n, err
+			}
+		}
+		copied := copy(s.buf[len(s.buf):streamChunkSize], p)
+		s.buf = s.buf[:len(s.buf)+copied]
+		p = p[copied:]
+		n += copied
+	}
+	return n, nil
+}
+
+func (s *streamWriter) flush(last bool) error {
+	s.sealed = s.aead.Seal(s.sealed[:0], streamNonce(s.counter, last), s.buf, s.ad)
+	if _, err := s.w.Write(s.sealed); err != nil {
+		s.err = err
+		return err
+	}
+	s.counter++
+	s.buf = s.buf[:0]
+	return nil
+}
+
+// Close seals the last chunk. Without it the data cannot be decrypted.
+func (s *streamWriter) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.flush(true); err != nil {
+		return err
+	}
+	s.err = errStreamClosed
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// streamReader decrypts and authenticates chunks as they are read. Data of a chunk is only
+// returned once the chunk is authenticated; a truncated stream fails on its last chunk, so
+// the output must not be used before the reader returns io.EOF.
+type streamReader struct {
+	aead cipher.AEAD
+	ad   []byte
+	// firstErr is returned when the first chunk fails, which means a wrong key unless the
+	// key was verified before
+	firstErr error
+	r        *bufio.Reader
+	buf      []byte
+	plain    []byte
+	counter  uint64
+	done     bool
+	err      error
+}
+
+func newStreamReader(aead cipher.AEAD, ad []byte, r io.Reader, firstErr error) *streamReader {
+	return &streamReader{aead: aead, ad: ad, firstErr: firstErr, r: bufio.NewReader(r), buf: make([]byte, streamChunkSize+aead.Overhead())}
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.done {
+			return 0, io.EOF
+		}
+		s.err = s.next()
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+// next decrypts the next chunk into s.plain
+func (s *streamReader) next() error {
+	n, err := io.ReadFull(s.r, s.buf)
+	last := false
+	switch err {
+	case nil:
+		// A full chunk is the last one when nothing follows
+		if _, err := s.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.ErrUnexpectedEOF:
+		last = true
+	case io.EOF:
+		return fmt.Errorf("encrypted data is truncated")
+	default:
+		return err
+	}
+	if n < s.aead.Overhead() || (last && s.counter > 0 && n == s.aead.Overhead()) {
+		return fmt.Errorf("encrypted data is truncated")
+	}
+
+	plain, err := s.aead.Open(s.buf[:0], streamNonce(s.counter, last), s.buf[:n], s.ad)
+	if err != nil {
+		if s.counter == 0 {
+			return s.firstErr
+		}
+		return fmt.Errorf("decryption failed: the data was truncated or modified after %d KiB", s.counter*streamChunkSize/1024)
+	}
+	s.plain = plain
+	s.counter++
+	s.done = last
+	return nil
+}
@@ -0,0 +1,191 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"io"
+	"testing"
+)
+
+// sealedChunkSize is the size of a full chunk with its authentication tag
+const sealedChunkSize = streamChunkSize + 16
+
+func testStreamAEAD(t *testing.T) cipher.AEAD {
+	t.Helper()
+	aead, err := newAEAD(cipherAES256GCM, bytes.Repeat([]byte{1}, keySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead
+}
+
+func sealStream(t *testing.T, aead cipher.AEAD, plaintext []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w := newStreamWriter(aead, []byte("ad"), &out, nil)
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func openStream(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	return io.ReadAll(newStreamReader(aead, []byte("ad"), bytes.NewReader(sealed), ErrAuthentication))
+}
+
+// chunks splits a sealed stream into its chunks
+func chunks(sealed []byte) [][]byte {
+	var result [][]byte
+	for len(sealed) > sealedChunkSize {
+		result = append(result, sealed[:sealedChunkSize])
+		sealed = sealed[sealedChunkSize:]
+	}
+	return append(result, sealed)
+}
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	aead := testStreamAEAD(t)
+	for _, size := range testSizes {
+		plaintext := testPlaintext(size)
+		sealed := sealStream(t, aead, plaintext)
+
+		wantChunks := max(1, (size+streamChunkSize-1)/streamChunkSize)
+		if got := len(chunks(sealed)); got != wantChunks {
+			t.Errorf("size %d: %d chunks, want %d", size, got, wantChunks)
+		}
+		opened, err := openStream(aead, sealed)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(opened, plaintext) {
+			t.Errorf("size %d: opened content differs", size)
+		}
+	}
+}
+
+func TestStreamWriteSizes(t *testing.T) {
+	// The chunks do not depend on how the content is split into writes
+	aead := testStreamAEAD(t)
+	plaintext := testPlaintext(2*streamChunkSize + 3)
+	var out bytes.Buffer
+	w := newStreamWriter(aead, []byte("ad"), &out, nil)
+	for rest := plaintext; len(rest) > 0; {
+		n := min(len(rest), 1000)
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatal(err)
+		}
+		rest = rest[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := openStream(aead, out.Bytes())
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("small writes do not round trip: %v", err)
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("write after Close succeeded")
+	}
+}
+
+func TestStreamRejectsTampering(t *testing.T) {
+	aead := testStreamAEAD(t)
+	sealed := sealStream(t, aead, testPlaintext(3*streamChunkSize+7))
+	parts := chunks(sealed)
+	if len(parts) != 4 {
+		t.Fatalf("%d chunks", len(parts))
+	}
+
+	for i := range parts {
+		for _, offset := range []int{0, len(parts[i]) / 2, len(parts[i]) - 1} {
+			modified := bytes.Clone(sealed)
+			modified[i*sealedChunkSize+offset] ^= 0x80
+			if _, err := openStream(aead, modified); err == nil {
+				t.Errorf("flipped byte %d of chunk %d was not detected", offset, i)
+			}
+		}
+	}
+}
+
+func TestStreamRejectsTruncation(t *testing.T) {
+	aead := testStreamAEAD(t)
+	sealed := sealStream(t, aead, testPlaintext(3*streamChunkSize+7))
+
+	tests := map[string][]byte{
+		"empty":                  nil,
+		"tag only":               sealed[:16],
+		"after the first chunk":  sealed[:sealedChunkSize],
+		"after the third chunk":  sealed[:3*sealedChunkSize],
+		"inside the last chunk":  sealed[:len(sealed)-1],
+		"inside a middle chunk":  sealed[:sealedChunkSize+100],
+		"last chunk tag missing": sealed[:len(sealed)-16],
+	}
+	for name, truncated := range tests {
+		if _, err := openStream(aead, truncated); err == nil {
+			t.Errorf("%s: truncated stream was accepted", name)
+		}
+	}
+
+	// An empty stream is one empty final chunk, which cannot be dropped either
+	empty := sealStream(t, aead, nil)
+	if len(empty) != 16 {
+		t.Fatalf("empty stream is %d bytes", len(empty))
+	}
+	if _, err := openStream(aead, empty[:0]); err == nil {
+		t.Error("empty stream without its chunk was accepted")
+	}
+}
+
+func TestStreamRejectsReordering(t *testing.T) {
+	aead := testStreamAEAD(t)
+	sealed := sealStream(t, aead, testPlaintext(3*streamChunkSize+7))
+	c := chunks(sealed)
+
+	tests := map[string][]byte{
+		"swapped":            join(c[1], c[0], c[2], c[3]),
+		"duplicated":         join(c[0], c[0], c[1], c[2], c[3]),
+		"dropped":            join(c[0], c[2], c[3]),
+		"last chunk earlier": join(c[0], c[1], c[3]),
+		"appended":           join(c[0], c[1], c[2], c[3], c[3]),
+	}
+	for name, modified := range tests {
+		if _, err := openStream(aead, modified); err == nil {
+			t.Errorf("%s chunks were accepted", name)
+		}
+	}
+}
+
+func TestStreamOnlyReturnsAuthenticatedChunks(t *testing.T) {
+	aead := testStreamAEAD(t)
+	plaintext := testPlaintext(3*streamChunkSize + 7)
+	sealed := sealStream(t, aead, plaintext)
+	sealed[2*sealedChunkSize+5] ^= 1
+
+	opened, err := openStream(aead, sealed)
+	if err == nil {
+		t.Fatal("modified chunk was accepted")
+	}
+	if len(opened) != 2*streamChunkSize || !bytes.Equal(opened, plaintext[:len(opened)]) {
+		t.Errorf("read %d bytes before the modified chunk, want the %d bytes of the chunks before it", len(opened), 2*streamChunkSize)
+	}
+}
+
+func TestDecryptRejectsTruncatedFile(t *testing.T) {
+	data := encryptBytes(t, testPlaintext(2*streamChunkSize+7), "secret", false)
+	_, offset, err := decodeHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{offset, offset + sealedChunkSize, offset + 2*sealedChunkSize, len(data) - 1} {
+		if _, _, err := decryptBytes(data[:size], passwordKeys("secret")); err == nil {
+			t.Errorf("file cut to %d of %d bytes was accepted", size, len(data))
+		}
+	}
+}
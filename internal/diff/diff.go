@@ -0,0 +1,233 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/secrets"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+const DefaultContext = 3
+
+// ANSI colors used when rendering to a terminal
+const (
+	colorReset = "\033[0m"
+	colorBold  = "\033[1m"
+	colorCyan  = "\033[36m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+)
+
+// Line is a line of a hunk: Op is " " for context, "-" for removed and "+" for added lines
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Hunk is a group of nearby changes with their context
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Line `json:"lines"`
+}
+
+// FileDiff is the change to one file
+type FileDiff struct {
+	File    string `json:"file"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Hunks   []Hunk `json:"hunks"`
+}
+
+// Empty reports whether the file is unchanged
+func (d FileDiff) Empty() bool {
+	return len(d.Hunks) == 0
+}
+
+// Compute returns the line diff between two versions of a file
+func Compute(file, before, after string, context int) FileDiff {
+	a, b := splitLines(before), splitLines(after)
+	ops := lineOps(a, b)
+
+	result := FileDiff{File: file}
+	for _, op := range ops {
+		switch op.Op {
+		case "+":
+			result.Added++
+		case "-":
+			result.Removed++
+		}
+	}
+
+	// Group changes that are at most 2*context lines apart into hunks
+	for i := 0; i < len(ops); {
+		if ops[i].Op == " " {
+			i++
+			continue
+		}
+		start := max(i-context, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].Op != " " {
+				end = j
+			} else if j-end > 2*context {
+				break
+			}
+		}
+		stop := min(end+context+1, len(ops))
+
+		hunk := Hunk{OldStart: ops[start].oldLine, NewStart: ops[start].newLine}
+		for _, op := range ops[start:stop] {
+			hunk.Lines = append(hunk.Lines, op.Line)
+			if op.Op != "+" {
+				hunk.OldLines++
+			}
+			if op.Op != "-" {
+				hunk.NewLines++
+			}
+		}
+		result.Hunks = append(result.Hunks, hunk)
+		i = stop
+	}
+	return result
+}
+
+// MaskValues replaces the values of KEY=VALUE lines with masked values
+func (d *FileDiff) MaskValues() {
+	for i := range d.Hunks {
+		for j, line := range d.Hunks[i].Lines {
+			d.Hunks[i].Lines[j].Text = maskLine(line.Text)
+		}
+	}
+}
+
+// maskLine masks the value of an assignment, keeping quotes so the change stays readable
+func maskLine(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || !strings.Contains(text, "=") {
+		return text
+	}
+	parts := strings.SplitN(text, "=", 2)
+	value := strings.TrimSpace(parts[1])
+	quote := ""
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		quote = value[:1]
+		value = value[1 : len(value)-1]
+	}
+	return parts[0] + "=" + quote + secrets.Mask(value) + quote
+}
+
+// Render writes diffs in unified format, colored for terminals
+func Render(w io.Writer, diffs []FileDiff, color bool) {
+	paint := func(code, text string) string {
+		if !color {
+			return text
+		}
+		return code + text + colorReset
+	}
+
+	for _, d := range diffs {
+		if d.Empty() {
+			continue
+		}
+		fmt.Fprintln(w, paint(colorBold, "--- a/"+d.File))
+		fmt.Fprintln(w, paint(colorBold, "+++ b/"+d.File))
+		for _, hunk := range d.Hunks {
+			fmt.Fprintln(w, paint(colorCyan, fmt.Sprintf("@@ -%s +%s @@", hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))))
+			for _, line := range hunk.Lines {
+				switch line.Op {
+				case "+":
+					fmt.Fprintln(w, paint(colorGreen, "+"+line.Text))
+				case "-":
+					fmt.Fprintln(w, paint(colorRed, "-"+line.Text))
+				default:
+					fmt.Fprintln(w, " "+line.Text)
+				}
+			}
+		}
+	}
+}
+
+// RenderJSON writes diffs as a JSON array, including unchanged files
+func RenderJSON(w io.Writer, diffs []FileDiff) error {
+	for i := range diffs {
+		if diffs[i].Hunks == nil {
+			diffs[i].Hunks = []Hunk{}
+		}
+	}
+	if diffs == nil {
+		diffs = []FileDiff{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diffs)
+}
+
+// hunkRange formats the start,count part of a hunk header
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	if count == 0 {
+		// An empty range refers to the line before it
+		start--
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// op is a diff line with its position in both versions (1-based)
+type op struct {
+	Line
+	oldLine int
+	newLine int
+}
+
+// lineOps computes an edit script from the longest common subsequence of lines
+func lineOps(a, b []string) []op {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []op
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, op{Line{" ", a[i]}, i + 1, j + 1})
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, op{Line{"+", b[j]}, i + 1, j + 1})
+			j++
+		default:
+			ops = append(ops, op{Line{"-", a[i]}, i + 1, j + 1})
+			i++
+		}
+	}
+	return ops
+}
+
+// splitLines splits content into lines without their line endings
+func splitLines(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
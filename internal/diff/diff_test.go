@@ -0,0 +1,147 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// render returns the unified diff of two contents without the file header
+func render(before, after string) string {
+	var out bytes.Buffer
+	Render(&out, []FileDiff{Compute("f", before, after, DefaultContext)}, false)
+	lines := strings.SplitAfterN(out.String(), "\n", 3)
+	if len(lines) < 3 {
+		return ""
+	}
+	return lines[2]
+}
+
+// The expected hunks are the output of GNU diff -u for the same contents
+func TestRenderMatchesUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{
+			name:   "separate hunks",
+			before: "A=1\nB=2\nC=3\nD=4\nE=5\nF=6\nG=7\nH=8\nI=9\nJ=10\nK=11\nL=12\n",
+			after:  "A=1\nB=two\nC=3\nD=4\nE=5\nF=6\nG=7\nH=8\nI=9\nJ=10\nK=11\nL=12\nM=13\n",
+			want: "@@ -1,5 +1,5 @@\n A=1\n-B=2\n+B=two\n C=3\n D=4\n E=5\n" +
+				"@@ -10,3 +10,4 @@\n J=10\n K=11\n L=12\n+M=13\n",
+		},
+		{
+			name:   "nearby changes share a hunk",
+			before: "A=1\nB=2\nC=3\nD=4\nE=5\nF=6\nG=7\nH=8\nI=9\n",
+			after:  "A=1\nB=x\nC=3\nD=4\nE=5\nF=6\nG=y\nH=8\nI=9\n",
+			want:   "@@ -1,9 +1,9 @@\n A=1\n-B=2\n+B=x\n C=3\n D=4\n E=5\n F=6\n-G=7\n+G=y\n H=8\n I=9\n",
+		},
+		{
+			name:   "created",
+			before: "",
+			after:  "A=1\nB=2\n",
+			want:   "@@ -0,0 +1,2 @@\n+A=1\n+B=2\n",
+		},
+		{
+			name:   "emptied",
+			before: "A=1\nB=2\n",
+			after:  "",
+			want:   "@@ -1,2 +0,0 @@\n-A=1\n-B=2\n",
+		},
+		{
+			name:   "unchanged",
+			before: "A=1\n",
+			after:  "A=1\n",
+			want:   "",
+		},
+		{
+			name:   "line endings are ignored",
+			before: "A=1\r\nB=2\r\n",
+			after:  "A=1\nB=2\n",
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(tt.before, tt.after); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeCounts(t *testing.T) {
+	d := Compute("f", "A=1\nB=2\nC=3\n", "A=1\nC=4\nD=5\n", DefaultContext)
+	if d.Added != 2 || d.Removed != 2 {
+		t.Errorf("added %d, removed %d, want 2 and 2", d.Added, d.Removed)
+	}
+	if d.Empty() {
+		t.Error("diff is empty")
+	}
+	if !Compute("f", "A=1\n", "A=1\n", DefaultContext).Empty() {
+		t.Error("diff of equal contents is not empty")
+	}
+}
+
+func TestMaskValues(t *testing.T) {
+	d := Compute("f", "# Database\nDB_PASSWORD=hunter2hunter2\n", "# Database\nDB_PASSWORD=\"correct horse battery\"\n", DefaultContext)
+	d.MaskValues()
+	var out bytes.Buffer
+	Render(&out, []FileDiff{d}, false)
+	rendered := out.String()
+
+	for _, secret := range []string{"hunter2hunter2", "correct horse battery"} {
+		if strings.Contains(rendered, secret) {
+			t.Errorf("value %q is not masked:\n%s", secret, rendered)
+		}
+	}
+	for _, kept := range []string{" # Database\n", "-DB_PASSWORD=", "+DB_PASSWORD=\""} {
+		if !strings.Contains(rendered, kept) {
+			t.Errorf("%q is missing:\n%s", kept, rendered)
+		}
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	var out bytes.Buffer
+	Render(&out, []FileDiff{Compute("f", "A=1\n", "A=2\n", DefaultContext)}, true)
+	for _, colored := range []string{colorRed + "-A=1" + colorReset, colorGreen + "+A=2" + colorReset, colorBold + "--- a/f" + colorReset} {
+		if !strings.Contains(out.String(), colored) {
+			t.Errorf("%q is missing from %q", colored, out.String())
+		}
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	var out bytes.Buffer
+	diffs := []FileDiff{
+		Compute("changed", "A=1\n", "A=2\n", DefaultContext),
+		Compute("unchanged", "A=1\n", "A=1\n", DefaultContext),
+	}
+	if err := RenderJSON(&out, diffs); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded []FileDiff
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0].File != "changed" || decoded[0].Added != 1 || decoded[0].Removed != 1 {
+		t.Fatalf("unexpected JSON: %s", out.String())
+	}
+	// Unchanged files are listed with an empty array, not null
+	if !strings.Contains(out.String(), `"hunks": []`) {
+		t.Errorf("unchanged file has no empty hunk list: %s", out.String())
+	}
+
+	out.Reset()
+	if err := RenderJSON(&out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("no diffs rendered as %q", out.String())
+	}
+}
@@ -0,0 +1,193 @@
+package drift
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+)
+
+// State describes a key's value in one file relative to the other files
+type State string
+
+const (
+	// Present marks the reference value: the first file that sets the key
+	Present State = "present"
+	// Missing means the key is not defined in the file
+	Missing State = "missing"
+	// Empty means the key is defined without a value
+	Empty State = "empty"
+	// Same means the value equals the reference value
+	Same State = "same"
+	// Different means the value differs from the reference value
+	Different State = "different"
+	// Sealed means the value is encrypted and cannot be compared
+	Sealed State = "sealed"
+)
+
+// Cell is a single key × file entry of the matrix
+type Cell struct {
+	State State
+	Value string
+}
+
+// Row holds the cells of one key, in file order
+type Row struct {
+	Key   string
+	Cells []Cell
+}
+
+// Matrix is the key × file drift matrix
+type Matrix struct {
+	Files []string
+	Rows  []Row
+}
+
+// Violation is a failed consistency check
+type Violation struct {
+	Key     string
+	Check   string
+	Message string
+}
+
+// Build computes the drift matrix for the files, in the given order
+func Build(files []string, allEnvVars map[string][]parser.EnvVar) *Matrix {
+	values := make([]map[string]string, len(files))
+	keySet := make(map[string]bool)
+	for i, file := range files {
+		values[i] = make(map[string]string)
+		for _, envVar := range allEnvVars[file] {
+			values[i][envVar.Key] = parser.Unquote(envVar.Value)
+			keySet[envVar.Key] = true
+		}
+	}
+
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	matrix := &Matrix{Files: files}
+	for _, key := range keys {
+		row := Row{Key: key, Cells: make([]Cell, len(files))}
+		reference, hasReference := "", false
+		for i := range files {
+			value, ok := values[i][key]
+			switch {
+			case !ok:
+				row.Cells[i] = Cell{State: Missing}
+			case value == "":
+				row.Cells[i] = Cell{State: Empty}
+			case parser.IsSealed(value):
+				row.Cells[i] = Cell{State: Sealed}
+			case !hasReference:
+				reference, hasReference = value, true
+				row.Cells[i] = Cell{State: Present, Value: value}
+			case value == reference:
+				row.Cells[i] = Cell{State: Same, Value: value}
+			default:
+				row.Cells[i] = Cell{State: Different, Value: value}
+			}
+		}
+		matrix.Rows = append(matrix.Rows, row)
+	}
+
+	return matrix
+}
+
+// HasDrift reports whether the row has missing, empty or differing values
+func (r Row) HasDrift() bool {
+	for _, cell := range r.Cells {
+		if cell.State == Missing || cell.State == Empty || cell.State == Different {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckMustDiffer reports keys matching the patterns whose value is shared by two or more files
+func CheckMustDiffer(m *Matrix, patterns []string) []Violation {
+	var violations []Violation
+	for _, row := range m.Rows {
+		if !matchesAny(patterns, row.Key) {
+			continue
+		}
+		filesByValue := make(map[string][]string)
+		var order []string
+		for i, cell := range row.Cells {
+			if cell.Value == "" {
+				continue
+			}
+			if _, seen := filesByValue[cell.Value]; !seen {
+				order = append(order, cell.Value)
+			}
+			filesByValue[cell.Value] = append(filesByValue[cell.Value], m.Files[i])
+		}
+		for _, value := range order {
+			if shared := filesByValue[value]; len(shared) > 1 {
+				violations = append(violations, Violation{
+					Key:     row.Key,
+					Check:   "must-differ",
+					Message: fmt.Sprintf("Same value in %s", quoteFiles(shared)),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// CheckMustMatch reports keys matching the patterns whose value is not identical in every file
+func CheckMustMatch(m *Matrix, patterns []string) []Violation {
+	var violations []Violation
+	for _, row := range m.Rows {
+		if !matchesAny(patterns, row.Key) {
+			continue
+		}
+		var missing, differing []string
+		for i, cell := range row.Cells {
+			switch cell.State {
+			case Missing, Empty:
+				missing = append(missing, m.Files[i])
+			case Different:
+				differing = append(differing, m.Files[i])
+			}
+		}
+		if len(differing) > 0 {
+			violations = append(violations, Violation{
+				Key:     row.Key,
+				Check:   "must-match",
+				Message: fmt.Sprintf("Value differs in %s", quoteFiles(differing)),
+			})
+		}
+		if len(missing) > 0 {
+			violations = append(violations, Violation{
+				Key:     row.Key,
+				Check:   "must-match",
+				Message: fmt.Sprintf("Missing or empty in %s", quoteFiles(missing)),
+			})
+		}
+	}
+	return violations
+}
+
+// matchesAny reports whether the key matches any of the glob patterns
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteFiles formats file names for a message
+func quoteFiles(files []string) string {
+	quoted := make([]string, len(files))
+	for i, file := range files {
+		quoted[i] = "`" + file + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
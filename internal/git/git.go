@@ -0,0 +1,233 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// zeroHash is the object ID git uses for a missing blob
+const zeroHash = "0000000000000000000000000000000000000000"
+
+// Change is a blob added or modified by a commit
+type Change struct {
+	Commit string
+	Date   string
+	Author string
+	Path   string
+	Blob   string
+}
+
+// Command runs git in the given directory and returns its standard output
+func Command(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "core.quotePath=false"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// IsRepository reports whether the directory is inside a git work tree
+func IsRepository(dir string) bool {
+	out, err := Command(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// TopLevel returns the root directory of the work tree
+func TopLevel(dir string) (string, error) {
+	out, err := Command(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// TrackedFiles returns the paths tracked in the index, relative to the work tree root
+func TrackedFiles(dir string) (map[string]bool, error) {
+	out, err := Command(dir, "ls-files", "-z", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool)
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path != "" {
+			files[path] = true
+		}
+	}
+	return files, nil
+}
+
+// IgnoredPaths returns which of the paths (relative to dir) are excluded by .gitignore rules.
+// With noIndex the rules are applied even to tracked files.
+func IgnoredPaths(dir string, paths []string, noIndex bool) (map[string]bool, error) {
+	ignored := make(map[string]bool)
+	if len(paths) == 0 {
+		return ignored, nil
+	}
+	args := []string{"-C", dir, "check-ignore", "--stdin", "-z"}
+	if noIndex {
+		args = append(args, "--no-index")
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	// check-ignore exits with status 1 when none of the paths are ignored
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return ignored, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("git check-ignore: %s", strings.TrimSpace(stderr.String()))
+	}
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path != "" {
+			ignored[path] = true
+		}
+	}
+	return ignored, nil
+}
+
+// StagedFiles returns the paths added, copied, modified or renamed in the index, relative to the work tree root
+func StagedFiles(dir string) ([]string, error) {
+	out, err := Command(dir, "diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// StagedContent returns the content of a file as it is staged in the index
+func StagedContent(dir, path string) ([]byte, error) {
+	return Command(dir, "show", ":"+path)
+}
+
+// HooksDir returns the directory git runs hooks from, honouring core.hooksPath
+func HooksDir(dir string) (string, error) {
+	out, err := Command(dir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	hooks := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(hooks) {
+		hooks = filepath.Join(dir, hooks)
+	}
+	return hooks, nil
+}
+
+// History returns every blob added or modified in the history reachable from all refs,
+// oldest commit first. since is passed to git log --since when set.
+func History(dir, since string) ([]Change, error) {
+	args := []string{"log", "--all", "--reverse", "--no-renames", "--raw", "--no-abbrev", "--format=%x01%H%x00%aI%x00%an"}
+	if since != "" {
+		args = append(args, "--since="+since)
+	}
+	out, err := Command(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	var commit, date, author string
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "\x01"):
+			parts := strings.SplitN(strings.TrimPrefix(line, "\x01"), "\x00", 3)
+			if len(parts) == 3 {
+				commit, date, author = parts[0], parts[1], parts[2]
+			}
+		case strings.HasPrefix(line, ":"):
+			// :<old mode> <new mode> <old blob> <new blob> <status>\t<path>
+			meta, path, ok := strings.Cut(line, "\t")
+			fields := strings.Fields(meta)
+			if !ok || len(fields) < 5 {
+				continue
+			}
+			blob, status := fields[3], fields[4]
+			if blob == zeroHash || strings.HasPrefix(status, "D") || fields[1] == "160000" {
+				continue
+			}
+			changes = append(changes, Change{Commit: commit, Date: date, Author: author, Path: path, Blob: blob})
+		}
+	}
+	return changes, nil
+}
+
+// BlobReader reads blob contents through a single long-running git cat-file process
+type BlobReader struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// NewBlobReader starts git cat-file --batch in the given directory
+func NewBlobReader(dir string) (*BlobReader, error) {
+	cmd := exec.Command("git", "-C", dir, "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+	}
+	return &BlobReader{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// Read returns the content of a blob. Blobs larger than maxSize are skipped and nil is returned.
+func (b *BlobReader) Read(blob string, maxSize int64) ([]byte, error) {
+	if _, err := fmt.Fprintln(b.stdin, blob); err != nil {
+		return nil, err
+	}
+	header, err := b.stdout.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	// <sha> <type> <size>, or <sha> missing
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("object %s not found", blob)
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cat-file header: %q", header)
+	}
+
+	if size > maxSize {
+		_, err := io.CopyN(io.Discard, b.stdout, size+1)
+		return nil, err
+	}
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(b.stdout, data); err != nil {
+		return nil, err
+	}
+	return data[:size], nil
+}
+
+// Close stops the cat-file process
+func (b *BlobReader) Close() error {
+	if err := b.stdin.Close(); err != nil {
+		return err
+	}
+	return b.cmd.Wait()
+}
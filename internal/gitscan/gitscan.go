@@ -0,0 +1,137 @@
+package gitscan
+
+import (
+	"path"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/baseline"
+	"github.com/MayR-Labs/envdoc-go/internal/git"
+	"github.com/MayR-Labs/envdoc-go/internal/secrets"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+)
+
+// maxBlobSize skips large blobs such as assets and bundles
+const maxBlobSize = 1 << 20
+
+// CommittedEnvFile is the rule reported for plaintext env files found in history
+const CommittedEnvFile = "committed-env-file"
+
+// Finding is an env file or secret found in a commit
+type Finding struct {
+	Commit      string
+	Date        string
+	Author      string
+	Path        string
+	Line        int
+	Key         string
+	MaskedValue string
+	// Value is the unmasked secret, for keyed baseline fingerprints; never print it
+	Value       string
+	Rule        string
+	Description string
+	Confidence  secrets.Confidence
+	// Tracked is true when the path is still in the index, false when it only lives in history
+	Tracked bool
+	// Fingerprint identifies a committed env file independently of the commit it was first
+	// seen in. Secrets have none: their fingerprint hashes the value with the baseline's salt.
+	Fingerprint string
+}
+
+// Options controls the history walk
+type Options struct {
+	// Since limits the walk to commits newer than this date (any format git log --since accepts)
+	Since string
+}
+
+// Scan walks the history of the repository containing dir and returns committed env files
+// and secrets, each reported once at the oldest commit it appears in
+func Scan(dir string, opts Options) ([]Finding, error) {
+	changes, err := git.History(dir, opts.Since)
+	if err != nil {
+		return nil, err
+	}
+	tracked, err := git.TrackedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := git.NewBlobReader(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func(reader *git.BlobReader) {
+		err := reader.Close()
+		if err != nil {
+			return
+		}
+	}(reader)
+
+	var findings []Finding
+	seen := make(map[string]bool)
+	scanned := make(map[string]bool)
+	add := func(finding Finding) {
+		id := strings.Join([]string{finding.Rule, finding.Path, finding.Key, finding.Value}, "\x00")
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		findings = append(findings, finding)
+	}
+
+	for _, change := range changes {
+		base := path.Base(change.Path)
+		envFile := utils.IsEnvFileName(base)
+
+		if envFile && !utils.IsExampleEnvFileName(base) {
+			add(Finding{
+				Commit:      change.Commit,
+				Date:        change.Date,
+				Author:      change.Author,
+				Path:        change.Path,
+				Rule:        CommittedEnvFile,
+				Description: "Plaintext env file committed to the repository",
+				Confidence:  secrets.High,
+				Tracked:     tracked[change.Path],
+				Fingerprint: baseline.Fingerprint(CommittedEnvFile, change.Path),
+			})
+		}
+
+		if scanned[change.Blob] {
+			continue
+		}
+		scanned[change.Blob] = true
+
+		content, err := reader.Read(change.Blob, maxBlobSize)
+		if err != nil {
+			return nil, err
+		}
+		if content == nil || utils.IsBinary(content) {
+			continue
+		}
+
+		for _, secret := range secrets.ScanContent(string(content)) {
+			// Outside env files only known credential formats are reported, to keep noise down
+			if !envFile && secret.Confidence != secrets.High {
+				continue
+			}
+			// Every distinct value is reported, so a rotated secret that leaked again is not
+			// hidden behind the first one
+			add(Finding{
+				Commit:      change.Commit,
+				Date:        change.Date,
+				Author:      change.Author,
+				Path:        change.Path,
+				Line:        secret.Line,
+				Key:         secret.Key,
+				MaskedValue: secret.MaskedValue,
+				Value:       secret.Value,
+				Rule:        secret.Rule,
+				Description: secret.Description,
+				Confidence:  secret.Confidence,
+				Tracked:     tracked[change.Path],
+			})
+		}
+	}
+
+	return findings, nil
+}
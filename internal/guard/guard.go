@@ -0,0 +1,289 @@
+package guard
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/git"
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+	"github.com/MayR-Labs/envdoc-go/internal/secrets"
+	"github.com/MayR-Labs/envdoc-go/internal/usage"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+	"github.com/MayR-Labs/envdoc-go/internal/validator"
+)
+
+// maxStagedSize skips large staged files such as assets and bundles
+const maxStagedSize = 1 << 20
+
+// EncryptedSuffix is the extension written by the encrypt command
+const EncryptedSuffix = ".encrypted"
+
+// Issue is a single git hygiene problem
+type Issue struct {
+	Rule     string
+	Severity string
+	File     string
+	Line     int
+	Key      string
+	Value    string
+	Message  string
+}
+
+// probes are paths that must be ignored even before such files exist
+var probes = []struct {
+	path    string
+	pattern string
+}{
+	{".env", ".env"},
+	{".env.local", ".env.local"},
+	{".env.development.local", ".env.*.local"},
+}
+
+// DiscoverEnvFiles returns the env files in the work tree and the index, as slash-separated
+// paths relative to root. Dependency and build directories are skipped.
+func DiscoverEnvFiles(root string, tracked map[string]bool) ([]string, error) {
+	found := make(map[string]bool)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			for _, exclude := range usage.DefaultExcludes {
+				if p != root && d.Name() == exclude {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if utils.IsEnvFileName(d.Name()) {
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			found[filepath.ToSlash(rel)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for file := range tracked {
+		if utils.IsEnvFileName(path.Base(file)) {
+			found[file] = true
+		}
+	}
+
+	files := make([]string, 0, len(found))
+	for file := range found {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// DiscoverEncryptedFiles returns the files with the encrypted suffix in the work tree, as
+// slash-separated paths relative to root. Dependency and build directories are skipped.
+func DiscoverEncryptedFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			for _, exclude := range usage.DefaultExcludes {
+				if p != root && d.Name() == exclude {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), EncryptedSuffix) {
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// IsLocalOnly reports whether an env file only ever holds a developer's local settings,
+// so no encrypted copy is expected for it
+func IsLocalOnly(name string) bool {
+	return name == ".env" || strings.HasSuffix(name, ".local")
+}
+
+// CheckRepository verifies .gitignore coverage, tracked plaintext files and encrypted copies
+// for every env file in the repository rooted at root
+func CheckRepository(root string) ([]Issue, []string, error) {
+	tracked, err := git.TrackedFiles(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err := DiscoverEnvFiles(root, tracked)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var plaintext []string
+	for _, file := range files {
+		if !utils.IsExampleEnvFileName(path.Base(file)) {
+			plaintext = append(plaintext, file)
+		}
+	}
+	ignored, err := git.IgnoredPaths(root, plaintext, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var issues []Issue
+	for _, file := range plaintext {
+		switch {
+		case tracked[file]:
+			issues = append(issues, Issue{
+				Rule:     "tracked-env-file",
+				Severity: "error",
+				File:     file,
+				Message:  "Plaintext env file is tracked by git. Untrack it with `git rm --cached " + file + "` and ignore it",
+			})
+		case !ignored[file]:
+			issues = append(issues, Issue{
+				Rule:     "not-ignored",
+				Severity: "error",
+				File:     file,
+				Message:  "Not covered by .gitignore, so it can be committed by accident",
+			})
+		}
+
+		if IsLocalOnly(path.Base(file)) {
+			continue
+		}
+		encrypted := file + EncryptedSuffix
+		switch {
+		case !utils.FileExists(filepath.Join(root, filepath.FromSlash(encrypted))) && !tracked[encrypted]:
+			issues = append(issues, Issue{
+				Rule:     "missing-encrypted",
+				Severity: "warning",
+				File:     file,
+				Message:  fmt.Sprintf("No encrypted copy (%s). Create one with `envdoc encrypt` so the environment can be restored", path.Base(encrypted)),
+			})
+		case !tracked[encrypted]:
+			issues = append(issues, Issue{
+				Rule:     "encrypted-not-committed",
+				Severity: "info",
+				File:     encrypted,
+				Message:  "Encrypted copy exists but is not committed",
+			})
+		}
+	}
+
+	// Files that do not exist yet must be ignored too
+	probePaths := make([]string, len(probes))
+	for i, probe := range probes {
+		probePaths[i] = probe.path
+	}
+	probed, err := git.IgnoredPaths(root, probePaths, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, probe := range probes {
+		if !probed[probe.path] {
+			issues = append(issues, Issue{
+				Rule:     "gitignore-pattern",
+				Severity: "warning",
+				File:     ".gitignore",
+				Key:      probe.pattern,
+				Message:  fmt.Sprintf("`%s` files are not ignored. Add `%s` to .gitignore", probe.pattern, probe.pattern),
+			})
+		}
+	}
+
+	return issues, files, nil
+}
+
+// CheckStaged inspects the files staged for commit in the repository rooted at root.
+// Plaintext env files and known credential formats are reported as errors; when schemaJSON
+// is set, staged env files are also validated against it.
+func CheckStaged(root, schemaJSON string) ([]Issue, []string, error) {
+	staged, err := git.StagedFiles(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var issues []Issue
+	for _, file := range staged {
+		base := path.Base(file)
+		envFile := utils.IsEnvFileName(base)
+
+		if envFile && !utils.IsExampleEnvFileName(base) {
+			issues = append(issues, Issue{
+				Rule:     "staged-env-file",
+				Severity: "error",
+				File:     file,
+				Message:  "Plaintext env file staged for commit. Unstage it with `git restore --staged " + file + "` and ignore it",
+			})
+		}
+
+		content, err := git.StagedContent(root, file)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(content) > maxStagedSize || utils.IsBinary(content) {
+			continue
+		}
+
+		for _, finding := range secrets.ScanContent(string(content)) {
+			// Only known credential formats block a commit; weaker signals are left to audit
+			if finding.Confidence != secrets.High {
+				continue
+			}
+			issues = append(issues, Issue{
+				Rule:     finding.Rule,
+				Severity: "error",
+				File:     file,
+				Line:     finding.Line,
+				Key:      finding.Key,
+				Value:    finding.MaskedValue,
+				Message:  finding.Description,
+			})
+		}
+
+		if schemaJSON != "" && envFile {
+			envVars, err := parser.ParseEnv(bytes.NewReader(content))
+			if err != nil {
+				return nil, nil, err
+			}
+			// Templates hold no values, so only the keys are checked against the schema
+			if utils.IsExampleEnvFileName(base) {
+				for i := range envVars {
+					envVars[i].Value = ""
+				}
+			}
+			errors, err := validator.ValidateAgainstSchema(envVars, schemaJSON)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, message := range errors {
+				issues = append(issues, Issue{
+					Rule:     "schema",
+					Severity: "error",
+					File:     file,
+					Message:  message,
+				})
+			}
+		}
+	}
+
+	return issues, staged, nil
+}
@@ -0,0 +1,250 @@
+package insecure
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/config"
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+)
+
+// Environment names recognised by the checks
+const (
+	Production  = "production"
+	Staging     = "staging"
+	Development = "development"
+	Test        = "test"
+)
+
+// Issue represents an insecure value found in an env file
+type Issue struct {
+	Rule     string
+	Key      string
+	Value    string
+	Severity string
+	Message  string
+}
+
+// environmentAliases maps file name tokens to environments
+var environmentAliases = map[string]string{
+	"production": Production, "prod": Production, "live": Production, "prd": Production,
+	"staging": Staging, "stage": Staging, "stg": Staging, "preprod": Staging, "uat": Staging,
+	"development": Development, "dev": Development, "local": Development,
+	"test": Test, "testing": Test, "ci": Test,
+}
+
+// DefaultRules is the built-in rule pack of insecure-in-production checks
+var DefaultRules = []config.InsecureRule{
+	{
+		ID:           "debug-enabled",
+		Keys:         []string{"APP_DEBUG", "DEBUG", "*_DEBUG"},
+		Environments: []string{Production, Staging},
+		Equals:       []string{"true", "1", "yes", "on"},
+		Severity:     "error",
+		Message:      "Debug mode exposes stack traces and configuration",
+	},
+	{
+		ID:           "env-mismatch",
+		Keys:         []string{"APP_ENV", "NODE_ENV", "RAILS_ENV", "RACK_ENV", "FLASK_ENV", "GO_ENV"},
+		Environments: []string{Production},
+		Equals:       []string{"local", "dev", "development", "test", "testing"},
+		Severity:     "error",
+		Message:      "Application environment is not set to production",
+	},
+	{
+		ID:           "verbose-logging",
+		Keys:         []string{"LOG_LEVEL", "*_LOG_LEVEL"},
+		Environments: []string{Production},
+		Equals:       []string{"debug", "trace"},
+		Severity:     "warning",
+		Message:      "Verbose logging can leak sensitive data",
+	},
+	{
+		ID:           "local-host",
+		Keys:         []string{"*_HOST"},
+		Environments: []string{Production, Staging},
+		Matches:      `^(localhost|127\.0\.0\.1|0\.0\.0\.0|::1)$`,
+		Severity:     "warning",
+		Message:      "Service points at a local address",
+	},
+	{
+		ID:           "empty-password",
+		Keys:         []string{"*_PASSWORD", "*_PASS"},
+		Environments: []string{Production, Staging},
+		Equals:       []string{"null"},
+		Empty:        true,
+		Severity:     "error",
+		Message:      "Password is empty",
+	},
+	{
+		ID:           "root-user",
+		Keys:         []string{"DB_USERNAME", "DB_USER", "*_DB_USER", "MYSQL_USER", "POSTGRES_USER"},
+		Environments: []string{Production},
+		Equals:       []string{"root", "postgres", "sa", "admin"},
+		Severity:     "warning",
+		Message:      "Application connects with a superuser account",
+	},
+	{
+		ID:           "insecure-url",
+		Keys:         []string{"APP_URL", "*_URL"},
+		Environments: []string{Production},
+		Matches:      `^http://`,
+		Severity:     "warning",
+		Message:      "URL does not use HTTPS",
+	},
+	{
+		ID:           "insecure-cookie",
+		Keys:         []string{"SESSION_SECURE_COOKIE", "SESSION_ENCRYPT"},
+		Environments: []string{Production},
+		Equals:       []string{"false", "0", "no", "off"},
+		Severity:     "warning",
+		Message:      "Session cookies are not secured",
+	},
+}
+
+// InferEnvironment determines which environment a file targets, from the config or its name
+func InferEnvironment(filename string, cfg *config.Config) string {
+	if cfg != nil {
+		if env, ok := cfg.Environment(filename); ok {
+			return normalizeEnvironment(env)
+		}
+	}
+
+	base := strings.ToLower(filepath.Base(filename))
+	tokens := strings.FieldsFunc(base, func(r rune) bool {
+		return r == '.' || r == '-' || r == '_'
+	})
+	for _, token := range tokens {
+		if env, ok := environmentAliases[token]; ok {
+			return env
+		}
+	}
+	return ""
+}
+
+// normalizeEnvironment maps aliases like "prod" to their canonical name
+func normalizeEnvironment(env string) string {
+	lower := strings.ToLower(env)
+	if canonical, ok := environmentAliases[lower]; ok {
+		return canonical
+	}
+	return lower
+}
+
+// Rules returns the active rule pack: built-in rules minus disabled ones, plus project rules
+func Rules(cfg *config.Config) []config.InsecureRule {
+	disabled := make(map[string]bool)
+	var custom []config.InsecureRule
+	if cfg != nil {
+		for _, id := range cfg.Insecure.Disable {
+			disabled[id] = true
+		}
+		custom = cfg.Insecure.Rules
+	}
+
+	var rules []config.InsecureRule
+	overridden := make(map[string]bool)
+	for _, rule := range custom {
+		overridden[rule.ID] = true
+	}
+	for _, rule := range DefaultRules {
+		if !disabled[rule.ID] && !overridden[rule.ID] {
+			rules = append(rules, rule)
+		}
+	}
+	for _, rule := range custom {
+		if !disabled[rule.ID] {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// Check runs the rules that apply to the environment against the variables
+func Check(envVars []parser.EnvVar, environment string, rules []config.InsecureRule) ([]Issue, error) {
+	var issues []Issue
+
+	for _, rule := range rules {
+		if !appliesTo(rule, environment) {
+			continue
+		}
+
+		var re *regexp.Regexp
+		if rule.Matches != "" {
+			var err error
+			re, err = regexp.Compile(rule.Matches)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern in rule %s: %w", rule.ID, err)
+			}
+		}
+
+		for _, envVar := range envVars {
+			if !matchesKey(rule.Keys, envVar.Key) || parser.IsSealed(envVar.Value) {
+				continue
+			}
+			value := parser.Unquote(envVar.Value)
+			if !matchesValue(rule, re, value) {
+				continue
+			}
+
+			severity := rule.Severity
+			if severity == "" {
+				severity = "warning"
+			}
+			issues = append(issues, Issue{
+				Rule:     rule.ID,
+				Key:      envVar.Key,
+				Value:    value,
+				Severity: severity,
+				Message:  rule.Message,
+			})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Key != issues[j].Key {
+			return issues[i].Key < issues[j].Key
+		}
+		return issues[i].Rule < issues[j].Rule
+	})
+	return issues, nil
+}
+
+// appliesTo reports whether the rule should run for the environment
+func appliesTo(rule config.InsecureRule, environment string) bool {
+	if len(rule.Environments) == 0 {
+		return true
+	}
+	for _, env := range rule.Environments {
+		if normalizeEnvironment(env) == environment {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesKey reports whether the key matches any of the glob patterns
+func matchesKey(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesValue reports whether the value triggers the rule
+func matchesValue(rule config.InsecureRule, re *regexp.Regexp, value string) bool {
+	if value == "" {
+		return rule.Empty
+	}
+	for _, candidate := range rule.Equals {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return re != nil && re.MatchString(value)
+}
@@ -0,0 +1,516 @@
+package journal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MayR-Labs/envdoc-go/internal/crypto"
+)
+
+// Dir is the journal directory, relative to the project root
+const Dir = ".envdoc/journal"
+
+// Default retention limits
+const (
+	DefaultMaxEntries = 50
+	DefaultMaxAge     = 30 * 24 * time.Hour
+)
+
+// KeyFile is the name of the snapshot key in the user's envdoc config directory
+const KeyFile = "journal.key"
+
+// version is the current entry file format
+const version = 1
+
+// ErrNotFound is returned when no entry matches
+var ErrNotFound = errors.New("no matching journal entry")
+
+// File is the state of one file before and after an operation. Contents are never stored
+// in plain text: the previous content is encrypted and hashes are keyed with the journal key.
+type File struct {
+	Path     string `json:"path"`
+	Existed  bool   `json:"existed"`
+	Exists   bool   `json:"exists"`
+	After    string `json:"after_hash,omitempty"`
+	Snapshot string `json:"snapshot,omitempty"`
+	// SnapshotFile is the file in the journal directory holding the encrypted previous
+	// content, for files that were snapshotted as a stream instead of in the entry
+	SnapshotFile string `json:"snapshot_file,omitempty"`
+}
+
+// Entry is a recorded operation
+type Entry struct {
+	Version  int       `json:"version"`
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Command  string    `json:"command"`
+	Files    []File    `json:"files"`
+	UndoOf   string    `json:"undo_of,omitempty"`
+	UndoneBy string    `json:"undone_by,omitempty"`
+}
+
+// Change is a file write about to happen. Exists is false when the write deletes the file.
+// The previous content is Before, or the content of BeforeFile, and the new content is
+// After, or the content of AfterFile. Files are read as streams, so large files are not
+// held in memory.
+type Change struct {
+	Path       string
+	Before     string
+	BeforeFile string
+	Existed    bool
+	After      string
+	AfterFile  string
+	Exists     bool
+}
+
+// Retention limits how many entries are kept, and for how long
+type Retention struct {
+	MaxEntries int
+	MaxAge     time.Duration
+}
+
+// Journal is the operation journal of a project
+type Journal struct {
+	Root      string
+	Retention Retention
+	key       []byte
+}
+
+// Open returns the journal of the project rooted at root
+func Open(root string, retention Retention) *Journal {
+	if retention.MaxEntries <= 0 {
+		retention.MaxEntries = DefaultMaxEntries
+	}
+	if retention.MaxAge <= 0 {
+		retention.MaxAge = DefaultMaxAge
+	}
+	return &Journal{Root: root, Retention: retention}
+}
+
+// Dir returns the journal directory
+func (j *Journal) Dir() string {
+	return filepath.Join(j.Root, Dir)
+}
+
+// Record snapshots the previous content of the changed files and returns the new entry.
+// Older entries beyond the retention limits are removed.
+func (j *Journal) Record(command, undoOf string, changes []Change) (*Entry, error) {
+	if err := j.loadKey(true); err != nil {
+		return nil, err
+	}
+	if err := j.ensureDir(); err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{Version: version, ID: newID(), Time: time.Now(), Command: command, UndoOf: undoOf}
+	files, err := j.record(entry.ID, changes)
+	if err != nil {
+		j.removeSnapshots(entry.ID)
+		return nil, err
+	}
+	entry.Files = files
+
+	if err := j.save(entry); err != nil {
+		j.removeSnapshots(entry.ID)
+		return nil, err
+	}
+	if err := j.Prune(time.Now()); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// record hashes and snapshots the changes of the entry with the given ID
+func (j *Journal) record(id string, changes []Change) ([]File, error) {
+	var files []File
+	for i, change := range changes {
+		path, err := filepath.Abs(change.Path)
+		if err != nil {
+			return nil, err
+		}
+		file := File{Path: path, Existed: change.Existed, Exists: change.Exists}
+		if change.Exists && change.AfterFile != "" {
+			if file.After, err = j.hashFile(change.AfterFile); err != nil {
+				return nil, err
+			}
+		} else if change.Exists {
+			file.After = j.hash(change.After)
+		}
+		switch {
+		case change.Existed && change.BeforeFile != "":
+			file.SnapshotFile = fmt.Sprintf("%s.%d.snapshot", id, i)
+			if err := j.sealFile(change.BeforeFile, file.SnapshotFile, id+"\x00"+path); err != nil {
+				return nil, err
+			}
+		case change.Existed:
+			file.Snapshot, err = j.seal([]byte(change.Before), id+"\x00"+path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// Entries returns the recorded entries, oldest first
+func (j *Journal) Entries() ([]Entry, error) {
+	matches, err := filepath.Glob(filepath.Join(j.Dir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journal entry: %w", err)
+		}
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", match, err)
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		if !entries[a].Time.Equal(entries[b].Time) {
+			return entries[a].Time.Before(entries[b].Time)
+		}
+		return entries[a].ID < entries[b].ID
+	})
+	return entries, nil
+}
+
+// Find returns the entry with the given ID or unique ID prefix. Without an ID it returns
+// the most recent operation that has not been undone, so repeated undos walk back in time.
+func (j *Journal) Find(id string) (*Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].UndoOf == "" && entries[i].UndoneBy == "" {
+				return &entries[i], nil
+			}
+		}
+		return nil, ErrNotFound
+	}
+
+	var found *Entry
+	for i := range entries {
+		if strings.HasPrefix(entries[i].ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("journal entry ID '%s' is ambiguous", id)
+			}
+			found = &entries[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+// Restore returns the content a file had before the operation
+func (j *Journal) Restore(entry *Entry, file File) (string, error) {
+	if !file.Existed {
+		return "", nil
+	}
+	if err := j.loadKey(false); err != nil {
+		return "", err
+	}
+	var content []byte
+	var err error
+	if file.SnapshotFile != "" {
+		content, err = j.openFile(file.SnapshotFile, entry.ID+"\x00"+file.Path)
+	} else {
+		content, err = j.open(file.Snapshot, entry.ID+"\x00"+file.Path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the snapshot of '%s': %w", file.Path, err)
+	}
+	return string(content), nil
+}
+
+// Modified reports whether a file no longer has the content the operation left behind
+func (j *Journal) Modified(file File) (bool, error) {
+	if _, err := os.Stat(file.Path); os.IsNotExist(err) {
+		return file.Exists, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !file.Exists {
+		return true, nil
+	}
+	if err := j.loadKey(false); err != nil {
+		return false, err
+	}
+	hash, err := j.hashFile(file.Path)
+	if err != nil {
+		return false, err
+	}
+	return !hmac.Equal([]byte(hash), []byte(file.After)), nil
+}
+
+// SetUndoneBy records which entry undid an entry; an empty undoneBy makes it undoable again
+func (j *Journal) SetUndoneBy(id, undoneBy string) error {
+	entry, err := j.Find(id)
+	if err == ErrNotFound {
+		// Pruned in the meantime
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	entry.UndoneBy = undoneBy
+	return j.save(entry)
+}
+
+// Prune removes entries beyond the retention limits
+func (j *Journal) Prune(now time.Time) error {
+	entries, err := j.Entries()
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		if len(entries)-i <= j.Retention.MaxEntries && now.Sub(entry.Time) <= j.Retention.MaxAge {
+			continue
+		}
+		if err := os.Remove(j.entryFile(entry.ID)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to prune journal entry: %w", err)
+		}
+		j.removeSnapshots(entry.ID)
+	}
+	return nil
+}
+
+// Remove removes an entry, for operations that were rolled back
+func (j *Journal) Remove(id string) error {
+	if err := os.Remove(j.entryFile(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove journal entry: %w", err)
+	}
+	j.removeSnapshots(id)
+	return nil
+}
+
+// Clear removes every entry
+func (j *Journal) Clear() error {
+	entries, err := j.Entries()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.Remove(j.entryFile(entry.ID)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove journal entry: %w", err)
+		}
+		j.removeSnapshots(entry.ID)
+	}
+	return nil
+}
+
+func (j *Journal) entryFile(id string) string {
+	return filepath.Join(j.Dir(), id+".json")
+}
+
+// removeSnapshots removes the snapshot files of an entry
+func (j *Journal) removeSnapshots(id string) {
+	matches, _ := filepath.Glob(filepath.Join(j.Dir(), id+".*.snapshot"))
+	for _, match := range matches {
+		_ = os.Remove(match)
+	}
+}
+
+func (j *Journal) save(entry *Entry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(j.entryFile(entry.ID), append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
+
+// ensureDir creates the journal directory with a .gitignore so it is never committed
+func (j *Journal) ensureDir() error {
+	if err := os.MkdirAll(j.Dir(), 0700); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	ignore := filepath.Join(j.Dir(), ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := os.WriteFile(ignore, []byte("*\n"), 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", ignore, err)
+		}
+	}
+	return nil
+}
+
+// KeyPath returns the location of the snapshot key. It lives outside the project, so a
+// copied or committed journal does not reveal the snapshots.
+func KeyPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the user config directory: %w", err)
+	}
+	return filepath.Join(dir, "envdoc", KeyFile), nil
+}
+
+// loadKey reads the snapshot key, generating it on first use when create is set
+func (j *Journal) loadKey(create bool) error {
+	if j.key != nil {
+		return nil
+	}
+	path, err := KeyPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && create {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return fmt.Errorf("failed to generate journal key: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+			return fmt.Errorf("failed to write journal key: %w", err)
+		}
+		j.key = key
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read journal key %s: %w", path, err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return fmt.Errorf("invalid journal key in %s", path)
+	}
+	j.key = key
+	return nil
+}
+
+// hash returns a keyed hash of content, so hashes of short files cannot be brute-forced
+func (j *Journal) hash(content string) string {
+	mac := hmac.New(sha256.New, j.key)
+	mac.Write([]byte(content))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// hashFile returns the keyed hash of the content of a file, like hash
+func (j *Journal) hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	mac := hmac.New(sha256.New, j.key)
+	if _, err := io.Copy(mac, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// seal encrypts data with AES-256-GCM, binding it to the entry and path
+func (j *Journal) seal(data []byte, context string) (string, error) {
+	gcm, err := j.cipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, data, []byte(context))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// sealFile encrypts the content of src as a stream into the snapshot file name, binding it
+// to the entry and path like seal
+func (j *Journal) sealFile(src, name, context string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(filepath.Join(j.Dir(), name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write journal snapshot: %w", err)
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	w, err := crypto.NewKeyWriter(out, j.key, []byte(context))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// openFile decrypts a snapshot file
+func (j *Journal) openFile(name, context string) ([]byte, error) {
+	in, err := os.Open(filepath.Join(j.Dir(), name))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = in.Close() }()
+	r, err := crypto.NewKeyReader(in, j.key, []byte(context))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// open decrypts a sealed snapshot
+func (j *Journal) open(sealed, context string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := j.cipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("snapshot is too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(context))
+}
+
+func (j *Journal) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(j.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newID returns a sortable entry ID: the UTC time plus a random suffix
+func newID() string {
+	suffix := make([]byte, 2)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
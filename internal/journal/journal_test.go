@@ -0,0 +1,317 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testJournal returns a journal in a temporary project, with the key in a temporary
+// config directory
+func testJournal(t *testing.T, retention Retention) (*Journal, string) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	return Open(root, retention), root
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func record(t *testing.T, j *Journal, command string, changes ...Change) *Entry {
+	t.Helper()
+	entry, err := j.Record(command, "", changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func snapshots(t *testing.T, j *Journal) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(j.Dir(), "*.snapshot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestRecordAndRestore(t *testing.T) {
+	j, root := testJournal(t, Retention{})
+	env := filepath.Join(root, ".env")
+	large := filepath.Join(root, ".env.large")
+	created := filepath.Join(root, ".env.new")
+	deleted := filepath.Join(root, ".env.old")
+
+	// The large file is snapshotted from a copy of its previous content
+	previous := filepath.Join(root, "previous")
+	writeFile(t, previous, "BIG=before\n")
+	writeFile(t, env, "A=2\n")
+	writeFile(t, large, "BIG=after\n")
+	writeFile(t, created, "NEW=1\n")
+
+	entry := record(t, j, "set A 2",
+		Change{Path: env, Before: "A=1\n", Existed: true, After: "A=2\n", Exists: true},
+		Change{Path: large, BeforeFile: previous, Existed: true, AfterFile: large, Exists: true},
+		Change{Path: created, After: "NEW=1\n", Exists: true},
+		Change{Path: deleted, Before: "OLD=1\n", Existed: true},
+	)
+	if len(entry.Files) != 4 {
+		t.Fatalf("%d files recorded", len(entry.Files))
+	}
+	if entry.Files[0].Snapshot == "" || entry.Files[1].SnapshotFile == "" {
+		t.Fatalf("snapshots missing: %+v", entry.Files)
+	}
+
+	// Nothing is stored in plain text
+	data, err := os.ReadFile(j.entryFile(entry.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := os.ReadFile(filepath.Join(j.Dir(), entry.Files[1].SnapshotFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"A=1", "A=2", "OLD=1", "NEW=1"} {
+		if strings.Contains(string(data), content) {
+			t.Errorf("entry contains %q", content)
+		}
+	}
+	if strings.Contains(string(snapshot), "BIG=before") {
+		t.Error("snapshot file is not encrypted")
+	}
+
+	found, err := j.Find(entry.ID[:10])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"A=1\n", "BIG=before\n", "", "OLD=1\n"}
+	for i, file := range found.Files {
+		content, err := j.Restore(found, file)
+		if err != nil {
+			t.Fatalf("%s: %v", file.Path, err)
+		}
+		if content != want[i] {
+			t.Errorf("%s restored as %q, want %q", file.Path, content, want[i])
+		}
+	}
+}
+
+func TestModified(t *testing.T) {
+	j, root := testJournal(t, Retention{})
+	env := filepath.Join(root, ".env")
+	removed := filepath.Join(root, ".env.old")
+	writeFile(t, env, "A=2\n")
+
+	entry := record(t, j, "set A 2",
+		Change{Path: env, Before: "A=1\n", Existed: true, After: "A=2\n", Exists: true},
+		Change{Path: removed, Before: "OLD=1\n", Existed: true},
+	)
+
+	for _, file := range entry.Files {
+		if modified, err := j.Modified(file); err != nil || modified {
+			t.Errorf("%s: Modified = %v, %v right after the operation", file.Path, modified, err)
+		}
+	}
+
+	writeFile(t, env, "A=3\n")
+	writeFile(t, removed, "OLD=2\n")
+	for _, file := range entry.Files {
+		if modified, err := j.Modified(file); err != nil || !modified {
+			t.Errorf("%s: Modified = %v, %v after a later change", file.Path, modified, err)
+		}
+	}
+
+	if err := os.Remove(env); err != nil {
+		t.Fatal(err)
+	}
+	if modified, err := j.Modified(entry.Files[0]); err != nil || !modified {
+		t.Errorf("deleted file: Modified = %v, %v", modified, err)
+	}
+}
+
+func TestRestoreRejectsTampering(t *testing.T) {
+	j, root := testJournal(t, Retention{})
+	env := filepath.Join(root, ".env")
+	previous := filepath.Join(root, "previous")
+	writeFile(t, previous, "A=1\n")
+	entry := record(t, j, "set",
+		Change{Path: env, Before: "A=1\n", Existed: true, After: "A=2\n", Exists: true},
+		Change{Path: env + ".large", BeforeFile: previous, Existed: true, After: "A=2\n", Exists: true},
+	)
+
+	// Snapshots are bound to their entry and path
+	moved := entry.Files[0]
+	moved.Path = filepath.Join(root, ".env.other")
+	if _, err := j.Restore(entry, moved); err == nil {
+		t.Error("snapshot restored for another path")
+	}
+	other := *entry
+	other.ID = "20000101-000000-0000"
+	if _, err := j.Restore(&other, entry.Files[0]); err == nil {
+		t.Error("snapshot restored for another entry")
+	}
+
+	modified := entry.Files[0]
+	modified.Snapshot = modified.Snapshot[:len(modified.Snapshot)-8] + "AAAAAAA="
+	if _, err := j.Restore(entry, modified); err == nil {
+		t.Error("modified snapshot was restored")
+	}
+
+	snapshotFile := filepath.Join(j.Dir(), entry.Files[1].SnapshotFile)
+	data, err := os.ReadFile(snapshotFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 1
+	writeFile(t, snapshotFile, string(data))
+	if _, err := j.Restore(entry, entry.Files[1]); err == nil {
+		t.Error("modified snapshot file was restored")
+	}
+
+	// Without the key, which lives outside the project, nothing can be restored
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if _, err := Open(root, Retention{}).Restore(entry, entry.Files[0]); err == nil {
+		t.Error("snapshot restored without the journal key")
+	}
+}
+
+func TestFindWalksBackThroughUndos(t *testing.T) {
+	j, root := testJournal(t, Retention{})
+	env := filepath.Join(root, ".env")
+	change := Change{Path: env, Before: "A=1\n", Existed: true, After: "A=2\n", Exists: true}
+
+	first := record(t, j, "first", change)
+	second := record(t, j, "second", change)
+
+	latest, err := j.Find("")
+	if err != nil || latest.ID != second.ID {
+		t.Fatalf("Find(\"\") = %v, %v, want %s", latest, err, second.ID)
+	}
+
+	undo, err := j.Record("undo", second.ID, []Change{change})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.SetUndoneBy(second.ID, undo.ID); err != nil {
+		t.Fatal(err)
+	}
+	latest, err = j.Find("")
+	if err != nil || latest.ID != first.ID {
+		t.Fatalf("after undo Find(\"\") = %v, %v, want %s", latest, err, first.ID)
+	}
+
+	if err := j.SetUndoneBy(second.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if latest, err = j.Find(""); err != nil || latest.ID != second.ID {
+		t.Errorf("after redo Find(\"\") = %v, %v, want %s", latest, err, second.ID)
+	}
+
+	if _, err := j.Find("nonexistent"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	if err := j.SetUndoneBy("nonexistent", undo.ID); err != nil {
+		t.Errorf("SetUndoneBy of a pruned entry: %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	j, root := testJournal(t, Retention{MaxEntries: 2, MaxAge: time.Hour})
+	previous := filepath.Join(root, "previous")
+	writeFile(t, previous, "A=1\n")
+	change := Change{Path: filepath.Join(root, ".env"), BeforeFile: previous, Existed: true, After: "A=2\n", Exists: true}
+
+	var ids []string
+	for range 3 {
+		ids = append(ids, record(t, j, "set", change).ID)
+	}
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d entries kept, want 2", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.ID == ids[0] {
+			t.Error("oldest entry was kept")
+		}
+	}
+	if got := len(snapshots(t, j)); got != 2 {
+		t.Errorf("%d snapshot files left, want 2", got)
+	}
+
+	if err := j.Prune(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := j.Entries(); len(entries) != 0 {
+		t.Errorf("%d expired entries kept", len(entries))
+	}
+	if got := len(snapshots(t, j)); got != 0 {
+		t.Errorf("%d snapshot files left after pruning everything", got)
+	}
+}
+
+func TestRemoveAndClear(t *testing.T) {
+	j, root := testJournal(t, Retention{})
+	previous := filepath.Join(root, "previous")
+	writeFile(t, previous, "A=1\n")
+	change := Change{Path: filepath.Join(root, ".env"), BeforeFile: previous, Existed: true, After: "A=2\n", Exists: true}
+
+	first := record(t, j, "first", change)
+	record(t, j, "second", change)
+	record(t, j, "third", change)
+
+	if err := j.Remove(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Find(first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("removed entry found: %v", err)
+	}
+	if got := len(snapshots(t, j)); got != 2 {
+		t.Errorf("%d snapshot files left, want 2", got)
+	}
+
+	if err := j.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := j.Entries(); len(entries) != 0 {
+		t.Errorf("%d entries left after Clear", len(entries))
+	}
+	if got := len(snapshots(t, j)); got != 0 {
+		t.Errorf("%d snapshot files left after Clear", got)
+	}
+	// The directory stays ignored by git
+	if _, err := os.Stat(filepath.Join(j.Dir(), ".gitignore")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKeyIsPrivate(t *testing.T) {
+	j, root := testJournal(t, Retention{})
+	record(t, j, "set", Change{Path: filepath.Join(root, ".env"), After: "A=1\n", Exists: true})
+
+	path, err := KeyPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(path, root) {
+		t.Errorf("key %s is inside the project", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key mode %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,380 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source is where a container variable gets its value
+type Source string
+
+const (
+	Literal   Source = "value"
+	ConfigMap Source = "configmap"
+	Secret    Source = "secret"
+	Field     Source = "field"
+	Resource  Source = "resource"
+)
+
+// Variable is an environment variable a container receives
+type Variable struct {
+	Name   string
+	Source Source
+	// Ref is the ConfigMap or Secret name
+	Ref string
+	// Key is the ConfigMap or Secret key
+	Key      string
+	Optional bool
+	// FromEnvFrom is true when the variable was expanded from an envFrom block
+	FromEnvFrom bool
+}
+
+// EnvFromRef is an envFrom block whose ConfigMap or Secret is not in the manifests,
+// so the variables it provides are unknown
+type EnvFromRef struct {
+	Source   Source
+	Ref      string
+	Prefix   string
+	Optional bool
+}
+
+// Container is a container or init container of a workload
+type Container struct {
+	// Workload is Kind/name of the object the container belongs to
+	Workload   string
+	Name       string
+	Init       bool
+	File       string
+	Line       int
+	Variables  []Variable
+	Unresolved []EnvFromRef
+}
+
+// Receives reports whether the container receives a variable from env or a resolved envFrom block
+func (c Container) Receives(name string) bool {
+	for _, v := range c.Variables {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Manifests is the parsed content of a set of manifest files
+type Manifests struct {
+	Files      []string
+	Containers []Container
+	// ConfigMaps and Secrets map object names to their keys
+	ConfigMaps map[string]map[string]bool
+	Secrets    map[string]map[string]bool
+}
+
+// knownKinds are the object kinds that provide or consume environment variables
+var knownKinds = map[string]bool{
+	"List": true, "ConfigMap": true, "Secret": true, "Pod": true, "Deployment": true, "StatefulSet": true,
+	"DaemonSet": true, "ReplicaSet": true, "ReplicationController": true, "Job": true, "CronJob": true,
+}
+
+// object holds the parts of a Kubernetes object envdoc reads
+type object struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+	BinaryData map[string]string `yaml:"binaryData"`
+	StringData map[string]string `yaml:"stringData"`
+	Items      []yaml.Node       `yaml:"items"`
+	Spec       struct {
+		podSpec  `yaml:",inline"`
+		Template struct {
+			Spec podSpec `yaml:"spec"`
+		} `yaml:"template"`
+		JobTemplate struct {
+			Spec struct {
+				Template struct {
+					Spec podSpec `yaml:"spec"`
+				} `yaml:"template"`
+			} `yaml:"spec"`
+		} `yaml:"jobTemplate"`
+	} `yaml:"spec"`
+}
+
+type podSpec struct {
+	Containers     []container `yaml:"containers"`
+	InitContainers []container `yaml:"initContainers"`
+}
+
+type container struct {
+	Name    string    `yaml:"name"`
+	Env     []envVar  `yaml:"env"`
+	EnvFrom []envFrom `yaml:"envFrom"`
+	// line is where the container starts in its file
+	line int
+}
+
+// UnmarshalYAML decodes a container and records its line
+func (c *container) UnmarshalYAML(node *yaml.Node) error {
+	type plain container
+	if err := node.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	c.line = node.Line
+	return nil
+}
+
+type envVar struct {
+	Name      string `yaml:"name"`
+	Value     string `yaml:"value"`
+	ValueFrom *struct {
+		ConfigMapKeyRef  *keyRef   `yaml:"configMapKeyRef"`
+		SecretKeyRef     *keyRef   `yaml:"secretKeyRef"`
+		FieldRef         *struct{} `yaml:"fieldRef"`
+		ResourceFieldRef *struct{} `yaml:"resourceFieldRef"`
+	} `yaml:"valueFrom"`
+}
+
+type keyRef struct {
+	Name     string `yaml:"name"`
+	Key      string `yaml:"key"`
+	Optional bool   `yaml:"optional"`
+}
+
+type envFrom struct {
+	Prefix       string     `yaml:"prefix"`
+	ConfigMapRef *objectRef `yaml:"configMapRef"`
+	SecretRef    *objectRef `yaml:"secretRef"`
+}
+
+type objectRef struct {
+	Name     string `yaml:"name"`
+	Optional bool   `yaml:"optional"`
+}
+
+// pendingContainer is a container whose envFrom blocks are resolved once every document is read
+type pendingContainer struct {
+	Container
+	envFrom []envFrom
+}
+
+// Load reads manifests from files and directories (every .yaml and .yml file below them).
+// "-" reads from standard input, e.g. rendered Helm or kustomize output.
+func Load(paths []string, stdin io.Reader) (*Manifests, error) {
+	m := &Manifests{ConfigMaps: make(map[string]map[string]bool), Secrets: make(map[string]map[string]bool)}
+	var pending []pendingContainer
+
+	for _, path := range paths {
+		if path == "-" {
+			m.Files = append(m.Files, "-")
+			if err := m.decode("-", stdin, &pending); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		files, err := manifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s: %w", file, err)
+			}
+			err = m.decode(file, f, &pending)
+			closeErr := f.Close()
+			if err != nil {
+				return nil, err
+			}
+			if closeErr != nil {
+				return nil, closeErr
+			}
+			m.Files = append(m.Files, file)
+		}
+	}
+
+	for _, p := range pending {
+		m.Containers = append(m.Containers, m.resolve(p))
+	}
+	return m, nil
+}
+
+// manifestFiles expands a path to the YAML files it contains
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		if !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// decode reads every document of a multi-document YAML stream
+func (m *Manifests) decode(file string, r io.Reader, pending *[]pendingContainer) error {
+	decoder := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		if err := m.addObject(file, doc.Content[0], pending); err != nil {
+			return err
+		}
+	}
+}
+
+// addObject records a single object, descending into List items
+func (m *Manifests) addObject(file string, node *yaml.Node, pending *[]pendingContainer) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	// Only decode the kinds envdoc reads, custom resources may use any shape
+	var header struct {
+		Kind string `yaml:"kind"`
+	}
+	if err := node.Decode(&header); err != nil || !knownKinds[header.Kind] {
+		return nil
+	}
+	var obj object
+	if err := node.Decode(&obj); err != nil {
+		return fmt.Errorf("failed to parse %s (line %d): %w", file, node.Line, err)
+	}
+
+	switch obj.Kind {
+	case "List":
+		for i := range obj.Items {
+			if err := m.addObject(file, &obj.Items[i], pending); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "ConfigMap":
+		m.ConfigMaps[obj.Metadata.Name] = keysOf(obj.Data, obj.BinaryData)
+		return nil
+	case "Secret":
+		m.Secrets[obj.Metadata.Name] = keysOf(obj.Data, obj.StringData)
+		return nil
+	}
+
+	var spec podSpec
+	switch obj.Kind {
+	case "Pod":
+		spec = obj.Spec.podSpec
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		spec = obj.Spec.Template.Spec
+	case "CronJob":
+		spec = obj.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return nil
+	}
+
+	workload := obj.Kind + "/" + obj.Metadata.Name
+	for i, c := range append(spec.InitContainers, spec.Containers...) {
+		p := pendingContainer{
+			Container: Container{Workload: workload, Name: c.Name, Init: i < len(spec.InitContainers), File: file, Line: c.line},
+			envFrom:   c.EnvFrom,
+		}
+		for _, env := range c.Env {
+			p.Variables = append(p.Variables, variableOf(env))
+		}
+		*pending = append(*pending, p)
+	}
+	return nil
+}
+
+// resolve expands the envFrom blocks of a container using the ConfigMaps and Secrets found
+func (m *Manifests) resolve(p pendingContainer) Container {
+	c := p.Container
+	var expanded []Variable
+	for _, from := range p.envFrom {
+		source, ref, objects := ConfigMap, from.ConfigMapRef, m.ConfigMaps
+		if from.SecretRef != nil {
+			source, ref, objects = Secret, from.SecretRef, m.Secrets
+		}
+		if ref == nil {
+			continue
+		}
+		keys, ok := objects[ref.Name]
+		if !ok {
+			c.Unresolved = append(c.Unresolved, EnvFromRef{Source: source, Ref: ref.Name, Prefix: from.Prefix, Optional: ref.Optional})
+			continue
+		}
+		for _, key := range sortedKeys(keys) {
+			expanded = append(expanded, Variable{Name: from.Prefix + key, Source: source, Ref: ref.Name, Key: key, Optional: ref.Optional, FromEnvFrom: true})
+		}
+	}
+	// Explicit env entries take precedence over envFrom
+	c.Variables = append(expanded, c.Variables...)
+	return c
+}
+
+// variableOf converts an env entry
+func variableOf(env envVar) Variable {
+	v := Variable{Name: env.Name, Source: Literal}
+	if env.ValueFrom == nil {
+		return v
+	}
+	switch {
+	case env.ValueFrom.ConfigMapKeyRef != nil:
+		ref := env.ValueFrom.ConfigMapKeyRef
+		v.Source, v.Ref, v.Key, v.Optional = ConfigMap, ref.Name, ref.Key, ref.Optional
+	case env.ValueFrom.SecretKeyRef != nil:
+		ref := env.ValueFrom.SecretKeyRef
+		v.Source, v.Ref, v.Key, v.Optional = Secret, ref.Name, ref.Key, ref.Optional
+	case env.ValueFrom.FieldRef != nil:
+		v.Source = Field
+	case env.ValueFrom.ResourceFieldRef != nil:
+		v.Source = Resource
+	}
+	return v
+}
+
+// keysOf merges the keys of data maps
+func keysOf(maps ...map[string]string) map[string]bool {
+	keys := make(map[string]bool)
+	for _, data := range maps {
+		for key := range data {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
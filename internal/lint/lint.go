@@ -0,0 +1,237 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Severity is the importance of a lint issue
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Info    Severity = "info"
+	Off     Severity = "off"
+)
+
+// disableNextLine is the inline comment that silences rules for the following line
+const disableNextLine = "envdoc-disable-next-line"
+
+// Issue represents a single lint problem
+type Issue struct {
+	Rule     string
+	Severity Severity
+	Line     int // 1-based, 0 for file-level issues
+	Key      string
+	Message  string
+	Fixable  bool
+}
+
+// Rule is a single lint check with an optional autofix
+type Rule struct {
+	ID          string
+	Description string
+	Severity    Severity
+	Check       func(doc *Document) []Issue
+	Fix         func(doc *Document, issue Issue) bool // nil when the rule has no autofix
+}
+
+// Line is a parsed line of an env file
+type Line struct {
+	Text    string
+	Comment bool
+	Blank   bool
+	Key     string // raw key as written, including surrounding spaces
+	Value   string // raw value as written, including surrounding spaces
+	Export  bool
+	HasPair bool
+}
+
+// Document is an env file held as raw lines so it can be checked and fixed without losing formatting
+type Document struct {
+	Lines        []Line
+	FinalNewline bool
+}
+
+// ParseDocument splits file content into lines
+func ParseDocument(content string) *Document {
+	doc := &Document{FinalNewline: content == "" || strings.HasSuffix(content, "\n")}
+	content = strings.TrimSuffix(content, "\n")
+	if content == "" {
+		return doc
+	}
+	for _, text := range strings.Split(content, "\n") {
+		doc.Lines = append(doc.Lines, parseLine(strings.TrimSuffix(text, "\r")))
+	}
+	return doc
+}
+
+// LoadDocument reads and parses an env file
+func LoadDocument(filename string) (*Document, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return ParseDocument(string(data)), nil
+}
+
+// parseLine classifies a single line
+func parseLine(text string) Line {
+	line := Line{Text: text}
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case trimmed == "":
+		line.Blank = true
+	case strings.HasPrefix(trimmed, "#"):
+		line.Comment = true
+	default:
+		body := strings.TrimLeft(text, " \t")
+		if strings.HasPrefix(body, "export ") {
+			line.Export = true
+			body = strings.TrimPrefix(body, "export ")
+		}
+		if parts := strings.SplitN(body, "=", 2); len(parts) == 2 {
+			line.HasPair = true
+			line.Key = parts[0]
+			line.Value = parts[1]
+		}
+	}
+	return line
+}
+
+// setLine replaces the text of a line and re-parses it
+func (d *Document) setLine(index int, text string) {
+	d.Lines[index] = parseLine(text)
+}
+
+// String renders the document back to file content
+func (d *Document) String() string {
+	var sb strings.Builder
+	for i, line := range d.Lines {
+		sb.WriteString(line.Text)
+		if i < len(d.Lines)-1 || d.FinalNewline {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// Linter runs a configured set of rules
+type Linter struct {
+	rules []Rule
+}
+
+// New returns a linter with the built-in rules, applying severity overrides by rule ID.
+// An override of "off" disables the rule.
+func New(overrides map[string]string) (*Linter, error) {
+	var rules []Rule
+	known := make(map[string]bool)
+	for _, rule := range Rules() {
+		known[rule.ID] = true
+		if override, ok := overrides[rule.ID]; ok {
+			severity := Severity(strings.ToLower(override))
+			switch severity {
+			case Error, Warning, Info:
+				rule.Severity = severity
+			case Off:
+				continue
+			default:
+				return nil, fmt.Errorf("invalid severity %q for lint rule %s", override, rule.ID)
+			}
+		}
+		if rule.Severity == Off {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	for id := range overrides {
+		if !known[id] {
+			return nil, fmt.Errorf("unknown lint rule: %s", id)
+		}
+	}
+	return &Linter{rules: rules}, nil
+}
+
+// Lint checks the document and returns the issues that are not disabled inline
+func (l *Linter) Lint(doc *Document) []Issue {
+	disabled := disabledRules(doc)
+	var issues []Issue
+	for _, rule := range l.rules {
+		for _, issue := range rule.Check(doc) {
+			issue.Rule = rule.ID
+			if isDisabled(disabled, issue) {
+				continue
+			}
+			issue.Severity = rule.Severity
+			issue.Fixable = rule.Fix != nil
+			issues = append(issues, issue)
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
+
+// Fix applies every available autofix and returns the number of issues fixed
+func (l *Linter) Fix(doc *Document) int {
+	fixed := 0
+	disabled := disabledRules(doc)
+	for _, rule := range l.rules {
+		if rule.Fix == nil {
+			continue
+		}
+		for _, issue := range rule.Check(doc) {
+			issue.Rule = rule.ID
+			if isDisabled(disabled, issue) {
+				continue
+			}
+			if rule.Fix(doc, issue) {
+				fixed++
+			}
+		}
+	}
+	return fixed
+}
+
+// disabledRules maps 1-based line numbers to the rules disabled for them. An empty rule list disables all rules.
+func disabledRules(doc *Document) map[int][]string {
+	disabled := make(map[int][]string)
+	for i, line := range doc.Lines {
+		if !line.Comment {
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line.Text), "#"))
+		if !strings.HasPrefix(text, disableNextLine) {
+			continue
+		}
+		ids := strings.FieldsFunc(strings.TrimPrefix(text, disableNextLine), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if ids == nil {
+			ids = []string{}
+		}
+		disabled[i+2] = ids
+	}
+	return disabled
+}
+
+// isDisabled reports whether an issue is silenced by an inline comment
+func isDisabled(disabled map[int][]string, issue Issue) bool {
+	ids, ok := disabled[issue.Line]
+	if !ok {
+		return false
+	}
+	if len(ids) == 0 {
+		return true
+	}
+	for _, id := range ids {
+		if id == issue.Rule {
+			return true
+		}
+	}
+	return false
+}
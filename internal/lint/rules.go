@@ -0,0 +1,328 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var upperSnakeCase = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// Rules returns the built-in lint rules with their default severities
+func Rules() []Rule {
+	return []Rule{
+		{
+			ID:          "invalid-line",
+			Description: "Line is not a comment, blank line or KEY=value pair",
+			Severity:    Error,
+			Check:       checkInvalidLine,
+		},
+		{
+			ID:          "key-case",
+			Description: "Keys should be UPPER_SNAKE_CASE",
+			Severity:    Warning,
+			Check:       checkKeyCase,
+			Fix:         fixKeyCase,
+		},
+		{
+			ID:          "duplicate-key",
+			Description: "Keys should only be defined once",
+			Severity:    Error,
+			Check:       checkDuplicateKey,
+		},
+		{
+			ID:          "spaces-around-equals",
+			Description: "There should be no spaces around =",
+			Severity:    Warning,
+			Check:       checkSpacesAroundEquals,
+			Fix:         fixSpacesAroundEquals,
+		},
+		{
+			ID:          "leading-whitespace",
+			Description: "Lines should not be indented",
+			Severity:    Warning,
+			Check:       checkLeadingWhitespace,
+			Fix:         fixLeadingWhitespace,
+		},
+		{
+			ID:          "trailing-whitespace",
+			Description: "Lines should not end with whitespace",
+			Severity:    Warning,
+			Check:       checkTrailingWhitespace,
+			Fix:         fixTrailingWhitespace,
+		},
+		{
+			ID:          "unquoted-spaces",
+			Description: "Values containing spaces should be quoted",
+			Severity:    Error,
+			Check:       checkUnquotedSpaces,
+			Fix:         fixUnquotedSpaces,
+		},
+		{
+			ID:          "quote-style",
+			Description: "Quoted values should use one quote style consistently",
+			Severity:    Warning,
+			Check:       checkQuoteStyle,
+			Fix:         fixQuoteStyle,
+		},
+		{
+			ID:          "final-newline",
+			Description: "Files should end with a newline",
+			Severity:    Warning,
+			Check:       checkFinalNewline,
+			Fix:         fixFinalNewline,
+		},
+		{
+			ID:          "missing-comment",
+			Description: "Every key should have a comment describing it",
+			Severity:    Off,
+			Check:       checkMissingComment,
+		},
+	}
+}
+
+// pairs calls fn for every KEY=value line with its index
+func pairs(doc *Document, fn func(index int, line Line)) {
+	for i, line := range doc.Lines {
+		if line.HasPair {
+			fn(i, line)
+		}
+	}
+}
+
+// rebuild renders a key/value line from its parts
+func rebuild(line Line, key, value string) string {
+	prefix := ""
+	if line.Export {
+		prefix = "export "
+	}
+	return prefix + key + "=" + value
+}
+
+func checkInvalidLine(doc *Document) []Issue {
+	var issues []Issue
+	for i, line := range doc.Lines {
+		if !line.Blank && !line.Comment && !line.HasPair {
+			issues = append(issues, Issue{Line: i + 1, Message: fmt.Sprintf("Cannot parse line: %s", strings.TrimSpace(line.Text))})
+		}
+	}
+	return issues
+}
+
+func checkKeyCase(doc *Document) []Issue {
+	var issues []Issue
+	pairs(doc, func(i int, line Line) {
+		key := strings.TrimSpace(line.Key)
+		if !upperSnakeCase.MatchString(key) {
+			issues = append(issues, Issue{Line: i + 1, Key: key, Message: fmt.Sprintf("Key %s is not UPPER_SNAKE_CASE", key)})
+		}
+	})
+	return issues
+}
+
+func fixKeyCase(doc *Document, issue Issue) bool {
+	line := doc.Lines[issue.Line-1]
+	key := strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, strings.TrimSpace(line.Key))
+	if key == "" {
+		return false
+	}
+	leading := line.Key[:len(line.Key)-len(strings.TrimLeft(line.Key, " \t"))]
+	trailing := line.Key[len(strings.TrimRight(line.Key, " \t")):]
+	doc.setLine(issue.Line-1, leadingIndent(line.Text)+rebuild(line, leading+key+trailing, line.Value))
+	return true
+}
+
+func checkDuplicateKey(doc *Document) []Issue {
+	var issues []Issue
+	seen := make(map[string]int)
+	pairs(doc, func(i int, line Line) {
+		key := strings.TrimSpace(line.Key)
+		if first, ok := seen[key]; ok {
+			issues = append(issues, Issue{Line: i + 1, Key: key, Message: fmt.Sprintf("Key %s is already defined on line %d", key, first)})
+			return
+		}
+		seen[key] = i + 1
+	})
+	return issues
+}
+
+func checkSpacesAroundEquals(doc *Document) []Issue {
+	var issues []Issue
+	pairs(doc, func(i int, line Line) {
+		if strings.TrimRight(line.Key, " \t") != line.Key || (line.Value != "" && strings.TrimLeft(line.Value, " \t") != line.Value) {
+			key := strings.TrimSpace(line.Key)
+			issues = append(issues, Issue{Line: i + 1, Key: key, Message: fmt.Sprintf("Spaces around = for %s", key)})
+		}
+	})
+	return issues
+}
+
+func fixSpacesAroundEquals(doc *Document, issue Issue) bool {
+	line := doc.Lines[issue.Line-1]
+	doc.setLine(issue.Line-1, leadingIndent(line.Text)+rebuild(line, strings.TrimRight(line.Key, " \t"), strings.TrimLeft(line.Value, " \t")))
+	return true
+}
+
+func checkLeadingWhitespace(doc *Document) []Issue {
+	var issues []Issue
+	pairs(doc, func(i int, line Line) {
+		if leadingIndent(line.Text) != "" {
+			key := strings.TrimSpace(line.Key)
+			issues = append(issues, Issue{Line: i + 1, Key: key, Message: fmt.Sprintf("Line for %s is indented", key)})
+		}
+	})
+	return issues
+}
+
+func fixLeadingWhitespace(doc *Document, issue Issue) bool {
+	doc.setLine(issue.Line-1, strings.TrimLeft(doc.Lines[issue.Line-1].Text, " \t"))
+	return true
+}
+
+func checkTrailingWhitespace(doc *Document) []Issue {
+	var issues []Issue
+	for i, line := range doc.Lines {
+		if !line.Blank && strings.TrimRight(line.Text, " \t") != line.Text {
+			issues = append(issues, Issue{Line: i + 1, Key: strings.TrimSpace(line.Key), Message: "Line ends with whitespace"})
+		}
+	}
+	return issues
+}
+
+func fixTrailingWhitespace(doc *Document, issue Issue) bool {
+	doc.setLine(issue.Line-1, strings.TrimRight(doc.Lines[issue.Line-1].Text, " \t"))
+	return true
+}
+
+// quoteOf returns the quote character wrapping a value, or 0
+func quoteOf(value string) byte {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[0]
+	}
+	return 0
+}
+
+// splitInlineComment splits a raw value into the value without surrounding whitespace and an
+// inline comment: a # after whitespace outside quotes, returned with the whitespace before it
+func splitInlineComment(raw string) (string, string) {
+	value := strings.TrimSpace(raw)
+	if strings.HasPrefix(value, "#") && value != raw && !strings.HasPrefix(raw, "#") {
+		// Only a comment after the =
+		return "", value
+	}
+	start := 0
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			start = end + 2
+		}
+	}
+	for i := start; i < len(value); i++ {
+		if value[i] == '#' && i > 0 && (value[i-1] == ' ' || value[i-1] == '\t') {
+			before := strings.TrimRight(value[:i], " \t")
+			return before, value[len(before):]
+		}
+	}
+	return value, ""
+}
+
+func checkUnquotedSpaces(doc *Document) []Issue {
+	var issues []Issue
+	pairs(doc, func(i int, line Line) {
+		value, _ := splitInlineComment(line.Value)
+		if quoteOf(value) == 0 && strings.ContainsAny(value, " \t") {
+			key := strings.TrimSpace(line.Key)
+			issues = append(issues, Issue{Line: i + 1, Key: key, Message: fmt.Sprintf("Value of %s contains spaces but is not quoted", key)})
+		}
+	})
+	return issues
+}
+
+func fixUnquotedSpaces(doc *Document, issue Issue) bool {
+	line := doc.Lines[issue.Line-1]
+	value, comment := splitInlineComment(line.Value)
+	if strings.ContainsAny(value, "\"\\") {
+		return false
+	}
+	doc.setLine(issue.Line-1, leadingIndent(line.Text)+rebuild(line, line.Key, "\""+value+"\""+comment))
+	return true
+}
+
+// preferredQuote returns the quote style used by most quoted values, preferring double quotes on a tie
+func preferredQuote(doc *Document) byte {
+	counts := map[byte]int{}
+	pairs(doc, func(_ int, line Line) {
+		if q := quoteOf(line.Value); q != 0 {
+			counts[q]++
+		}
+	})
+	if counts['\''] > counts['"'] {
+		return '\''
+	}
+	return '"'
+}
+
+func checkQuoteStyle(doc *Document) []Issue {
+	var issues []Issue
+	preferred := preferredQuote(doc)
+	pairs(doc, func(i int, line Line) {
+		if q := quoteOf(line.Value); q != 0 && q != preferred {
+			key := strings.TrimSpace(line.Key)
+			issues = append(issues, Issue{Line: i + 1, Key: key, Message: fmt.Sprintf("Value of %s uses %c quotes, the file uses %c", key, q, preferred)})
+		}
+	})
+	return issues
+}
+
+func fixQuoteStyle(doc *Document, issue Issue) bool {
+	line := doc.Lines[issue.Line-1]
+	value := strings.TrimSpace(line.Value)
+	from := quoteOf(value)
+	inner := value[1 : len(value)-1]
+	to := byte('"')
+	if from == '"' {
+		to = '\''
+	}
+	// Single and double quotes differ in interpolation and escaping; only convert values where they are equivalent
+	if strings.ContainsAny(inner, "$\\\"'") {
+		return false
+	}
+	doc.setLine(issue.Line-1, leadingIndent(line.Text)+rebuild(line, line.Key, string(to)+inner+string(to)))
+	return true
+}
+
+func checkFinalNewline(doc *Document) []Issue {
+	if len(doc.Lines) > 0 && !doc.FinalNewline {
+		return []Issue{{Line: len(doc.Lines), Message: "File does not end with a newline"}}
+	}
+	return nil
+}
+
+func fixFinalNewline(doc *Document, _ Issue) bool {
+	doc.FinalNewline = true
+	return true
+}
+
+func checkMissingComment(doc *Document) []Issue {
+	var issues []Issue
+	pairs(doc, func(i int, line Line) {
+		if i == 0 || !doc.Lines[i-1].Comment {
+			key := strings.TrimSpace(line.Key)
+			issues = append(issues, Issue{Line: i + 1, Key: key, Message: fmt.Sprintf("Key %s has no comment", key)})
+		}
+	})
+	return issues
+}
+
+// leadingIndent returns the whitespace a line starts with
+func leadingIndent(text string) string {
+	return text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+}
@@ -0,0 +1,47 @@
+package lint
+
+import "testing"
+
+func TestUnquotedSpaces(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		issues int
+		fixed  string
+	}{
+		{"plain value", "DEBUG=true\n", 0, "DEBUG=true\n"},
+		{"inline comment", "DEBUG=true # enable debug\n", 0, "DEBUG=true # enable debug\n"},
+		{"comment without value", "DEBUG= # unset\n", 0, "DEBUG= # unset\n"},
+		{"hash inside a value", "COLOR=a#b\n", 0, "COLOR=a#b\n"},
+		{"spaces", "GREETING=hello world\n", 1, "GREETING=\"hello world\"\n"},
+		{"spaces and inline comment", "GREETING=hello world # shown on login\n", 1, "GREETING=\"hello world\" # shown on login\n"},
+		{"quoted with inline comment", "GREETING=\"hello world\" # shown on login\n", 0, "GREETING=\"hello world\" # shown on login\n"},
+		{"quoted hash", "GREETING=\"hello # world\"\n", 0, "GREETING=\"hello # world\"\n"},
+		{"tab before comment", "NAME=a b\t# note\n", 1, "NAME=\"a b\"\t# note\n"},
+	}
+
+	linter, err := New(map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := ParseDocument(tt.input)
+			var issues []Issue
+			for _, issue := range linter.Lint(doc) {
+				if issue.Rule == "unquoted-spaces" {
+					issues = append(issues, issue)
+				}
+			}
+			if len(issues) != tt.issues {
+				t.Fatalf("got %d unquoted-spaces issues, want %d: %v", len(issues), tt.issues, issues)
+			}
+			for _, issue := range issues {
+				fixUnquotedSpaces(doc, issue)
+			}
+			if got := doc.String(); got != tt.fixed {
+				t.Errorf("fixed to %q, want %q", got, tt.fixed)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package merge
+
+import (
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+)
+
+// Conflict markers, as written by git
+const (
+	MarkerOurs   = "<<<<<<< ours"
+	MarkerSplit  = "======="
+	MarkerTheirs = ">>>>>>> theirs"
+)
+
+// Side is the state of a key in one version of the file
+type Side struct {
+	Value   string
+	Present bool
+}
+
+func sideOf(doc *parser.Document, key string) Side {
+	value, ok := doc.Value(key)
+	return Side{Value: value, Present: ok}
+}
+
+// Conflict is a key changed differently on both sides
+type Conflict struct {
+	Key    string
+	Base   Side
+	Ours   Side
+	Theirs Side
+}
+
+// Description explains how the sides disagree
+func (c Conflict) Description() string {
+	switch {
+	case !c.Base.Present && c.Ours.Present && c.Theirs.Present:
+		return "Added on both sides with different values"
+	case !c.Ours.Present:
+		return "Deleted in ours, changed in theirs"
+	case !c.Theirs.Present:
+		return "Changed in ours, deleted in theirs"
+	default:
+		return "Changed on both sides with different values"
+	}
+}
+
+// Change is a change taken from theirs
+type Change struct {
+	Key  string
+	Kind string // added, changed or removed
+}
+
+// Result is the outcome of a merge
+type Result struct {
+	Document  *parser.Document
+	Changes   []Change
+	Conflicts []Conflict
+}
+
+// Merge merges two versions of an env file at the key level. The layout of ours is kept,
+// so reordered keys never conflict; changes made only in theirs are applied to it and
+// keys changed differently on both sides are conflicts. With markers, conflicts are
+// written as git conflict markers, otherwise ours is kept for them.
+func Merge(base, ours, theirs *parser.Document, markers bool) Result {
+	result := Result{Document: &parser.Document{Lines: append([]string(nil), ours.Lines...)}}
+	doc := result.Document
+
+	theirsOrder := theirs.Keys()
+	theirsComments := comments(theirs)
+
+	// Keys of ours first, then keys added in theirs in their order, then keys only in base
+	seen := make(map[string]bool)
+	var keys []string
+	for _, list := range [][]string{ours.Keys(), theirsOrder, base.Keys()} {
+		for _, key := range list {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		b, o, t := sideOf(base, key), sideOf(ours, key), sideOf(theirs, key)
+		switch {
+		case o == t, t == b:
+			// Same on both sides, or only changed in ours
+		case o == b:
+			// Only changed in theirs
+			switch {
+			case !t.Present:
+				doc.Remove(key)
+				result.Changes = append(result.Changes, Change{Key: key, Kind: "removed"})
+			case !o.Present:
+				doc.Insert(parser.EnvVar{Key: key, Value: t.Value, Comment: theirsComments[key]}, theirsOrder)
+				result.Changes = append(result.Changes, Change{Key: key, Kind: "added"})
+			default:
+				doc.Set(key, t.Value)
+				result.Changes = append(result.Changes, Change{Key: key, Kind: "changed"})
+			}
+		default:
+			result.Conflicts = append(result.Conflicts, Conflict{Key: key, Base: b, Ours: o, Theirs: t})
+		}
+	}
+
+	// Markers are written last so keys added next to a conflicting key land outside the markers
+	if markers {
+		for _, conflict := range result.Conflicts {
+			lines := conflictLines(conflict)
+			if conflict.Ours.Present {
+				doc.ReplaceKey(conflict.Key, lines)
+			} else {
+				doc.InsertLines(conflict.Key, lines, theirsOrder)
+			}
+		}
+	}
+
+	return result
+}
+
+// conflictLines renders a conflict with git conflict markers
+func conflictLines(c Conflict) []string {
+	lines := []string{MarkerOurs}
+	if c.Ours.Present {
+		lines = append(lines, c.Key+"="+c.Ours.Value)
+	}
+	lines = append(lines, MarkerSplit)
+	if c.Theirs.Present {
+		lines = append(lines, c.Key+"="+c.Theirs.Value)
+	}
+	return append(lines, MarkerTheirs)
+}
+
+// comments returns the comment directly above each key, as ParseEnv reads it
+func comments(doc *parser.Document) map[string]string {
+	result := make(map[string]string)
+	envVars, err := parser.ParseEnv(strings.NewReader(doc.String()))
+	if err != nil {
+		return result
+	}
+	for _, envVar := range envVars {
+		if _, ok := result[envVar.Key]; !ok {
+			result[envVar.Key] = envVar.Comment
+		}
+	}
+	return result
+}
@@ -0,0 +1,194 @@
+package merge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+)
+
+func doc(t *testing.T, content string) *parser.Document {
+	t.Helper()
+	d, err := parser.ParseDocument(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		want      string
+		changes   []Change
+		conflicts []string
+	}{
+		{
+			name:   "identical",
+			base:   "A=1\nB=2\n",
+			ours:   "A=1\nB=2\n",
+			theirs: "A=1\nB=2\n",
+			want:   "A=1\nB=2\n",
+		},
+		{
+			name:   "only ours changed",
+			base:   "A=1\n",
+			ours:   "A=2\n",
+			theirs: "A=1\n",
+			want:   "A=2\n",
+		},
+		{
+			name:    "only theirs changed",
+			base:    "# Database\nA=1\nB=2\n",
+			ours:    "# Database\nA=1\nB=2\n",
+			theirs:  "# Database\nA=1\nB=3\n",
+			want:    "# Database\nA=1\nB=3\n",
+			changes: []Change{{Key: "B", Kind: "changed"}},
+		},
+		{
+			name:   "same change on both sides",
+			base:   "A=1\n",
+			ours:   "A=2\n",
+			theirs: "A=2\n",
+			want:   "A=2\n",
+		},
+		{
+			name:    "added in theirs next to its neighbour",
+			base:    "DB_HOST=x\n\nAPP_ENV=local\n",
+			ours:    "DB_HOST=x\n\nAPP_ENV=local\n",
+			theirs:  "DB_HOST=x\n# Port\nDB_PORT=5432\n\nAPP_ENV=local\n",
+			want:    "DB_HOST=x\n# Port\nDB_PORT=5432\n\nAPP_ENV=local\n",
+			changes: []Change{{Key: "DB_PORT", Kind: "added"}},
+		},
+		{
+			name:    "removed in theirs",
+			base:    "A=1\nB=2\n",
+			ours:    "A=1\nB=2\n",
+			theirs:  "A=1\n",
+			want:    "A=1\n",
+			changes: []Change{{Key: "B", Kind: "removed"}},
+		},
+		{
+			name:    "reordered in theirs",
+			base:    "A=1\nB=2\n",
+			ours:    "A=1\nB=2\n",
+			theirs:  "B=2\nA=9\n",
+			want:    "A=9\nB=2\n",
+			changes: []Change{{Key: "A", Kind: "changed"}},
+		},
+		{
+			name:      "changed on both sides",
+			base:      "A=1\n",
+			ours:      "A=2\n",
+			theirs:    "A=3\n",
+			want:      "A=2\n",
+			conflicts: []string{"A"},
+		},
+		{
+			name:      "added on both sides",
+			base:      "",
+			ours:      "A=1\n",
+			theirs:    "A=2\n",
+			want:      "A=1\n",
+			conflicts: []string{"A"},
+		},
+		{
+			name:      "deleted in ours, changed in theirs",
+			base:      "A=1\nB=1\n",
+			ours:      "A=1\n",
+			theirs:    "A=1\nB=2\n",
+			want:      "A=1\n",
+			conflicts: []string{"B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Merge(doc(t, tt.base), doc(t, tt.ours), doc(t, tt.theirs), false)
+			if got := result.Document.String(); got != tt.want {
+				t.Errorf("merged to %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(result.Changes, tt.changes) {
+				t.Errorf("changes %v, want %v", result.Changes, tt.changes)
+			}
+			var conflicts []string
+			for _, conflict := range result.Conflicts {
+				conflicts = append(conflicts, conflict.Key)
+			}
+			if !reflect.DeepEqual(conflicts, tt.conflicts) {
+				t.Errorf("conflicts %v, want %v", conflicts, tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestMergeDoesNotModifyInputs(t *testing.T) {
+	ours := doc(t, "A=1\nB=2\n")
+	Merge(doc(t, "A=1\nB=2\n"), ours, doc(t, "A=5\n"), true)
+	if got := ours.String(); got != "A=1\nB=2\n" {
+		t.Errorf("ours was modified: %q", got)
+	}
+}
+
+func TestMergeMarkers(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   string
+		ours   string
+		theirs string
+		want   string
+	}{
+		{
+			name:   "changed on both sides",
+			base:   "A=1\nB=1\n",
+			ours:   "A=2\nB=1\n",
+			theirs: "A=3\nB=1\n",
+			want:   "<<<<<<< ours\nA=2\n=======\nA=3\n>>>>>>> theirs\nB=1\n",
+		},
+		{
+			name:   "deleted in ours",
+			base:   "A=1\nB=1\n",
+			ours:   "A=1\n",
+			theirs: "A=1\nB=2\n",
+			want:   "A=1\n<<<<<<< ours\n=======\nB=2\n>>>>>>> theirs\n",
+		},
+		{
+			name:   "deleted in theirs",
+			base:   "A=1\nB=1\n",
+			ours:   "A=1\nB=2\n",
+			theirs: "A=1\n",
+			want:   "A=1\n<<<<<<< ours\nB=2\n=======\n>>>>>>> theirs\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Merge(doc(t, tt.base), doc(t, tt.ours), doc(t, tt.theirs), true)
+			if got := result.Document.String(); got != tt.want {
+				t.Errorf("merged to %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConflictDescription(t *testing.T) {
+	present := Side{Value: "1", Present: true}
+	other := Side{Value: "2", Present: true}
+	tests := []struct {
+		conflict Conflict
+		want     string
+	}{
+		{Conflict{Ours: present, Theirs: other}, "Added on both sides with different values"},
+		{Conflict{Base: present, Theirs: other}, "Deleted in ours, changed in theirs"},
+		{Conflict{Base: present, Ours: other}, "Changed in ours, deleted in theirs"},
+		{Conflict{Base: present, Ours: other, Theirs: Side{Value: "3", Present: true}}, "Changed on both sides with different values"},
+	}
+	for _, tt := range tests {
+		if got := tt.conflict.Description(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.conflict, got, tt.want)
+		}
+	}
+}
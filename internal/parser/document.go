@@ -0,0 +1,274 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// AddedSectionHeader marks the section at the end of a file that holds keys without a natural place
+const AddedSectionHeader = "# Added by envdoc"
+
+// Document is an env file kept line by line, so edits preserve the author's ordering,
+// comments and blank lines
+type Document struct {
+	Lines []string
+}
+
+// ReadDocument reads an env file as a document
+func ReadDocument(filename string) (*Document, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			return
+		}
+	}(file)
+
+	return ParseDocument(file)
+}
+
+// ParseDocument reads a document from a reader
+func ParseDocument(r io.Reader) (*Document, error) {
+	doc := &Document{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		doc.Lines = append(doc.Lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return doc, nil
+}
+
+// String returns the document content, ending with a newline
+func (d *Document) String() string {
+	if len(d.Lines) == 0 {
+		return ""
+	}
+	return strings.Join(d.Lines, "\n") + "\n"
+}
+
+// Write writes the document to a file
+func (d *Document) Write(filename string) error {
+	if err := os.WriteFile(filename, []byte(d.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// Has reports whether the document defines a key
+func (d *Document) Has(key string) bool {
+	return d.indexOf(key) >= 0
+}
+
+// Insert adds a key next to its closest relative: after the nearest key that precedes it in
+// order and is present in the document (or before the nearest one that follows it), else after
+// the last key with the same prefix, else in the "Added by envdoc" section at the end.
+// The variable's comment, if any, is inserted above it.
+func (d *Document) Insert(envVar EnvVar, order []string) {
+	lines := []string{fmt.Sprintf("%s=%s", envVar.Key, envVar.Value)}
+	if envVar.Comment != "" {
+		lines = append(strings.Split(envVar.Comment, "\n"), lines...)
+	}
+	d.InsertLines(envVar.Key, lines, order)
+}
+
+// InsertLines inserts lines where the key would be inserted by Insert
+func (d *Document) InsertLines(key string, lines []string, order []string) {
+	position := d.insertPosition(key, order)
+	d.Lines = append(d.Lines[:position], append(lines, d.Lines[position:]...)...)
+}
+
+// insertPosition returns the line index a new key belongs at
+func (d *Document) insertPosition(key string, order []string) int {
+	// Template or reference file neighbors
+	for i, k := range order {
+		if k != key {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if index := d.indexOf(order[j]); index >= 0 {
+				return index + 1
+			}
+		}
+		for j := i + 1; j < len(order); j++ {
+			if index := d.indexOf(order[j]); index >= 0 {
+				return d.blockStart(index)
+			}
+		}
+		break
+	}
+
+	// Same prefix group
+	position := -1
+	prefix := GetPrefix(key)
+	for i, line := range d.Lines {
+		if k := lineKey(line); k != "" && GetPrefix(k) == prefix {
+			position = i + 1
+		}
+	}
+	if position >= 0 {
+		return position
+	}
+
+	return d.addedSectionEnd()
+}
+
+// Keys returns the keys of the document in order of first appearance
+func (d *Document) Keys() []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, line := range d.Lines {
+		if key := lineKey(line); key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Value returns the value of the first assignment of a key, as ParseEnv reads it
+func (d *Document) Value(key string) (string, bool) {
+	index := d.indexOf(key)
+	if index < 0 {
+		return "", false
+	}
+	parts := strings.SplitN(d.Lines[index], "=", 2)
+	return strings.TrimSpace(parts[1]), true
+}
+
+// Set changes the value of every assignment of a key, keeping everything before the "="
+func (d *Document) Set(key, value string) {
+	for i, line := range d.Lines {
+		if lineKey(line) == key {
+			parts := strings.SplitN(line, "=", 2)
+			d.Lines[i] = parts[0] + "=" + value
+		}
+	}
+}
+
+// UpdateValues replaces the value of every assignment with the result of fn, which gets
+// the key and the current value. Lines whose value fn returns unchanged are left alone.
+func (d *Document) UpdateValues(fn func(key, value string) (string, error)) error {
+	for i, line := range d.Lines {
+		key := lineKey(line)
+		if key == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		value := strings.TrimSpace(parts[1])
+		updated, err := fn(key, value)
+		if err != nil {
+			return err
+		}
+		if updated != value {
+			d.Lines[i] = parts[0] + "=" + updated
+		}
+	}
+	return nil
+}
+
+// ReplaceKey replaces the line of the first assignment of a key with other lines
+func (d *Document) ReplaceKey(key string, lines []string) {
+	index := d.indexOf(key)
+	if index < 0 {
+		return
+	}
+	d.Lines = append(d.Lines[:index], append(lines, d.Lines[index+1:]...)...)
+}
+
+// Remove deletes a key. The comment directly above it is removed too when it only
+// belongs to that key, i.e. the key is not followed by another key of the same block.
+func (d *Document) Remove(key string) {
+	for index := d.indexOf(key); index >= 0; index = d.indexOf(key) {
+		start := index
+		if index+1 >= len(d.Lines) || strings.TrimSpace(d.Lines[index+1]) == "" {
+			start = d.blockStart(index)
+		}
+		d.Lines = append(d.Lines[:start], d.Lines[index+1:]...)
+
+		// Collapse the blank lines around a block that is now empty
+		if start > 0 && strings.TrimSpace(d.Lines[start-1]) == "" && (start >= len(d.Lines) || strings.TrimSpace(d.Lines[start]) == "") {
+			d.Lines = append(d.Lines[:start-1], d.Lines[start:]...)
+		} else if start == 0 && len(d.Lines) > 0 && strings.TrimSpace(d.Lines[0]) == "" {
+			d.Lines = d.Lines[1:]
+		}
+	}
+	d.removeEmptyAddedSection()
+}
+
+// indexOf returns the line index of a key, or -1
+func (d *Document) indexOf(key string) int {
+	for i, line := range d.Lines {
+		if lineKey(line) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// blockStart returns the index of the first comment line directly above a line
+func (d *Document) blockStart(index int) int {
+	for index > 0 && strings.HasPrefix(strings.TrimSpace(d.Lines[index-1]), "#") && strings.TrimSpace(d.Lines[index-1]) != AddedSectionHeader {
+		index--
+	}
+	return index
+}
+
+// addedSectionEnd returns the position after the last line of the "Added by envdoc" section,
+// creating the section at the end of the document if needed
+func (d *Document) addedSectionEnd() int {
+	for i, line := range d.Lines {
+		if strings.TrimSpace(line) != AddedSectionHeader {
+			continue
+		}
+		end := i + 1
+		for end < len(d.Lines) && strings.TrimSpace(d.Lines[end]) != "" {
+			end++
+		}
+		return end
+	}
+
+	if len(d.Lines) > 0 && strings.TrimSpace(d.Lines[len(d.Lines)-1]) != "" {
+		d.Lines = append(d.Lines, "")
+	}
+	d.Lines = append(d.Lines, AddedSectionHeader)
+	return len(d.Lines)
+}
+
+// removeEmptyAddedSection drops the "Added by envdoc" header once its section has no keys left
+func (d *Document) removeEmptyAddedSection() {
+	for i, line := range d.Lines {
+		if strings.TrimSpace(line) != AddedSectionHeader {
+			continue
+		}
+		if i+1 < len(d.Lines) && strings.TrimSpace(d.Lines[i+1]) != "" {
+			return
+		}
+		end := i + 1
+		if i > 0 && strings.TrimSpace(d.Lines[i-1]) == "" {
+			i--
+		}
+		d.Lines = append(d.Lines[:i], d.Lines[end:]...)
+		return
+	}
+}
+
+// lineKey returns the key assigned on a line, or "" for comments, blank lines and other content
+func lineKey(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return ""
+	}
+	parts := strings.SplitN(trimmed, "=", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return strings.TrimSpace(parts[0])
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
@@ -29,8 +30,13 @@ func ParseEnvFile(filename string) ([]EnvVar, error) {
 		}
 	}(file)
 
+	return ParseEnv(file)
+}
+
+// ParseEnv parses environment variables from a reader
+func ParseEnv(r io.Reader) ([]EnvVar, error) {
 	var envVars []EnvVar
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	var currentComment string
 
 	for scanner.Scan() {
@@ -71,49 +77,27 @@ func ParseEnvFile(filename string) ([]EnvVar, error) {
 
 // WriteEnvFile writes environment variables to a file
 func WriteEnvFile(filename string, envVars []EnvVar) error {
-	file, err := os.Create(filename)
-	if err != nil {
+	if err := os.WriteFile(filename, []byte(FormatEnv(envVars)), 0644); err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			return
-		}
-	}(file)
-
-	writer := bufio.NewWriter(file)
-	defer func(writer *bufio.Writer) {
-		err := writer.Flush()
-		if err != nil {
-			return
-		}
-	}(writer)
+	return nil
+}
 
+// FormatEnv returns the env file content WriteEnvFile writes for the variables
+func FormatEnv(envVars []EnvVar) string {
+	var b strings.Builder
 	for _, envVar := range envVars {
 		if envVar.Comment != "" {
-			_, err := fmt.Fprintln(writer, envVar.Comment)
-
-			if err != nil {
-				return err
-			}
-		}
-
-		_, err := fmt.Fprintf(writer, "%s=%s\n", envVar.Key, envVar.Value)
-		if err != nil {
-			return err
+			b.WriteString(envVar.Comment + "\n")
 		}
+		fmt.Fprintf(&b, "%s=%s\n", envVar.Key, envVar.Value)
 
 		// Add blank line after this variable if requested
 		if envVar.BlankAfter {
-			_, err := fmt.Fprintln(writer)
-			if err != nil {
-				return err
-			}
+			b.WriteString("\n")
 		}
 	}
-
-	return nil
+	return b.String()
 }
 
 // GetEnvKeys returns a list of unique keys from environment variables
@@ -142,7 +126,7 @@ func ArrangeByPrefix(envVars []EnvVar) []EnvVar {
 
 	for i, envVar := range envVars {
 		// Extract prefix (everything before the first underscore)
-		prefix := getPrefix(envVar.Key)
+		prefix := GetPrefix(envVar.Key)
 
 		// Check if prefix has changed
 		if i > 0 && prefix != lastPrefix {
@@ -159,8 +143,8 @@ func ArrangeByPrefix(envVars []EnvVar) []EnvVar {
 	return result
 }
 
-// getPrefix extracts the prefix from a key (everything before the first underscore)
-func getPrefix(key string) string {
+// GetPrefix extracts the prefix from a key (everything before the first underscore)
+func GetPrefix(key string) string {
 	for i, c := range key {
 		if c == '_' {
 			return key[:i]
@@ -169,6 +153,34 @@ func getPrefix(key string) string {
 	return key
 }
 
+// CommentText returns the text of a comment without its leading "#"
+func CommentText(comment string) string {
+	lines := strings.Split(strings.TrimSpace(comment), "\n")
+	text := strings.TrimSpace(lines[len(lines)-1])
+	return strings.TrimSpace(strings.TrimPrefix(text, "#"))
+}
+
+// Unquote removes matching single or double quotes around a value
+func Unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+// SealedPrefix marks a value encrypted in place by envdoc seal
+const SealedPrefix = "enc:v1:"
+
+// IsSealed reports whether a value, optionally quoted, is sealed. Sealed values are
+// ciphertext: they can be checked for presence, but not compared or validated without
+// unsealing them first.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(Unquote(value), SealedPrefix)
+}
+
 // FindDuplicates finds duplicate keys in environment variables
 func FindDuplicates(envVars []EnvVar) []string {
 	keyCount := make(map[string]int)
@@ -0,0 +1,174 @@
+package recipients
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MayR-Labs/envdoc-go/internal/crypto"
+)
+
+// DefaultFile is the recipients file, relative to the project root
+const DefaultFile = ".envdoc/recipients.txt"
+
+// IdentityFile is the name of the user's identity in the envdoc config directory
+const IdentityFile = "identity.txt"
+
+// fileHeader starts every recipients file written by envdoc
+const fileHeader = `# Public keys (age X25519) that encrypted env files are readable by.
+# Change with 'envdoc recipients add/remove', which re-encrypts the files.
+`
+
+// Entry is a recipient with the name given in the comment above its key
+type Entry struct {
+	Name      string
+	Recipient *crypto.Recipient
+}
+
+// List is the content of a recipients file. It is also a valid age recipients file
+// (age -R).
+type List struct {
+	Entries []Entry
+}
+
+// Load reads a recipients file. A missing file is an empty list.
+func Load(path string) (*List, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &List{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	list, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return list, nil
+}
+
+// Parse reads recipients, one public key per line. The comment line directly above a key
+// names it; blank lines reset the name.
+func Parse(r io.Reader) (*List, error) {
+	list := &List{}
+	var name string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			name = ""
+		case strings.HasPrefix(line, "#"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		default:
+			recipient, err := crypto.ParseRecipient(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			list.Entries = append(list.Entries, Entry{Name: name, Recipient: recipient})
+			name = ""
+		}
+	}
+	return list, scanner.Err()
+}
+
+// Add appends a recipient. A key can only be listed once.
+func (l *List) Add(name string, recipient *crypto.Recipient) error {
+	for _, entry := range l.Entries {
+		if entry.Recipient.String() == recipient.String() {
+			return fmt.Errorf("%s is already a recipient", entry.Describe())
+		}
+	}
+	l.Entries = append(l.Entries, Entry{Name: name, Recipient: recipient})
+	return nil
+}
+
+// Remove removes the recipient with the given public key or name
+func (l *List) Remove(keyOrName string) (Entry, error) {
+	match := -1
+	for i, entry := range l.Entries {
+		if entry.Recipient.String() == keyOrName || (entry.Name != "" && entry.Name == keyOrName) {
+			if match >= 0 {
+				return Entry{}, fmt.Errorf("'%s' matches more than one recipient; use the public key", keyOrName)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return Entry{}, fmt.Errorf("no recipient '%s'", keyOrName)
+	}
+	removed := l.Entries[match]
+	l.Entries = append(l.Entries[:match], l.Entries[match+1:]...)
+	return removed, nil
+}
+
+// Recipients returns the public keys
+func (l *List) Recipients() []*crypto.Recipient {
+	recipients := make([]*crypto.Recipient, len(l.Entries))
+	for i, entry := range l.Entries {
+		recipients[i] = entry.Recipient
+	}
+	return recipients
+}
+
+// String returns the file content
+func (l *List) String() string {
+	var sb strings.Builder
+	sb.WriteString(fileHeader)
+	for _, entry := range l.Entries {
+		sb.WriteString("\n")
+		if entry.Name != "" {
+			sb.WriteString("# " + entry.Name + "\n")
+		}
+		sb.WriteString(entry.Recipient.String() + "\n")
+	}
+	return sb.String()
+}
+
+// Describe returns the name and key of an entry for messages
+func (e Entry) Describe() string {
+	if e.Name == "" {
+		return e.Recipient.String()
+	}
+	return fmt.Sprintf("%s (%s)", e.Name, e.Recipient)
+}
+
+// IdentityPath returns the default location of the user's identity. It lives outside the
+// project, like the journal key.
+func IdentityPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the user config directory: %w", err)
+	}
+	return filepath.Join(dir, "envdoc", IdentityFile), nil
+}
+
+// LoadIdentities reads an identity file
+func LoadIdentities(path string) ([]*crypto.Identity, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no identity at %s (create one with 'envdoc keygen')", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	identities, err := crypto.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// FormatIdentity returns the content of an identity file, in the format of age-keygen
+func FormatIdentity(identity *crypto.Identity, created time.Time) string {
+	return fmt.Sprintf("# created: %s\n# public key: %s\n%s\n",
+		created.Format(time.RFC3339), identity.Recipient(), identity)
+}
@@ -0,0 +1,43 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON renders the report as indented JSON
+func JSON(r *Report) (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal report: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// CSV renders the findings of the report, one per row
+func CSV(r *Report) (string, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write([]string{"section", "severity", "rule", "file", "line", "key", "value", "confidence", "message"}); err != nil {
+		return "", err
+	}
+	for _, f := range r.AllFindings() {
+		line := ""
+		if f.Line != 0 {
+			line = fmt.Sprint(f.Line)
+		}
+		record := []string{f.Section, f.Severity, f.Rule, f.File, line, f.Key, f.Value, f.Confidence, f.Message}
+		if err := writer.Write(record); err != nil {
+			return "", err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,131 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// htmlStyle keeps the HTML report self-contained
+const htmlStyle = `body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Helvetica,Arial,sans-serif;max-width:1100px;margin:2rem auto;padding:0 1rem;color:#1f2328;line-height:1.5}
+h1{border-bottom:1px solid #d0d7de;padding-bottom:.3rem}h2{border-bottom:1px solid #d0d7de;padding-bottom:.2rem;margin-top:2rem}
+table{border-collapse:collapse;margin:1rem 0;width:100%}th,td{border:1px solid #d0d7de;padding:.35rem .6rem;text-align:left;vertical-align:top}
+th{background:#f6f8fa}code{background:#f6f8fa;padding:.1rem .3rem;border-radius:4px;font-size:90%}
+.empty{color:#1a7f37}.severity-error{color:#cf222e;font-weight:600}.severity-warning{color:#9a6700;font-weight:600}.severity-info{color:#0969da}
+nav ul{padding-left:1.2rem}`
+
+var inlineCode = regexp.MustCompile("`([^`]+)`")
+
+// HTML renders the report as a self-contained HTML page
+func HTML(r *Report) string {
+	var sb strings.Builder
+
+	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	sb.WriteString(fmt.Sprintf("<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", html.EscapeString(r.Title), htmlStyle))
+	sb.WriteString(fmt.Sprintf("<h1>%s</h1>\n", html.EscapeString(r.Title)))
+
+	sb.WriteString("<nav><h2>Table of Contents</h2>\n<ul>\n")
+	for _, section := range r.Sections {
+		sb.WriteString(fmt.Sprintf("<li><a href=\"#%s\">%s</a></li>\n", anchor(section.Title), html.EscapeString(section.Title)))
+	}
+	sb.WriteString("</ul></nav>\n")
+
+	for _, section := range r.Sections {
+		writeHTMLSection(&sb, section, 2)
+	}
+
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
+
+func writeHTMLSection(sb *strings.Builder, section Section, level int) {
+	if level > 6 {
+		level = 6
+	}
+	sb.WriteString(fmt.Sprintf("<h%d id=\"%s\">%s</h%d>\n", level, anchor(section.Title), htmlText(section.Title), level))
+
+	if section.Text != "" {
+		sb.WriteString(fmt.Sprintf("<p>%s</p>\n", htmlText(section.Text)))
+	}
+
+	for _, field := range section.Fields {
+		value := html.EscapeString(field.Value)
+		if field.Code {
+			value = "<code>" + value + "</code>"
+		}
+		sb.WriteString(fmt.Sprintf("<p><strong>%s:</strong> %s</p>\n", html.EscapeString(field.Label), value))
+	}
+
+	if section.IsEmpty() {
+		if section.Empty != "" {
+			sb.WriteString(fmt.Sprintf("<p class=\"empty\">%s</p>\n", htmlText(section.Empty)))
+		}
+		return
+	}
+
+	if len(section.List) > 0 {
+		sb.WriteString("<ul>\n")
+		for _, item := range section.List {
+			sb.WriteString(fmt.Sprintf("<li>%s</li>\n", htmlText(item)))
+		}
+		sb.WriteString("</ul>\n")
+	}
+
+	for _, table := range section.Tables {
+		writeHTMLTable(sb, table, -1)
+	}
+
+	if len(section.Findings) > 0 {
+		columns := findingColumns(section.Findings)
+		table := Table{}
+		severityColumn := -1
+		for i, column := range columns {
+			table.Columns = append(table.Columns, Column{Title: column, Code: isCodeColumn(column)})
+			if column == "Severity" {
+				severityColumn = i
+			}
+		}
+		for _, finding := range section.Findings {
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				row[i] = findingCell(finding, column)
+			}
+			table.Rows = append(table.Rows, row)
+		}
+		writeHTMLTable(sb, table, severityColumn)
+	}
+
+	for _, subsection := range section.Subsections {
+		writeHTMLSection(sb, subsection, level+1)
+	}
+}
+
+func writeHTMLTable(sb *strings.Builder, table Table, severityColumn int) {
+	sb.WriteString("<table>\n<thead><tr>")
+	for _, column := range table.Columns {
+		sb.WriteString(fmt.Sprintf("<th>%s</th>", html.EscapeString(column.Title)))
+	}
+	sb.WriteString("</tr></thead>\n<tbody>\n")
+	for _, row := range table.Rows {
+		sb.WriteString("<tr>")
+		for i, cell := range row {
+			value := htmlText(cell)
+			if i < len(table.Columns) && table.Columns[i].Code && cell != "" {
+				value = "<code>" + html.EscapeString(cell) + "</code>"
+			}
+			if i == severityColumn {
+				sb.WriteString(fmt.Sprintf("<td class=\"severity-%s\">%s</td>", html.EscapeString(cell), value))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("<td>%s</td>", value))
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("</tbody>\n</table>\n")
+}
+
+// htmlText escapes text and turns `inline code` spans into code elements
+func htmlText(text string) string {
+	return inlineCode.ReplaceAllString(html.EscapeString(text), "<code>$1</code>")
+}
@@ -0,0 +1,117 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the report as Markdown with a table of contents
+func Markdown(r *Report) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# %s\n\n", r.Title))
+	sb.WriteString("## Table of Contents\n")
+	for _, section := range r.Sections {
+		sb.WriteString(fmt.Sprintf("- [%s](#%s)\n", section.Title, anchor(section.Title)))
+	}
+	sb.WriteString("\n")
+
+	for _, section := range r.Sections {
+		writeMarkdownSection(&sb, section, 2)
+	}
+
+	return sb.String()
+}
+
+func writeMarkdownSection(sb *strings.Builder, section Section, level int) {
+	sb.WriteString(fmt.Sprintf("%s %s\n\n", strings.Repeat("#", level), section.Title))
+
+	if section.Text != "" {
+		sb.WriteString(section.Text + "\n\n")
+	}
+
+	for _, field := range section.Fields {
+		value := field.Value
+		if field.Code {
+			value = "`" + value + "`"
+		}
+		sb.WriteString(fmt.Sprintf("**%s:** %s\n\n", field.Label, value))
+	}
+
+	if section.IsEmpty() {
+		if section.Empty != "" {
+			sb.WriteString(section.Empty + "\n\n")
+		}
+		return
+	}
+
+	if len(section.List) > 0 {
+		for _, item := range section.List {
+			sb.WriteString(fmt.Sprintf("- %s\n", item))
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, table := range section.Tables {
+		writeMarkdownTable(sb, table)
+	}
+
+	if len(section.Findings) > 0 {
+		columns := findingColumns(section.Findings)
+		table := Table{}
+		for _, column := range columns {
+			table.Columns = append(table.Columns, Column{Title: column, Code: isCodeColumn(column)})
+		}
+		for _, finding := range section.Findings {
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				row[i] = findingCell(finding, column)
+			}
+			table.Rows = append(table.Rows, row)
+		}
+		writeMarkdownTable(sb, table)
+	}
+
+	for _, subsection := range section.Subsections {
+		writeMarkdownSection(sb, subsection, level+1)
+	}
+}
+
+func writeMarkdownTable(sb *strings.Builder, table Table) {
+	sb.WriteString("|")
+	for _, column := range table.Columns {
+		sb.WriteString(fmt.Sprintf(" %s |", column.Title))
+	}
+	sb.WriteString("\n|")
+	for _, column := range table.Columns {
+		sb.WriteString(strings.Repeat("-", len(column.Title)+2) + "|")
+	}
+	sb.WriteString("\n")
+
+	for _, row := range table.Rows {
+		sb.WriteString("|")
+		for i, cell := range row {
+			cell = strings.ReplaceAll(cell, "|", "\\|")
+			if i < len(table.Columns) && table.Columns[i].Code && cell != "" {
+				cell = "`" + cell + "`"
+			}
+			sb.WriteString(fmt.Sprintf(" %s |", cell))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+}
+
+// anchor returns the GitHub-style anchor for a heading
+func anchor(title string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == ' ':
+			sb.WriteRune('-')
+		case r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,235 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity levels used by findings
+const (
+	Error   = "error"
+	Warning = "warning"
+	Info    = "info"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{"markdown", "html", "json", "csv"}
+
+// Report is the shared data model filled by every reporting command
+type Report struct {
+	Title    string    `json:"title"`
+	Sections []Section `json:"sections"`
+}
+
+// Section is a titled part of a report. Content is rendered in field order:
+// text, fields, list, tables, findings, then subsections.
+type Section struct {
+	Title       string    `json:"title"`
+	Text        string    `json:"text,omitempty"`
+	Fields      []Field   `json:"fields,omitempty"`
+	List        []string  `json:"list,omitempty"`
+	Tables      []Table   `json:"tables,omitempty"`
+	Findings    []Finding `json:"findings,omitempty"`
+	Subsections []Section `json:"subsections,omitempty"`
+	// Empty is shown instead of the content when the section has no findings, tables, list or subsections
+	Empty string `json:"empty,omitempty"`
+}
+
+// Field is a labelled summary value
+type Field struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+	Code  bool   `json:"-"`
+}
+
+// Table is a generic table of strings
+type Table struct {
+	Columns []Column   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Column is a table column; code columns are rendered in monospace
+type Column struct {
+	Title string `json:"title"`
+	Code  bool   `json:"-"`
+}
+
+// Finding is a single reported problem
+type Finding struct {
+	Severity   string `json:"severity,omitempty"`
+	Rule       string `json:"rule,omitempty"`
+	File       string `json:"file,omitempty"`
+	Line       int    `json:"line,omitempty"`
+	Key        string `json:"key,omitempty"`
+	Value      string `json:"value,omitempty"`
+	Confidence string `json:"confidence,omitempty"`
+	Message    string `json:"message,omitempty"`
+	// Fingerprint identifies the finding across runs for baselines; empty when not supported
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Secret is the unmasked value of a secret finding, only hashed into its fingerprint and
+	// never rendered
+	Secret string `json:"-"`
+}
+
+// New returns an empty report
+func New(title string) *Report {
+	return &Report{Title: title}
+}
+
+// AddSection appends a section
+func (r *Report) AddSection(section Section) {
+	r.Sections = append(r.Sections, section)
+}
+
+// AddField appends a summary field
+func (s *Section) AddField(label string, value any) {
+	s.Fields = append(s.Fields, Field{Label: label, Value: fmt.Sprint(value)})
+}
+
+// AddCodeField appends a summary field rendered in monospace
+func (s *Section) AddCodeField(label string, value string) {
+	s.Fields = append(s.Fields, Field{Label: label, Value: value, Code: true})
+}
+
+// AddSubsection appends a subsection
+func (s *Section) AddSubsection(section Section) {
+	s.Subsections = append(s.Subsections, section)
+}
+
+// IsEmpty reports whether the section has no content besides its text and fields
+func (s *Section) IsEmpty() bool {
+	return len(s.Findings) == 0 && len(s.Tables) == 0 && len(s.List) == 0 && len(s.Subsections) == 0
+}
+
+// AllFindings returns every finding in the report, with the title of the section it belongs to
+func (r *Report) AllFindings() []SectionFinding {
+	var result []SectionFinding
+	var walk func(sections []Section)
+	walk = func(sections []Section) {
+		for _, section := range sections {
+			for _, finding := range section.Findings {
+				result = append(result, SectionFinding{Section: section.Title, Finding: finding})
+			}
+			walk(section.Subsections)
+		}
+	}
+	walk(r.Sections)
+	return result
+}
+
+// SectionFinding pairs a finding with its section title
+type SectionFinding struct {
+	Section string
+	Finding
+}
+
+// CountSeverity returns how many findings in the report have the given severity
+func (r *Report) CountSeverity(severity string) int {
+	count := 0
+	for _, finding := range r.AllFindings() {
+		if finding.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// SortFindings orders findings by file, line, key and rule so output is stable between runs
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Rule < b.Rule
+	})
+}
+
+// Render renders the report in the named format
+func Render(r *Report, format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "markdown", "md":
+		return Markdown(r), nil
+	case "html":
+		return HTML(r), nil
+	case "json":
+		return JSON(r)
+	case "csv":
+		return CSV(r)
+	default:
+		return "", fmt.Errorf("unsupported report format %q (expected %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Extension returns the file extension for a format
+func Extension(format string) string {
+	switch strings.ToLower(format) {
+	case "html":
+		return ".html"
+	case "json":
+		return ".json"
+	case "csv":
+		return ".csv"
+	default:
+		return ".md"
+	}
+}
+
+// findingColumns returns the finding fields that are set in at least one finding, in display order
+func findingColumns(findings []Finding) []string {
+	has := make(map[string]bool)
+	for _, f := range findings {
+		has["Severity"] = has["Severity"] || f.Severity != ""
+		has["Rule"] = has["Rule"] || f.Rule != ""
+		has["File"] = has["File"] || f.File != ""
+		has["Line"] = has["Line"] || f.Line != 0
+		has["Key"] = has["Key"] || f.Key != ""
+		has["Value"] = has["Value"] || f.Value != ""
+		has["Confidence"] = has["Confidence"] || f.Confidence != ""
+		has["Message"] = has["Message"] || f.Message != ""
+	}
+	var columns []string
+	for _, column := range []string{"File", "Line", "Key", "Value", "Rule", "Severity", "Confidence", "Message"} {
+		if has[column] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// findingCell returns the value of a finding field by column name
+func findingCell(f Finding, column string) string {
+	switch column {
+	case "Severity":
+		return f.Severity
+	case "Rule":
+		return f.Rule
+	case "File":
+		return f.File
+	case "Line":
+		if f.Line == 0 {
+			return ""
+		}
+		return fmt.Sprint(f.Line)
+	case "Key":
+		return f.Key
+	case "Value":
+		return f.Value
+	case "Confidence":
+		return f.Confidence
+	default:
+		return f.Message
+	}
+}
+
+// isCodeColumn reports whether a finding column is rendered in monospace
+func isCodeColumn(column string) bool {
+	return column == "File" || column == "Key" || column == "Value"
+}
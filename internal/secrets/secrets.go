@@ -0,0 +1,219 @@
+package secrets
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MayR-Labs/envdoc-go/internal/parser"
+)
+
+// Confidence describes how likely a finding is to be a real credential
+type Confidence string
+
+const (
+	High   Confidence = "high"
+	Medium Confidence = "medium"
+	Low    Confidence = "low"
+)
+
+// Finding represents a value that looks like a secret
+type Finding struct {
+	Key         string
+	MaskedValue string
+	// Value is the unmasked value, for keyed fingerprints; never print it
+	Value       string
+	Rule        string
+	Description string
+	Confidence  Confidence
+	Line        int
+}
+
+// pattern is a known credential format
+type pattern struct {
+	rule        string
+	description string
+	re          *regexp.Regexp
+}
+
+var patterns = []pattern{
+	{"aws-access-key", "AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`)},
+	{"github-token", "GitHub token", regexp.MustCompile(`\b(ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}\b`)},
+	{"github-fine-grained-token", "GitHub fine-grained token", regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{60,}\b`)},
+	{"gitlab-token", "GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}\b`)},
+	{"stripe-key", "Stripe API key", regexp.MustCompile(`\b(sk|rk)_(live|test)_[A-Za-z0-9]{16,}\b`)},
+	{"slack-token", "Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"slack-webhook", "Slack webhook URL", regexp.MustCompile(`https://hooks\.slack\.com/services/[A-Za-z0-9/]+`)},
+	{"jwt", "JSON Web Token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\b`)},
+	{"private-key", "PEM private key", regexp.MustCompile(`-----BEGIN ((RSA|EC|DSA|OPENSSH|ENCRYPTED|PGP) )?PRIVATE KEY( BLOCK)?-----`)},
+	{"url-credentials", "Credentials embedded in URL", regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)},
+	{"laravel-app-key", "Laravel application key", regexp.MustCompile(`^base64:[A-Za-z0-9+/]{40,}={0,2}$`)},
+}
+
+// secretKeyNames are key fragments that suggest the value is sensitive
+var secretKeyNames = []string{"PASSWORD", "PASSWD", "PASS", "SECRET", "TOKEN", "API_KEY", "APIKEY", "PRIVATE_KEY", "ACCESS_KEY", "CREDENTIAL", "AUTH", "SALT", "DSN"}
+
+// placeholders are values that are clearly not real secrets
+var placeholders = map[string]bool{
+	"null": true, "nil": true, "none": true, "true": true, "false": true,
+	"changeme": true, "change-me": true, "secret": true, "password": true,
+	"example": true, "xxx": true, "todo": true, "your-secret": true,
+}
+
+// minEntropyLength is the shortest value considered for entropy analysis
+const minEntropyLength = 20
+
+// highEntropyThreshold is the Shannon entropy (bits per character) above which a value looks random
+const highEntropyThreshold = 4.0
+
+// Scan inspects environment variables and returns the values that look like secrets
+func Scan(envVars []parser.EnvVar) []Finding {
+	var findings []Finding
+	for _, envVar := range envVars {
+		if finding, ok := ScanValue(envVar.Key, envVar.Value); ok {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// ScanContent scans raw file content line by line, recording line numbers
+func ScanContent(content string) []Finding {
+	var findings []Finding
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, value := "", trimmed
+		if parts := strings.SplitN(strings.TrimPrefix(trimmed, "export "), "=", 2); len(parts) == 2 {
+			key, value = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		}
+		if finding, ok := ScanValue(key, value); ok {
+			finding.Line = i + 1
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// ScanValue checks a single key/value pair against the known patterns, entropy and key names
+func ScanValue(key, value string) (Finding, bool) {
+	value = parser.Unquote(strings.TrimSpace(value))
+	if IsPlaceholder(value) || parser.IsSealed(value) {
+		return Finding{}, false
+	}
+
+	finding := Finding{Key: key, MaskedValue: Mask(value), Value: value}
+
+	for _, p := range patterns {
+		if p.re.MatchString(value) {
+			finding.Rule = p.rule
+			finding.Description = p.description
+			finding.Confidence = High
+			return finding, true
+		}
+	}
+
+	secretName := LooksLikeSecretKey(key)
+	highEntropy := len(value) >= minEntropyLength && !isStructured(value) && Entropy(value) >= highEntropyThreshold
+
+	switch {
+	case highEntropy && secretName:
+		finding.Rule = "high-entropy-secret"
+		finding.Description = "High-entropy value in a secret-looking key"
+		finding.Confidence = High
+	case highEntropy:
+		finding.Rule = "high-entropy"
+		finding.Description = "High-entropy value"
+		finding.Confidence = Medium
+	case secretName:
+		finding.Rule = "secret-key-name"
+		finding.Description = "Value set for a secret-looking key"
+		finding.Confidence = Low
+	default:
+		return Finding{}, false
+	}
+
+	return finding, true
+}
+
+// LooksLikeSecretKey reports whether a key name suggests the value is sensitive
+func LooksLikeSecretKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, name := range secretKeyNames {
+		if strings.Contains(upper, name) {
+			return true
+		}
+	}
+	return strings.HasSuffix(upper, "_KEY")
+}
+
+// IsPlaceholder reports whether a value is empty, a reference or an obvious placeholder
+func IsPlaceholder(value string) bool {
+	if value == "" {
+		return true
+	}
+	if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+		return true
+	}
+	lower := strings.ToLower(value)
+	if placeholders[lower] {
+		return true
+	}
+	if strings.HasPrefix(lower, "your-") || strings.HasPrefix(lower, "<") || strings.Trim(lower, "x*.") == "" {
+		return true
+	}
+	return false
+}
+
+// isStructured reports whether a value is a URL, path or sentence, which score high on entropy without being random
+func isStructured(value string) bool {
+	return urlPattern.MatchString(value) || strings.ContainsAny(value, " \t") || strings.HasPrefix(value, "/")
+}
+
+var urlPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+
+// Entropy returns the Shannon entropy of a string in bits per character
+func Entropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range value {
+		counts[r]++
+		total++
+	}
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Mask hides most of a value, keeping a short prefix so it can be recognised
+func Mask(value string) string {
+	runes := []rune(value)
+	switch {
+	case len(runes) == 0:
+		return ""
+	case len(runes) <= 8:
+		return strings.Repeat("*", len(runes))
+	default:
+		return string(runes[:4]) + strings.Repeat("*", 8)
+	}
+}
+
+// SortFindings orders findings by confidence, then key
+func SortFindings(findings []Finding) {
+	rank := map[Confidence]int{High: 0, Medium: 1, Low: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if rank[findings[i].Confidence] != rank[findings[j].Confidence] {
+			return rank[findings[i].Confidence] < rank[findings[j].Confidence]
+		}
+		return findings[i].Key < findings[j].Key
+	})
+}
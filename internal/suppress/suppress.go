@@ -0,0 +1,148 @@
+package suppress
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Directive is the inline comment that suppresses findings for the next key
+const Directive = "envdoc-ignore"
+
+// dateLayout is the expected format of the until attribute
+const dateLayout = "2006-01-02"
+
+var (
+	directivePattern = regexp.MustCompile(`^#\s*` + Directive + `(?:\s+(.*))?$`)
+	attributePattern = regexp.MustCompile(`(\w+)=(?:"([^"]*)"|(\S+))`)
+)
+
+// Status is the state of a suppression
+type Status string
+
+const (
+	// Active suppressions hide matching findings
+	Active Status = "active"
+	// Expired suppressions are past their until date and no longer hide anything
+	Expired Status = "expired"
+	// Invalid suppressions are malformed and never hide anything
+	Invalid Status = "invalid"
+)
+
+// Suppression is an inline envdoc-ignore comment
+type Suppression struct {
+	File   string
+	Line   int // 1-based line of the comment
+	Key    string
+	Rules  []string // rule IDs, or "*" for all rules
+	Reason string
+	Until  string // YYYY-MM-DD, inclusive
+	// Problem explains why the suppression is invalid
+	Problem string
+}
+
+// Status returns the state of the suppression on the given day
+func (s Suppression) Status(now time.Time) Status {
+	if s.Problem != "" {
+		return Invalid
+	}
+	if now.Format(dateLayout) > s.Until {
+		return Expired
+	}
+	return Active
+}
+
+// Matches reports whether the suppression covers a finding
+func (s Suppression) Matches(file, key, rule string) bool {
+	if s.File != file || s.Key != key {
+		return false
+	}
+	for _, id := range s.Rules {
+		if id == "*" || id == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFile reads the suppressions in an env file
+func ParseFile(filename string) ([]Suppression, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return Parse(filename, string(data)), nil
+}
+
+// Parse returns the suppressions in env file content. Each applies to the key on the next
+// non-comment line; a reason and an until date are mandatory.
+func Parse(filename, content string) []Suppression {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	var suppressions []Suppression
+
+	for i, line := range lines {
+		match := directivePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		s := Suppression{File: filename, Line: i + 1}
+
+		body := match[1]
+		rules := body
+		if loc := attributePattern.FindStringIndex(body); loc != nil {
+			rules = body[:loc[0]]
+		}
+		s.Rules = strings.FieldsFunc(rules, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		for _, attr := range attributePattern.FindAllStringSubmatch(body, -1) {
+			value := attr[2] + attr[3]
+			switch attr[1] {
+			case "reason":
+				s.Reason = strings.TrimSpace(value)
+			case "until":
+				s.Until = value
+			}
+		}
+		s.Key = nextKey(lines[i+1:])
+
+		switch {
+		case len(s.Rules) == 0:
+			s.Problem = "No rule given; use `*` to suppress every rule"
+		case s.Reason == "":
+			s.Problem = `A reason is required: reason="..."`
+		case s.Until == "":
+			s.Problem = "An expiry date is required: until=YYYY-MM-DD"
+		case !validDate(s.Until):
+			s.Problem = fmt.Sprintf("Invalid expiry date %q, expected YYYY-MM-DD", s.Until)
+		case s.Key == "":
+			s.Problem = "Not followed by a KEY=value line"
+		}
+		suppressions = append(suppressions, s)
+	}
+	return suppressions
+}
+
+// nextKey returns the key on the first non-comment line, or "" if that line is not a key
+func nextKey(lines []string) string {
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(trimmed, "export "), "=", 2)
+		if len(parts) != 2 {
+			return ""
+		}
+		return strings.TrimSpace(parts[0])
+	}
+	return ""
+}
+
+// validDate reports whether a string is a YYYY-MM-DD date
+func validDate(value string) bool {
+	_, err := time.Parse(dateLayout, value)
+	return err == nil
+}
@@ -0,0 +1,257 @@
+package usage
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Location is a place in the source tree where an env key is read
+type Location struct {
+	File string
+	Line int
+}
+
+// Result maps each key to the places that read it
+type Result map[string][]Location
+
+// maxFileSize skips generated bundles and other large files
+const maxFileSize = 1 << 20
+
+// DefaultExcludes are directories never scanned
+var DefaultExcludes = []string{".git", "node_modules", "vendor", "dist", "build", "target", ".venv", "venv", "__pycache__", ".next", ".nuxt", "coverage", ".envdoc"}
+
+// language groups the access patterns of one language
+type language struct {
+	extensions []string
+	patterns   []*regexp.Regexp
+}
+
+// jsExtensions are JavaScript and TypeScript sources, which also support destructuring process.env
+var jsExtensions = []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts", ".vue", ".svelte", ".astro"}
+
+var languages = []language{
+	{
+		extensions: []string{".go"},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`os\.(?:Getenv|LookupEnv)\(\s*"([A-Za-z_][A-Za-z0-9_]*)"`),
+		},
+	},
+	{
+		extensions: jsExtensions,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`process\.env\.([A-Za-z_][A-Za-z0-9_]*)`),
+			regexp.MustCompile(`process\.env\[\s*['"\x60]([A-Za-z_][A-Za-z0-9_]*)['"\x60]\s*\]`),
+			regexp.MustCompile(`import\.meta\.env\.([A-Za-z_][A-Za-z0-9_]*)`),
+			regexp.MustCompile(`Deno\.env\.get\(\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]`),
+		},
+	},
+	{
+		extensions: []string{".py"},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`os\.environ\[\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]\s*\]`),
+			regexp.MustCompile(`os\.environ\.get\(\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]`),
+			regexp.MustCompile(`\bgetenv\(\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]`),
+		},
+	},
+	{
+		extensions: []string{".php"},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`\benv\(\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]`),
+			regexp.MustCompile(`\bgetenv\(\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]`),
+			regexp.MustCompile(`\$_(?:ENV|SERVER)\[\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]\s*\]`),
+		},
+	},
+	{
+		extensions: []string{".rb", ".rake", ".erb"},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`ENV\[\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]\s*\]`),
+			regexp.MustCompile(`ENV\.fetch\(\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]`),
+		},
+	},
+	{
+		extensions: []string{".rs"},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`env::var(?:_os)?\(\s*"([A-Za-z_][A-Za-z0-9_]*)"`),
+			regexp.MustCompile(`\b(?:option_)?env!\(\s*"([A-Za-z_][A-Za-z0-9_]*)"`),
+		},
+	},
+}
+
+// shellExtensions are scanned for $VAR and ${VAR} references
+var shellExtensions = []string{".sh", ".bash", ".zsh"}
+
+var (
+	shellReference  = regexp.MustCompile(`\$\{?([A-Z_][A-Z0-9_]*)`)
+	shellAssignment = regexp.MustCompile(`^\s*(?:export\s+|local\s+|readonly\s+)?([A-Z_][A-Z0-9_]*)=`)
+	jsDestructuring = regexp.MustCompile(`\{([^}]*)\}\s*=\s*(?:process\.env|import\.meta\.env)\b`)
+)
+
+// shellBuiltins are variables set by the shell or OS rather than the application
+var shellBuiltins = map[string]bool{
+	"HOME": true, "PATH": true, "PWD": true, "OLDPWD": true, "USER": true, "SHELL": true, "TERM": true,
+	"LANG": true, "HOSTNAME": true, "IFS": true, "RANDOM": true, "SECONDS": true, "LINENO": true,
+	"BASH_SOURCE": true, "BASH_VERSION": true, "UID": true, "EUID": true, "PPID": true, "TMPDIR": true,
+	"OPTARG": true, "OPTIND": true, "FUNCNAME": true, "PIPESTATUS": true, "SHLVL": true,
+}
+
+// Scan walks the directory tree and records every env key read by source files.
+// Directories matching DefaultExcludes or the extra exclude patterns are skipped.
+func Scan(root string, excludes []string) (Result, error) {
+	result := make(Result)
+	excludes = append(append([]string(nil), DefaultExcludes...), excludes...)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && isExcluded(d.Name(), excludes) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isExcluded(d.Name(), excludes) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		return scanFile(path, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range result {
+		sort.Slice(result[key], func(i, j int) bool {
+			a, b := result[key][i], result[key][j]
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			return a.Line < b.Line
+		})
+	}
+	return result, nil
+}
+
+// Keys returns the keys in the result in sorted order
+func (r Result) Keys() []string {
+	keys := make([]string, 0, len(r))
+	for key := range r {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isExcluded reports whether a file or directory name matches an exclude pattern
+func isExcluded(name string, excludes []string) bool {
+	for _, pattern := range excludes {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// scanFile records the env keys read by a single file
+func scanFile(path string, result Result) error {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	var patterns []*regexp.Regexp
+	for _, lang := range languages {
+		for _, e := range lang.extensions {
+			if e == ext {
+				patterns = lang.patterns
+			}
+		}
+	}
+	shell := contains(shellExtensions, ext)
+	js := contains(jsExtensions, ext)
+	if patterns == nil && !shell {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			return
+		}
+	}(file)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFileSize)
+	var shellRefs []struct {
+		key  string
+		line int
+	}
+	assigned := make(map[string]bool)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+
+		for _, re := range patterns {
+			for _, match := range re.FindAllStringSubmatch(line, -1) {
+				result[match[1]] = append(result[match[1]], Location{File: path, Line: lineNumber})
+			}
+		}
+
+		if js {
+			for _, match := range jsDestructuring.FindAllStringSubmatch(line, -1) {
+				for _, part := range strings.Split(match[1], ",") {
+					name := strings.TrimSpace(strings.SplitN(strings.SplitN(part, ":", 2)[0], "=", 2)[0])
+					if name != "" && !strings.HasPrefix(name, "...") {
+						result[name] = append(result[name], Location{File: path, Line: lineNumber})
+					}
+				}
+			}
+		}
+
+		if shell {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			if match := shellAssignment.FindStringSubmatch(line); match != nil {
+				assigned[match[1]] = true
+			}
+			for _, match := range shellReference.FindAllStringSubmatch(line, -1) {
+				shellRefs = append(shellRefs, struct {
+					key  string
+					line int
+				}{match[1], lineNumber})
+			}
+		}
+	}
+
+	// Variables assigned in the script itself are not read from the environment
+	for _, ref := range shellRefs {
+		if !assigned[ref.key] && !shellBuiltins[ref.key] {
+			result[ref.key] = append(result[ref.key], Location{File: path, Line: ref.line})
+		}
+	}
+
+	return nil
+}
+
+// contains reports whether the slice contains the value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -58,6 +61,44 @@ func PromptForPassword(message string) (string, error) {
 	return password, nil
 }
 
+// PromptForValue prompts the user for a value, re-asking until validate accepts it
+func PromptForValue(message, defaultValue, help string, validate func(string) error) (string, error) {
+	var result string
+	prompt := &survey.Input{
+		Message: message,
+		Default: defaultValue,
+		Help:    help,
+	}
+	if err := survey.AskOne(prompt, &result, withValidator(validate)); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// PromptForSecret prompts the user for a hidden value, re-asking until validate accepts it
+func PromptForSecret(message, help string, validate func(string) error) (string, error) {
+	var result string
+	prompt := &survey.Password{
+		Message: message,
+		Help:    help,
+	}
+	if err := survey.AskOne(prompt, &result, withValidator(validate)); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// withValidator adapts a plain string validator to a survey option
+func withValidator(validate func(string) error) survey.AskOpt {
+	return survey.WithValidator(func(ans interface{}) error {
+		if validate == nil {
+			return nil
+		}
+		value, _ := ans.(string)
+		return validate(value)
+	})
+}
+
 // PromptForSelection prompts the user to select from a list
 func PromptForSelection(message string, options []string) (string, error) {
 	var selected string
@@ -87,6 +128,29 @@ func WriteToFile(filename, content string) error {
 	return os.WriteFile(filename, []byte(content), 0644)
 }
 
+// WriteFileAtomic writes a file with the content written by write. The content goes to a
+// temporary file next to it first, so the file is only created or replaced when write
+// succeeds.
+func WriteFileAtomic(filename string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := write(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
 // ReadFromFile reads content from a file
 func ReadFromFile(filename string) (string, error) {
 	data, err := os.ReadFile(filename)
@@ -113,6 +177,40 @@ func PromptForConfirmation(message string) (bool, error) {
 	return confirmed, nil
 }
 
+// PromptForBool prompts the user for a yes/no answer with a default
+func PromptForBool(message, help string, defaultValue bool) (bool, error) {
+	var confirmed bool
+	prompt := &survey.Confirm{
+		Message: message,
+		Help:    help,
+		Default: defaultValue,
+	}
+	if err := survey.AskOne(prompt, &confirmed); err != nil {
+		return false, err
+	}
+	return confirmed, nil
+}
+
+// PromptForChoice prompts the user to select from a list with a default option
+func PromptForChoice(message, help string, options []string, defaultValue string) (string, error) {
+	var selected string
+	prompt := &survey.Select{
+		Message: message,
+		Help:    help,
+		Options: options,
+	}
+	for _, option := range options {
+		if option == defaultValue {
+			prompt.Default = defaultValue
+			break
+		}
+	}
+	if err := survey.AskOne(prompt, &selected); err != nil {
+		return "", err
+	}
+	return selected, nil
+}
+
 // PromptForMultiSelect prompts the user to select multiple items from a list
 func PromptForMultiSelect(message string, options []string) ([]string, error) {
 	var selected []string
@@ -129,7 +227,6 @@ func PromptForMultiSelect(message string, options []string) ([]string, error) {
 // FindEnvFiles finds all .env files in the current directory
 // Excludes backup, json, yaml, hashed, b64, encrypted, and enc files
 func FindEnvFiles() ([]string, error) {
-	const envPrefix = ".env."
 	var files []string
 	entries, err := os.ReadDir(".")
 	if err != nil {
@@ -141,18 +238,43 @@ func FindEnvFiles() ([]string, error) {
 			continue
 		}
 		name := entry.Name()
-		// Include .env and .env.* files
-		if name == ".env" || (len(name) > len(envPrefix) && name[:len(envPrefix)] == envPrefix) {
-			// Exclude specific suffixes
-			if !hasExcludedSuffix(name) {
-				files = append(files, name)
-			}
+		if IsEnvFileName(name) {
+			files = append(files, name)
 		}
 	}
 
 	return files, nil
 }
 
+// IsEnvFileName reports whether a file name is a plaintext .env or .env.* file
+func IsEnvFileName(name string) bool {
+	const envPrefix = ".env."
+	// Include .env and .env.* files
+	if name == ".env" || (len(name) > len(envPrefix) && name[:len(envPrefix)] == envPrefix) {
+		// Exclude specific suffixes
+		return !hasExcludedSuffix(name)
+	}
+	return false
+}
+
+// IsBinary reports whether content looks like a binary file
+func IsBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// IsExampleEnvFileName reports whether an env file name is a template meant to be committed
+func IsExampleEnvFileName(name string) bool {
+	for _, suffix := range []string{".example", ".sample", ".template", ".dist", ".defaults"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
 // hasExcludedSuffix checks if a filename has an excluded suffix
 func hasExcludedSuffix(name string) bool {
 	excludedSuffixes := []string{".bak", ".tmp", ".b64", ".hash", ".enc", ".encrypted", ".decrypted", ".dec", ".json", ".yaml", ".yml", ".hashed"}
@@ -3,6 +3,11 @@ package validator
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/MayR-Labs/envdoc-go/internal/parser"
 )
@@ -17,8 +22,13 @@ type Schema struct {
 
 // Property represents a property in the schema
 type Property struct {
-	Type        string `json:"type"`
-	Description string `json:"description,omitempty"`
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Default     any      `json:"default,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Format      string   `json:"format,omitempty"`
+	Secret      bool     `json:"secret,omitempty"`
 }
 
 // GenerateSchema generates a JSON schema from environment variables
@@ -27,14 +37,9 @@ func GenerateSchema(envVars []parser.EnvVar) (string, error) {
 	var required []string
 
 	for _, envVar := range envVars {
-		description := envVar.Comment
-		if description != "" && len(description) > 2 {
-			description = description[2:] // Remove "# " prefix
-		}
-
 		properties[envVar.Key] = Property{
 			Type:        "string",
-			Description: description,
+			Description: parser.CommentText(envVar.Comment),
 		}
 		required = append(required, envVar.Key)
 	}
@@ -54,12 +59,117 @@ func GenerateSchema(envVars []parser.EnvVar) (string, error) {
 	return string(jsonData), nil
 }
 
-// ValidateAgainstSchema validates environment variables against a JSON schema
-func ValidateAgainstSchema(envVars []parser.EnvVar, schemaJSON string) ([]string, error) {
+// ParseSchema parses a JSON schema document
+func ParseSchema(schemaJSON string) (*Schema, error) {
 	var schema Schema
 	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
 		return nil, fmt.Errorf("failed to parse schema: %w", err)
 	}
+	return &schema, nil
+}
+
+// Keys returns the schema property names in sorted order
+func (s *Schema) Keys() []string {
+	keys := make([]string, 0, len(s.Properties))
+	for key := range s.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// IsRequired reports whether the key is listed as required
+func (s *Schema) IsRequired(key string) bool {
+	for _, required := range s.Required {
+		if required == key {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultString returns the property's default value formatted as an env value
+func (p Property) DefaultString() string {
+	switch v := p.Default.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// ValidateValue checks a single value against the property's type, enum, pattern and format
+func ValidateValue(prop Property, value string) error {
+	value = parser.Unquote(value)
+
+	switch prop.Type {
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("expected a boolean, got %q", value)
+		}
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("expected a number, got %q", value)
+		}
+	}
+
+	if len(prop.Enum) > 0 {
+		found := false
+		for _, option := range prop.Enum {
+			if option == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("expected one of [%s], got %q", strings.Join(prop.Enum, ", "), value)
+		}
+	}
+
+	if prop.Pattern != "" {
+		re, err := regexp.Compile(prop.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern in schema: %w", err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("value does not match pattern %s", prop.Pattern)
+		}
+	}
+
+	switch prop.Format {
+	case "uri", "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("expected a URL, got %q", value)
+		}
+	case "email":
+		if !strings.Contains(value, "@") || strings.HasPrefix(value, "@") || strings.HasSuffix(value, "@") {
+			return fmt.Errorf("expected an email address, got %q", value)
+		}
+	case "port":
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("expected a port number, got %q", value)
+		}
+	}
+
+	return nil
+}
+
+// ValidateAgainstSchema validates environment variables against a JSON schema
+func ValidateAgainstSchema(envVars []parser.EnvVar, schemaJSON string) ([]string, error) {
+	schema, err := ParseSchema(schemaJSON)
+	if err != nil {
+		return nil, err
+	}
 
 	var errors []string
 	envKeys := make(map[string]bool)
@@ -76,9 +186,25 @@ func ValidateAgainstSchema(envVars []parser.EnvVar, schemaJSON string) ([]string
 	}
 
 	// Check for extra keys not in schema
+	var extra []string
 	for key := range envKeys {
 		if _, exists := schema.Properties[key]; !exists {
-			errors = append(errors, fmt.Sprintf("Key not in schema: %s", key))
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		errors = append(errors, fmt.Sprintf("Key not in schema: %s", key))
+	}
+
+	// Check values of keys that are set; sealed values can only be checked once unsealed
+	for _, envVar := range envVars {
+		prop, exists := schema.Properties[envVar.Key]
+		if !exists || envVar.Value == "" || parser.IsSealed(envVar.Value) {
+			continue
+		}
+		if err := ValidateValue(prop, envVar.Value); err != nil {
+			errors = append(errors, fmt.Sprintf("Invalid value for %s: %v", envVar.Key, err))
 		}
 	}
 
@@ -0,0 +1,103 @@
+package workspace
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/MayR-Labs/envdoc-go/internal/git"
+	"github.com/MayR-Labs/envdoc-go/internal/usage"
+	"github.com/MayR-Labs/envdoc-go/internal/utils"
+)
+
+// Group is a directory holding a set of env files that belong together, typically one service
+type Group struct {
+	// Dir is the slash-separated directory relative to the workspace root, "." for the root itself
+	Dir string
+	// Files are the env file paths relative to the workspace root, sorted
+	Files []string
+}
+
+// Name returns a display name for the group
+func (g Group) Name() string {
+	if g.Dir == "." {
+		return "(root)"
+	}
+	return g.Dir
+}
+
+// Discover walks the workspace and returns one group per directory that contains env files.
+// Dependency and build directories, directories matching the exclude patterns (by name or
+// by path relative to root) and, inside a git repository, directories ignored by .gitignore
+// are skipped.
+func Discover(root string, excludes []string) ([]Group, error) {
+	byDir := make(map[string][]string)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel != "." && (matchesAny(usage.DefaultExcludes, d.Name()) || matchesAny(excludes, d.Name()) || matchesAny(excludes, rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if utils.IsEnvFileName(d.Name()) {
+			dir := path.Dir(rel)
+			byDir[dir] = append(byDir[dir], rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	// Env files themselves are usually ignored, so only their directories are checked
+	ignored := make(map[string]bool)
+	if git.IsRepository(root) {
+		var candidates []string
+		for _, dir := range dirs {
+			if dir != "." {
+				candidates = append(candidates, dir+"/")
+			}
+		}
+		ignored, err = git.IgnoredPaths(root, candidates, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var groups []Group
+	for _, dir := range dirs {
+		if ignored[dir+"/"] {
+			continue
+		}
+		files := byDir[dir]
+		sort.Strings(files)
+		groups = append(groups, Group{Dir: dir, Files: files})
+	}
+	return groups, nil
+}
+
+// matchesAny reports whether the name matches any of the glob patterns
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}